@@ -73,6 +73,163 @@ CREATE TABLE IF NOT EXISTS agent_skill_usage (
 );
 
 CREATE INDEX IF NOT EXISTS idx_agent_skill_session ON agent_skill_usage(session_id);
+
+-- Token and cost budgets
+CREATE TABLE IF NOT EXISTS agent_budgets (
+  budget_id TEXT PRIMARY KEY,
+  scope TEXT NOT NULL,
+  scope_value TEXT NOT NULL,
+  metric TEXT NOT NULL,
+  period TEXT NOT NULL,
+  limit_value REAL NOT NULL,
+  mode TEXT NOT NULL DEFAULT 'soft',
+  created_at TEXT DEFAULT (datetime('now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_budgets_scope ON agent_budgets(scope, scope_value);
+
+-- Subagent sessions and the session that started them
+CREATE TABLE IF NOT EXISTS agent_session_parents (
+  session_id TEXT PRIMARY KEY,
+  parent_session_id TEXT NOT NULL,
+  FOREIGN KEY (session_id) REFERENCES agent_sessions(session_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_session_parents_parent ON agent_session_parents(parent_session_id);
+
+-- Failure details for sessions that did not complete
+CREATE TABLE IF NOT EXISTS agent_session_failures (
+  session_id TEXT PRIMARY KEY,
+  error_class TEXT NOT NULL,
+  error_message TEXT,
+  failed_phase TEXT,
+  created_at TEXT NOT NULL,
+  FOREIGN KEY (session_id) REFERENCES agent_sessions(session_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_session_failures_class ON agent_session_failures(error_class);
+
+-- Errors hit during sessions, optionally while working on a work item
+CREATE TABLE IF NOT EXISTS agent_errors (
+  error_id TEXT PRIMARY KEY,
+  session_id TEXT NOT NULL,
+  work_id TEXT,
+  issue_id TEXT,
+  category TEXT NOT NULL,
+  message TEXT,
+  command TEXT,
+  retry_count INTEGER NOT NULL DEFAULT 0,
+  occurred_at TEXT NOT NULL,
+  FOREIGN KEY (session_id) REFERENCES agent_sessions(session_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_errors_session ON agent_errors(session_id);
+CREATE INDEX IF NOT EXISTS idx_agent_errors_occurred ON agent_errors(occurred_at);
+
+-- Tool invocations during sessions
+CREATE TABLE IF NOT EXISTS agent_tool_calls (
+  call_id TEXT PRIMARY KEY,
+  session_id TEXT NOT NULL,
+  work_id TEXT,
+  tool_name TEXT NOT NULL,
+  started_at TEXT NOT NULL,
+  ended_at TEXT,
+  duration_ms INTEGER,
+  success BOOLEAN,
+  bytes_in INTEGER DEFAULT 0,
+  bytes_out INTEGER DEFAULT 0,
+  tokens_in INTEGER DEFAULT 0,
+  tokens_out INTEGER DEFAULT 0,
+  FOREIGN KEY (session_id) REFERENCES agent_sessions(session_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_tool_calls_session ON agent_tool_calls(session_id);
+CREATE INDEX IF NOT EXISTS idx_agent_tool_calls_started ON agent_tool_calls(started_at);
+
+-- Claude Code hook sessions and the tracking session they currently map to
+CREATE TABLE IF NOT EXISTS agent_hook_sessions (
+  hook_session_id TEXT PRIMARY KEY,
+  session_id TEXT NOT NULL,
+  updated_at TEXT NOT NULL,
+  FOREIGN KEY (session_id) REFERENCES agent_sessions(session_id) ON DELETE CASCADE
+);
+
+-- Claude Code tool uses and, for Task calls, the subagent session they started
+CREATE TABLE IF NOT EXISTS agent_hook_tool_calls (
+  call_id TEXT PRIMARY KEY,
+  tool_use_id TEXT,
+  subagent_session_id TEXT,
+  prompt TEXT,
+  FOREIGN KEY (call_id) REFERENCES agent_tool_calls(call_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_hook_tool_calls_tool_use ON agent_hook_tool_calls(tool_use_id);
+CREATE INDEX IF NOT EXISTS idx_agent_hook_tool_calls_subagent ON agent_hook_tool_calls(subagent_session_id);
+
+-- Change log written by triggers, so that changes from every process can be watched
+CREATE TABLE IF NOT EXISTS agent_changes (
+  change_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  event_type TEXT NOT NULL,
+  session_id TEXT,
+  work_id TEXT,
+  issue_id TEXT,
+  agent_name TEXT,
+  skill_name TEXT,
+  exit_reason TEXT,
+  notes TEXT,
+  issues TEXT,
+  skills TEXT,
+  tokens INTEGER,
+  context_added INTEGER,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_changes_created ON agent_changes(created_at);
+
+CREATE TRIGGER IF NOT EXISTS agent_changes_session_started AFTER INSERT ON agent_sessions
+BEGIN
+  INSERT INTO agent_changes (event_type, session_id) VALUES ('session_started', NEW.session_id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS agent_changes_session_ended AFTER UPDATE OF ended_at ON agent_sessions
+WHEN NEW.ended_at IS NOT NULL
+BEGIN
+  INSERT INTO agent_changes (event_type, session_id, exit_reason) VALUES ('session_ended', NEW.session_id, NEW.exit_reason);
+END;
+
+CREATE TRIGGER IF NOT EXISTS agent_changes_session_issues AFTER UPDATE OF issues_claimed ON agent_sessions
+BEGIN
+  INSERT INTO agent_changes (event_type, session_id, issues) VALUES ('session_updated', NEW.session_id, NEW.issues_claimed);
+END;
+
+CREATE TRIGGER IF NOT EXISTS agent_changes_session_skills AFTER UPDATE OF skills_used ON agent_sessions
+BEGIN
+  INSERT INTO agent_changes (event_type, session_id, skills) VALUES ('session_updated', NEW.session_id, NEW.skills_used);
+END;
+
+CREATE TRIGGER IF NOT EXISTS agent_changes_tokens_updated AFTER UPDATE OF context_tokens ON agent_sessions
+BEGIN
+  INSERT INTO agent_changes (event_type, session_id, tokens) VALUES ('tokens_updated', NEW.session_id, NEW.context_tokens);
+END;
+
+CREATE TRIGGER IF NOT EXISTS agent_changes_work_started AFTER INSERT ON agent_issue_work
+BEGIN
+  INSERT INTO agent_changes (event_type, session_id, work_id, issue_id, agent_name)
+  VALUES ('work_started', NEW.session_id, NEW.work_id, NEW.issue_id, NEW.agent_name);
+END;
+
+CREATE TRIGGER IF NOT EXISTS agent_changes_work_completed AFTER UPDATE OF completed ON agent_issue_work
+WHEN NEW.completed = 1
+BEGIN
+  INSERT INTO agent_changes (event_type, session_id, work_id, issue_id, agent_name, notes)
+  VALUES ('work_completed', NEW.session_id, NEW.work_id, NEW.issue_id, NEW.agent_name, NEW.work_notes);
+END;
+
+CREATE TRIGGER IF NOT EXISTS agent_changes_skill_used AFTER INSERT ON agent_skill_usage
+BEGIN
+  INSERT INTO agent_changes (event_type, session_id, issue_id, skill_name, context_added)
+  VALUES ('skill_used', NEW.session_id, NEW.used_for_issue_id, NEW.skill_name, NEW.context_added);
+END;
 `
 
 // Session represents an agent work session.
@@ -82,7 +239,7 @@ type Session struct {
 	WorkspacePath string     `json:"workspace_path"`
 	StartedAt     time.Time  `json:"started_at"`
 	EndedAt       *time.Time `json:"ended_at,omitempty"`
-	ExitReason    string     `json:"exit_reason,omitempty"`
+	ExitReason    ExitReason `json:"exit_reason,omitempty"`
 	IssuesClaimed []string   `json:"issues_claimed"`
 	SkillsUsed    []string   `json:"skills_used"`
 	ModelTier     string     `json:"model_tier,omitempty"`
@@ -147,9 +304,18 @@ func formatTime(t time.Time) string {
 	return t.UTC().Format(time.RFC3339)
 }
 
+// sqliteTimeLayout is the format of SQLite's datetime('now'), used by column defaults.
+const sqliteTimeLayout = "2006-01-02 15:04:05"
+
 // parseTime parses a time string from SQLite storage.
 func parseTime(s string) (time.Time, error) {
-	return time.Parse(time.RFC3339, s)
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		if t, sqliteErr := time.Parse(sqliteTimeLayout, s); sqliteErr == nil {
+			return t, nil
+		}
+	}
+	return t, err
 }
 
 // parseNullableTime parses an optional time string from SQLite storage.
@@ -185,5 +351,5 @@ func Version() string {
 
 // SchemaVersion returns the schema version for migration tracking.
 func SchemaVersion() int {
-	return 1
+	return 8
 }
@@ -0,0 +1,80 @@
+package agent_tracking
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+// beadsSchema is the subset of the beads core schema the tracking tables read.
+const beadsSchema = `
+CREATE TABLE issues (
+	id TEXT PRIMARY KEY,
+	title TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'open',
+	priority INTEGER NOT NULL DEFAULT 2,
+	issue_type TEXT NOT NULL DEFAULT 'task',
+	assignee TEXT,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	closed_at DATETIME
+);
+CREATE TABLE dependencies (
+	issue_id TEXT NOT NULL,
+	depends_on_id TEXT NOT NULL,
+	type TEXT NOT NULL DEFAULT 'blocks',
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	created_by TEXT NOT NULL DEFAULT 'test',
+	PRIMARY KEY (issue_id, depends_on_id)
+);
+`
+
+// openTestDB returns a fresh beads database with the tracking schema applied.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db := openRawTestDB(t, filepath.Join(t.TempDir(), "beads.db"))
+	if _, err := db.Exec(beadsSchema); err != nil {
+		t.Fatalf("failed to create beads schema: %v", err)
+	}
+	if err := Initialize(db); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	return db
+}
+
+// openRawTestDB opens a SQLite database file without creating any tables.
+func openRawTestDB(t *testing.T, path string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func mustExec(t *testing.T, db *sql.DB, query string, args ...interface{}) {
+	t.Helper()
+	if _, err := db.Exec(query, args...); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+}
+
+func TestInitializeIsIdempotent(t *testing.T) {
+	db := openTestDB(t)
+	if err := Initialize(db); err != nil {
+		t.Fatalf("second Initialize failed: %v", err)
+	}
+	for _, table := range []string{"agent_sessions", "agent_issue_work", "agent_skill_usage"} {
+		exists, err := TableExists(db, table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !exists {
+			t.Errorf("table %s was not created", table)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package agent_tracking
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// workAttempt is a finished work entry joined with its session and issue, as used
+// by the outcome analyses.
+//
+// An attempt is a work entry that was completed or whose session has ended.
+// A completed attempt is reworked when later work was started on the same issue.
+// Tokens are the session's context tokens split evenly across its work entries.
+type workAttempt struct {
+	workID    string
+	issueID   string
+	sessionID string
+	agentName string
+	modelTier string
+	issueType string
+	completed bool
+	reworked  bool
+	seconds   float64
+	tokens    float64
+}
+
+// getWorkAttempts loads every finished work attempt in sessions started since the given time.
+func getWorkAttempts(db *sql.DB, since time.Time) ([]workAttempt, error) {
+	rows, err := db.Query(`
+		SELECT
+			w.work_id,
+			w.issue_id,
+			w.session_id,
+			w.agent_name,
+			COALESCE(s.model_tier, ''),
+			COALESCE(i.issue_type, ''),
+			w.completed,
+			w.completed = 1 AND EXISTS (
+				SELECT 1 FROM agent_issue_work later
+				WHERE later.issue_id = w.issue_id AND later.started_at > w.ended_at
+			) as reworked,
+			CASE WHEN w.ended_at IS NOT NULL
+				THEN (JULIANDAY(w.ended_at) - JULIANDAY(w.started_at)) * 86400 ELSE 0 END as seconds,
+			s.context_tokens * 1.0 / n.work_count as tokens
+		FROM agent_issue_work w
+		JOIN agent_sessions s ON w.session_id = s.session_id
+		JOIN (
+			SELECT session_id, COUNT(*) as work_count
+			FROM agent_issue_work
+			GROUP BY session_id
+		) n ON n.session_id = w.session_id
+		LEFT JOIN issues i ON i.id = w.issue_id
+		WHERE s.started_at >= ? AND (w.completed = 1 OR s.ended_at IS NOT NULL)
+	`, formatTime(since))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get work attempts: %w", err)
+	}
+	defer rows.Close()
+
+	var attempts []workAttempt
+	for rows.Next() {
+		var a workAttempt
+		err := rows.Scan(
+			&a.workID, &a.issueID, &a.sessionID, &a.agentName, &a.modelTier, &a.issueType,
+			&a.completed, &a.reworked, &a.seconds, &a.tokens,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan work attempt: %w", err)
+		}
+		attempts = append(attempts, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating work attempts: %w", err)
+	}
+
+	return attempts, nil
+}
@@ -0,0 +1,179 @@
+package agent_tracking
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// BeadsIssue is the subset of a beads core issue that the analysis helpers read.
+// It is loaded directly from the beads `issues` table, which lives in the same
+// database as the agent tracking tables.
+type BeadsIssue struct {
+	ID          string     `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"`
+	Priority    int        `json:"priority"`
+	IssueType   string     `json:"issue_type"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ClosedAt    *time.Time `json:"closed_at,omitempty"`
+}
+
+// beadsTimeLayouts lists the timestamp formats beads and the SQLite drivers are known to write.
+var beadsTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+// parseBeadsTime parses a timestamp written by beads core, which does not use
+// the RFC 3339 format the agent tracking tables use.
+func parseBeadsTime(s string) (time.Time, error) {
+	for _, layout := range beadsTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time format: %q", s)
+}
+
+// GetBeadsIssue retrieves an issue from the beads core issues table.
+//
+// Example:
+//
+//	issue, err := agent_tracking.GetBeadsIssue(db, "agents-42")
+//	fmt.Printf("%s (P%d %s)\n", issue.Title, issue.Priority, issue.IssueType)
+func GetBeadsIssue(db *sql.DB, issueID string) (*BeadsIssue, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	if issueID == "" {
+		return nil, fmt.Errorf("issue ID is required")
+	}
+
+	rows, err := db.Query(`
+		SELECT id, title, COALESCE(description, ''), status, priority,
+		       issue_type, created_at, closed_at
+		FROM issues
+		WHERE id = ?
+	`, issueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get issue: %w", err)
+	}
+	defer rows.Close()
+
+	issues, err := scanBeadsIssues(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(issues) == 0 {
+		return nil, fmt.Errorf("issue not found: %s", issueID)
+	}
+
+	return issues[0], nil
+}
+
+// ListEpicChildren returns the issues linked to an epic with a parent-child dependency.
+//
+// Example:
+//
+//	children, err := agent_tracking.ListEpicChildren(db, "agents-10")
+func ListEpicChildren(db *sql.DB, epicID string) ([]*BeadsIssue, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	if epicID == "" {
+		return nil, fmt.Errorf("epic ID is required")
+	}
+
+	rows, err := db.Query(`
+		SELECT i.id, i.title, COALESCE(i.description, ''), i.status, i.priority,
+		       i.issue_type, i.created_at, i.closed_at
+		FROM dependencies d
+		JOIN issues i ON i.id = d.issue_id
+		WHERE d.depends_on_id = ? AND d.type = 'parent-child'
+		ORDER BY i.id
+	`, epicID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list epic children: %w", err)
+	}
+	defer rows.Close()
+
+	return scanBeadsIssues(rows)
+}
+
+// ListReadyIssues returns open issues that have no open blocking dependencies,
+// matching what `bd ready` reports.
+//
+// Example:
+//
+//	ready, err := agent_tracking.ListReadyIssues(db)
+func ListReadyIssues(db *sql.DB) ([]*BeadsIssue, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	rows, err := db.Query(`
+		SELECT i.id, i.title, COALESCE(i.description, ''), i.status, i.priority,
+		       i.issue_type, i.created_at, i.closed_at
+		FROM issues i
+		WHERE i.status = 'open'
+		  AND NOT EXISTS (
+			SELECT 1
+			FROM dependencies d
+			JOIN issues b ON b.id = d.depends_on_id
+			WHERE d.issue_id = i.id AND d.type = 'blocks' AND b.status != 'closed'
+		  )
+		ORDER BY i.priority ASC, i.created_at ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ready issues: %w", err)
+	}
+	defer rows.Close()
+
+	return scanBeadsIssues(rows)
+}
+
+// scanBeadsIssues scans multiple beads issue rows into a slice.
+func scanBeadsIssues(rows *sql.Rows) ([]*BeadsIssue, error) {
+	var issues []*BeadsIssue
+
+	for rows.Next() {
+		var issue BeadsIssue
+		var createdAtStr string
+		var closedAtStr sql.NullString
+
+		err := rows.Scan(
+			&issue.ID, &issue.Title, &issue.Description, &issue.Status,
+			&issue.Priority, &issue.IssueType, &createdAtStr, &closedAtStr,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan issue: %w", err)
+		}
+
+		issue.CreatedAt, err = parseBeadsTime(createdAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse created_at: %w", err)
+		}
+
+		if closedAtStr.Valid && closedAtStr.String != "" {
+			closedAt, err := parseBeadsTime(closedAtStr.String)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse closed_at: %w", err)
+			}
+			issue.ClosedAt = &closedAt
+		}
+
+		issues = append(issues, &issue)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating issues: %w", err)
+	}
+
+	return issues, nil
+}
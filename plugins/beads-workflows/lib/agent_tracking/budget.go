@@ -0,0 +1,617 @@
+package agent_tracking
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Budget scopes.
+const (
+	BudgetScopeAgent     = "agent"
+	BudgetScopeWorkspace = "workspace"
+	BudgetScopeIssue     = "issue"
+)
+
+// Budget metrics.
+const (
+	BudgetMetricTokens = "tokens"
+	BudgetMetricCost   = "cost"
+)
+
+// Budget periods. Day and week periods start at midnight UTC (weeks on Monday);
+// session budgets apply to each session separately.
+const (
+	BudgetPeriodDay     = "day"
+	BudgetPeriodWeek    = "week"
+	BudgetPeriodSession = "session"
+)
+
+// Budget modes. A soft budget only publishes a budget_exceeded event when a
+// write takes it over its limit; a hard budget also makes writes return a *BudgetExceededError and
+// RecordWork refuse to start new work.
+const (
+	BudgetModeSoft = "soft"
+	BudgetModeHard = "hard"
+)
+
+// Budget caps the tokens or cost consumed by an agent, workspace or issue per period.
+// Cost limits are in dollars, priced with DefaultTierPrices. Workspace budgets
+// match every spelling of the path that NormalizeWorkspacePath maps to the same workspace.
+type Budget struct {
+	BudgetID   string    `json:"budget_id"`
+	Scope      string    `json:"scope"`
+	ScopeValue string    `json:"scope_value"`
+	Metric     string    `json:"metric"`
+	Period     string    `json:"period"`
+	Limit      float64   `json:"limit"`
+	Mode       string    `json:"mode"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// BudgetStatus is the current consumption against a budget. For session budgets
+// it reports the session that was checked, or the active session closest to the limit.
+type BudgetStatus struct {
+	Budget    Budget  `json:"budget"`
+	Consumed  float64 `json:"consumed"`
+	Remaining float64 `json:"remaining"`
+	Exceeded  bool    `json:"exceeded"`
+	SessionID string  `json:"session_id,omitempty"`
+}
+
+// BudgetExceededError is returned when recording usage puts a session over one or
+// more hard budgets. The usage itself has been recorded; callers should stop
+// picking up work. RecordWork returns it without recording anything.
+type BudgetExceededError struct {
+	Exceeded []BudgetStatus
+}
+
+// Error implements the error interface.
+func (e *BudgetExceededError) Error() string {
+	parts := make([]string, len(e.Exceeded))
+	for i, s := range e.Exceeded {
+		b := s.Budget
+		parts[i] = fmt.Sprintf("%s %s %s per %s %s/%s (%s)",
+			b.Scope, b.ScopeValue, b.Metric, b.Period,
+			formatBudgetAmount(b.Metric, s.Consumed), formatBudgetAmount(b.Metric, b.Limit), b.Mode)
+	}
+	return "budget exceeded: " + strings.Join(parts, "; ")
+}
+
+// Hard reports whether any of the exceeded budgets is a hard budget.
+func (e *BudgetExceededError) Hard() bool {
+	for _, s := range e.Exceeded {
+		if s.Budget.Mode == BudgetModeHard {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateBudget adds a budget and returns its ID. Mode defaults to soft.
+//
+// Example:
+//
+//	budgetID, err := agent_tracking.CreateBudget(db, agent_tracking.Budget{
+//	    Scope:      agent_tracking.BudgetScopeAgent,
+//	    ScopeValue: "beads-workflow-orchestrator",
+//	    Metric:     agent_tracking.BudgetMetricTokens,
+//	    Period:     agent_tracking.BudgetPeriodDay,
+//	    Limit:      500000,
+//	    Mode:       agent_tracking.BudgetModeHard,
+//	})
+func CreateBudget(db *sql.DB, budget Budget) (string, error) {
+	if db == nil {
+		return "", fmt.Errorf("database connection is nil")
+	}
+	if budget.Mode == "" {
+		budget.Mode = BudgetModeSoft
+	}
+	if err := validateBudget(budget); err != nil {
+		return "", err
+	}
+
+	budgetID := generateID()
+	createdAt := formatTime(time.Now())
+
+	_, err := db.Exec(`
+		INSERT INTO agent_budgets (budget_id, scope, scope_value, metric, period, limit_value, mode, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, budgetID, budget.Scope, budget.ScopeValue, budget.Metric, budget.Period, budget.Limit, budget.Mode, createdAt)
+	if err != nil {
+		return "", fmt.Errorf("failed to create budget: %w", err)
+	}
+
+	return budgetID, nil
+}
+
+// DeleteBudget removes a budget.
+func DeleteBudget(db *sql.DB, budgetID string) error {
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+	if budgetID == "" {
+		return fmt.Errorf("budget ID is required")
+	}
+
+	result, err := db.Exec(`DELETE FROM agent_budgets WHERE budget_id = ?`, budgetID)
+	if err != nil {
+		return fmt.Errorf("failed to delete budget: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("budget not found: %s", budgetID)
+	}
+
+	return nil
+}
+
+// ListBudgets returns all configured budgets. A database initialized before
+// budgets existed has none.
+func ListBudgets(db *sql.DB) ([]Budget, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	exists, err := TableExists(db, "agent_budgets")
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	rows, err := db.Query(`
+		SELECT budget_id, scope, scope_value, metric, period, limit_value, mode, created_at
+		FROM agent_budgets
+		ORDER BY scope, scope_value, metric, period
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list budgets: %w", err)
+	}
+	defer rows.Close()
+
+	var budgets []Budget
+	for rows.Next() {
+		var b Budget
+		var createdAtStr string
+		if err := rows.Scan(&b.BudgetID, &b.Scope, &b.ScopeValue, &b.Metric, &b.Period, &b.Limit, &b.Mode, &createdAtStr); err != nil {
+			return nil, fmt.Errorf("failed to scan budget: %w", err)
+		}
+		b.CreatedAt, err = parseTime(createdAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse created_at: %w", err)
+		}
+		budgets = append(budgets, b)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating budgets: %w", err)
+	}
+
+	return budgets, nil
+}
+
+// GetBudgetConsumption returns the current consumption against every budget.
+//
+// Example:
+//
+//	statuses, err := agent_tracking.GetBudgetConsumption(db)
+//	for _, s := range statuses {
+//	    fmt.Printf("%s %s: %.0f of %.0f %s\n", s.Budget.Scope, s.Budget.ScopeValue, s.Consumed, s.Budget.Limit, s.Budget.Metric)
+//	}
+func GetBudgetConsumption(db *sql.DB) ([]BudgetStatus, error) {
+	budgets, err := ListBudgets(db)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	statuses := make([]BudgetStatus, 0, len(budgets))
+	for _, b := range budgets {
+		status, err := getBudgetStatus(db, b, "", now)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// CheckSessionBudgets returns the status of every budget that applies to a session:
+// budgets for its agent, its workspace and the issues it has worked on.
+//
+// Example:
+//
+//	statuses, err := agent_tracking.CheckSessionBudgets(db, sessionID)
+func CheckSessionBudgets(db *sql.DB, sessionID string) ([]BudgetStatus, error) {
+	return checkSessionBudgets(db, sessionID, "")
+}
+
+// checkSessionBudgets returns the status of the budgets that apply to a session,
+// including budgets for an issue the session is about to start work on.
+func checkSessionBudgets(db *sql.DB, sessionID, extraIssueID string) ([]BudgetStatus, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	if sessionID == "" {
+		return nil, fmt.Errorf("session ID is required")
+	}
+
+	budgets, err := ListBudgets(db)
+	if err != nil {
+		return nil, err
+	}
+	if len(budgets) == 0 {
+		return nil, nil
+	}
+
+	var agentName, workspacePath string
+	err = db.QueryRow(`
+		SELECT agent_name, workspace_path FROM agent_sessions WHERE session_id = ?
+	`, sessionID).Scan(&agentName, &workspacePath)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	issues := make(map[string]bool)
+	if extraIssueID != "" {
+		issues[extraIssueID] = true
+	}
+	rows, err := db.Query(`SELECT DISTINCT issue_id FROM agent_issue_work WHERE session_id = ?`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session issues: %w", err)
+	}
+	for rows.Next() {
+		var issueID string
+		if err := rows.Scan(&issueID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan issue ID: %w", err)
+		}
+		issues[issueID] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating session issues: %w", err)
+	}
+
+	now := time.Now()
+	workspacePath = NormalizeWorkspacePath(workspacePath)
+	var statuses []BudgetStatus
+	for _, b := range budgets {
+		applies := (b.Scope == BudgetScopeAgent && b.ScopeValue == agentName) ||
+			(b.Scope == BudgetScopeWorkspace && NormalizeWorkspacePath(b.ScopeValue) == workspacePath) ||
+			(b.Scope == BudgetScopeIssue && issues[b.ScopeValue])
+		if !applies {
+			continue
+		}
+		status, err := getBudgetStatus(db, b, sessionID, now)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// getExceededBudgetIDs returns the IDs of the session's budgets that are already
+// exceeded. Writes call it before changing usage and pass the result to
+// checkBudgetsAfterWrite.
+func getExceededBudgetIDs(db *sql.DB, sessionID string) (map[string]bool, error) {
+	statuses, err := checkSessionBudgets(db, sessionID, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to check budgets: %w", err)
+	}
+
+	exceeded := make(map[string]bool)
+	for _, s := range statuses {
+		if s.Exceeded {
+			exceeded[s.Budget.BudgetID] = true
+		}
+	}
+	return exceeded, nil
+}
+
+// checkBudgetsAfterWrite checks a session's budgets after its usage changed.
+// Budgets the write pushed over their limit, i.e. those not in exceededBefore,
+// are published as one budget_exceeded event. Every exceeded hard budget is
+// returned as a *BudgetExceededError.
+func checkBudgetsAfterWrite(db *sql.DB, sessionID string, exceededBefore map[string]bool) error {
+	statuses, err := checkSessionBudgets(db, sessionID, "")
+	if err != nil {
+		return fmt.Errorf("failed to check budgets: %w", err)
+	}
+
+	var crossed []BudgetStatus
+	for _, s := range statuses {
+		if s.Exceeded && !exceededBefore[s.Budget.BudgetID] {
+			crossed = append(crossed, s)
+		}
+	}
+	if len(crossed) > 0 {
+		publishSessionEvent(db, Event{Type: EventBudgetExceeded, SessionID: sessionID, Budgets: crossed})
+	}
+	return exceededBudgets(statuses, true)
+}
+
+// checkWorkBudgets returns a *BudgetExceededError if a hard budget blocks the session
+// from starting work on an issue. Budgets for other issues don't block it.
+func checkWorkBudgets(db *sql.DB, sessionID, issueID string) error {
+	statuses, err := checkSessionBudgets(db, sessionID, issueID)
+	if err != nil {
+		return fmt.Errorf("failed to check budgets: %w", err)
+	}
+
+	var blocking []BudgetStatus
+	for _, s := range statuses {
+		if s.Budget.Scope == BudgetScopeIssue && s.Budget.ScopeValue != issueID {
+			continue
+		}
+		blocking = append(blocking, s)
+	}
+
+	err = exceededBudgets(blocking, true)
+	if exceeded, ok := err.(*BudgetExceededError); ok {
+		publishSessionEvent(db, Event{
+			Type:      EventBudgetExceeded,
+			SessionID: sessionID,
+			IssueID:   issueID,
+			Budgets:   exceeded.Exceeded,
+		})
+	}
+	return err
+}
+
+// exceededBudgets returns a *BudgetExceededError for the exceeded statuses, or nil.
+// With hardOnly, soft budgets are ignored.
+func exceededBudgets(statuses []BudgetStatus, hardOnly bool) error {
+	var exceeded []BudgetStatus
+	for _, s := range statuses {
+		if s.Exceeded && (!hardOnly || s.Budget.Mode == BudgetModeHard) {
+			exceeded = append(exceeded, s)
+		}
+	}
+	if len(exceeded) == 0 {
+		return nil
+	}
+	return &BudgetExceededError{Exceeded: exceeded}
+}
+
+// budgetSessionUsage is one session's contribution to a budget.
+type budgetSessionUsage struct {
+	sessionID string
+	modelTier string
+	tokens    float64
+}
+
+// getBudgetStatus computes the consumption against a budget. For session budgets,
+// sessionID selects the session; when empty, the active session with the highest
+// consumption is reported.
+func getBudgetStatus(db *sql.DB, b Budget, sessionID string, now time.Time) (BudgetStatus, error) {
+	status := BudgetStatus{Budget: b}
+
+	usage, err := getBudgetUsage(db, b, sessionID, now)
+	if err != nil {
+		return status, err
+	}
+
+	prices := DefaultTierPrices()
+	bySession := make(map[string]float64)
+	for _, u := range usage {
+		amount := u.tokens
+		if b.Metric == BudgetMetricCost {
+			amount = u.tokens * tierPrice(prices, u.modelTier) / 1e6
+		}
+		bySession[u.sessionID] += amount
+		status.Consumed += amount
+	}
+
+	if b.Period == BudgetPeriodSession {
+		status.Consumed = 0
+		for id, amount := range bySession {
+			if amount > status.Consumed || status.SessionID == "" {
+				status.Consumed = amount
+				status.SessionID = id
+			}
+		}
+	}
+
+	status.Remaining = b.Limit - status.Consumed
+	status.Exceeded = status.Consumed > b.Limit
+
+	return status, nil
+}
+
+// getBudgetUsage loads the sessions counted against a budget. A session's tokens are
+// its context_tokens, or the context added by its skill loads if that is higher.
+// Issue budgets count the session's tokens split evenly across the issues it worked on.
+func getBudgetUsage(db *sql.DB, b Budget, sessionID string, now time.Time) ([]budgetSessionUsage, error) {
+	share := "1.0"
+	var where []string
+	var args []interface{}
+
+	switch b.Scope {
+	case BudgetScopeAgent:
+		where = append(where, "s.agent_name = ?")
+		args = append(args, b.ScopeValue)
+	case BudgetScopeWorkspace:
+		paths, err := getWorkspaceSpellings(context.Background(), db, b.ScopeValue)
+		if err != nil {
+			return nil, err
+		}
+		if len(paths) == 0 {
+			return nil, nil
+		}
+		where = append(where, "s.workspace_path IN (?"+strings.Repeat(", ?", len(paths)-1)+")")
+		for _, path := range paths {
+			args = append(args, path)
+		}
+	case BudgetScopeIssue:
+		share = "1.0 / (SELECT COUNT(DISTINCT issue_id) FROM agent_issue_work WHERE session_id = s.session_id)"
+		where = append(where, "EXISTS (SELECT 1 FROM agent_issue_work WHERE session_id = s.session_id AND issue_id = ?)")
+		args = append(args, b.ScopeValue)
+	default:
+		return nil, fmt.Errorf("invalid budget scope: %s", b.Scope)
+	}
+
+	switch b.Period {
+	case BudgetPeriodDay, BudgetPeriodWeek:
+		where = append(where, "s.started_at >= ?")
+		args = append(args, formatTime(budgetPeriodStart(b.Period, now)))
+	case BudgetPeriodSession:
+		if sessionID != "" {
+			where = append(where, "s.session_id = ?")
+			args = append(args, sessionID)
+		} else {
+			where = append(where, "s.ended_at IS NULL")
+		}
+	default:
+		return nil, fmt.Errorf("invalid budget period: %s", b.Period)
+	}
+
+	rows, err := db.Query(`
+		SELECT
+			s.session_id,
+			COALESCE(s.model_tier, ''),
+			MAX(s.context_tokens, COALESCE(k.skill_context, 0)) * `+share+`
+		FROM agent_sessions s
+		LEFT JOIN (
+			SELECT session_id, SUM(context_added) as skill_context
+			FROM agent_skill_usage
+			GROUP BY session_id
+		) k ON k.session_id = s.session_id
+		WHERE `+strings.Join(where, " AND "), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get budget usage: %w", err)
+	}
+	defer rows.Close()
+
+	var usage []budgetSessionUsage
+	for rows.Next() {
+		var u budgetSessionUsage
+		if err := rows.Scan(&u.sessionID, &u.modelTier, &u.tokens); err != nil {
+			return nil, fmt.Errorf("failed to scan budget usage: %w", err)
+		}
+		usage = append(usage, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating budget usage: %w", err)
+	}
+
+	return usage, nil
+}
+
+// getWorkspaceSpellings returns the recorded workspace paths that normalize to the
+// same workspace as the given path.
+func getWorkspaceSpellings(ctx context.Context, q changeQuerier, workspacePath string) ([]string, error) {
+	rows, err := q.QueryContext(ctx, `SELECT DISTINCT workspace_path FROM agent_sessions`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace paths: %w", err)
+	}
+	defer rows.Close()
+
+	normalized := NormalizeWorkspacePath(workspacePath)
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, fmt.Errorf("failed to scan workspace path: %w", err)
+		}
+		if NormalizeWorkspacePath(path) == normalized {
+			paths = append(paths, path)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating workspace paths: %w", err)
+	}
+
+	return paths, nil
+}
+
+// NormalizeWorkspacePath maps the different spellings of a workspace to one path:
+// it cleans the path, drops trailing slashes, resolves symlinks when the path
+// exists on this machine, and lower-cases it on case-insensitive platforms
+// (macOS and Windows).
+//
+// Example:
+//
+//	agent_tracking.NormalizeWorkspacePath("/myStuff/project/") // "/myStuff/project"
+func NormalizeWorkspacePath(path string) string {
+	if path == "" {
+		return ""
+	}
+
+	normalized := filepath.Clean(path)
+	if resolved, err := filepath.EvalSymlinks(normalized); err == nil {
+		normalized = resolved
+	}
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		normalized = strings.ToLower(normalized)
+	}
+	return normalized
+}
+
+// budgetPeriodStart returns the start of the day or week containing now, in UTC.
+func budgetPeriodStart(period string, now time.Time) time.Time {
+	y, m, d := now.UTC().Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	if period == BudgetPeriodWeek {
+		offset := (int(start.Weekday()) + 6) % 7
+		start = start.AddDate(0, 0, -offset)
+	}
+	return start
+}
+
+// validateBudget checks that a budget's fields hold known values.
+func validateBudget(b Budget) error {
+	switch b.Scope {
+	case BudgetScopeAgent, BudgetScopeWorkspace, BudgetScopeIssue:
+	default:
+		return fmt.Errorf("invalid budget scope: %q", b.Scope)
+	}
+	if b.ScopeValue == "" {
+		return fmt.Errorf("budget scope value is required")
+	}
+	switch b.Metric {
+	case BudgetMetricTokens, BudgetMetricCost:
+	default:
+		return fmt.Errorf("invalid budget metric: %q", b.Metric)
+	}
+	switch b.Period {
+	case BudgetPeriodDay, BudgetPeriodWeek, BudgetPeriodSession:
+	default:
+		return fmt.Errorf("invalid budget period: %q", b.Period)
+	}
+	if b.Limit <= 0 {
+		return fmt.Errorf("budget limit must be positive")
+	}
+	switch b.Mode {
+	case BudgetModeSoft, BudgetModeHard:
+	default:
+		return fmt.Errorf("invalid budget mode: %q", b.Mode)
+	}
+	return nil
+}
+
+// formatBudgetAmount formats an amount in the budget's metric.
+func formatBudgetAmount(metric string, amount float64) string {
+	if metric == BudgetMetricCost {
+		return fmt.Sprintf("$%.2f", amount)
+	}
+	return fmt.Sprintf("%.0f", amount)
+}
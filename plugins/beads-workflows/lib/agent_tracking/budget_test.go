@@ -0,0 +1,148 @@
+package agent_tracking
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSoftBudgetOnlyPublishesEvent(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := CreateBudget(db, Budget{
+		Scope: BudgetScopeAgent, ScopeValue: "agent", Metric: BudgetMetricTokens, Period: BudgetPeriodDay, Limit: 1000,
+	}); err != nil {
+		t.Fatalf("CreateBudget failed: %v", err)
+	}
+	sessionID, err := StartSession(db, "agent", "/ws", "sonnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var exceeded []Event
+	remove := AddEventHook(func(e Event) {
+		if e.Type == EventBudgetExceeded && e.SessionID == sessionID {
+			exceeded = append(exceeded, e)
+		}
+	})
+	defer remove()
+
+	if err := UpdateSessionTokens(db, sessionID, 1500); err != nil {
+		t.Fatalf("soft overrun returned an error: %v", err)
+	}
+	if err := RecordSkillUsage(db, sessionID, "docker", "", 100); err != nil {
+		t.Fatalf("soft overrun returned an error: %v", err)
+	}
+	if err := UpdateSessionTokens(db, sessionID, 1800); err != nil {
+		t.Fatalf("soft overrun returned an error: %v", err)
+	}
+	if len(exceeded) != 1 || len(exceeded[0].Budgets) != 1 || exceeded[0].Budgets[0].Consumed != 1500 {
+		t.Fatalf("budget events = %+v, want one when the budget is first exceeded at 1500 tokens", exceeded)
+	}
+	if _, err := RecordWork(db, sessionID, "agents-1", "agent", ""); err != nil {
+		t.Errorf("soft budget blocked work: %v", err)
+	}
+}
+
+func TestHardBudgetReturnsError(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := CreateBudget(db, Budget{
+		Scope: BudgetScopeAgent, ScopeValue: "agent", Metric: BudgetMetricCost, Period: BudgetPeriodSession, Limit: 1, Mode: BudgetModeHard,
+	}); err != nil {
+		t.Fatalf("CreateBudget failed: %v", err)
+	}
+	sessionID, err := StartSession(db, "agent", "/ws", "opus")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 100k opus tokens cost $1.50.
+	err = UpdateSessionTokens(db, sessionID, 100000)
+	var budgetErr *BudgetExceededError
+	if !errors.As(err, &budgetErr) || !budgetErr.Hard() || budgetErr.Exceeded[0].Consumed != 1.5 {
+		t.Fatalf("UpdateSessionTokens error = %v, want a hard budget exceeded at $1.50", err)
+	}
+	session, err := GetSession(db, sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.ContextTokens != 100000 {
+		t.Errorf("tokens = %d, want the count saved despite the budget", session.ContextTokens)
+	}
+
+	if _, err := RecordWork(db, sessionID, "agents-1", "agent", ""); !errors.As(err, &budgetErr) {
+		t.Errorf("RecordWork error = %v, want a *BudgetExceededError", err)
+	}
+	work, err := ListWorkBySession(db, sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(work) != 0 {
+		t.Errorf("recorded %d work entries over a hard budget", len(work))
+	}
+
+	// Other sessions start with a fresh session budget.
+	other, err := StartSession(db, "agent", "/ws", "opus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateSessionTokens(db, other, 1000); err != nil {
+		t.Errorf("fresh session over budget: %v", err)
+	}
+}
+
+func TestBudgetsWithoutBudgetTable(t *testing.T) {
+	db := openTestDB(t)
+	mustExec(t, db, `DROP TABLE agent_budgets`)
+
+	sessionID, err := StartSession(db, "agent", "/ws", "sonnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateSessionTokens(db, sessionID, 5000); err != nil {
+		t.Errorf("UpdateSessionTokens failed without budgets: %v", err)
+	}
+	if err := RecordSkillUsage(db, sessionID, "docker", "", 100); err != nil {
+		t.Errorf("RecordSkillUsage failed without budgets: %v", err)
+	}
+	if _, err := RecordWork(db, sessionID, "agents-1", "agent", ""); err != nil {
+		t.Errorf("RecordWork failed without budgets: %v", err)
+	}
+	budgets, err := ListBudgets(db)
+	if err != nil || len(budgets) != 0 {
+		t.Errorf("ListBudgets = %v, %v; want no budgets", budgets, err)
+	}
+}
+
+func TestWorkspaceBudgetMatchesNormalizedPath(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := CreateBudget(db, Budget{
+		Scope: BudgetScopeWorkspace, ScopeValue: "/nonexistent/project/", Metric: BudgetMetricTokens,
+		Period: BudgetPeriodWeek, Limit: 1000, Mode: BudgetModeHard,
+	}); err != nil {
+		t.Fatalf("CreateBudget failed: %v", err)
+	}
+
+	first, err := StartSession(db, "agent", "/nonexistent/project", "sonnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateSessionTokens(db, first, 600); err != nil {
+		t.Fatal(err)
+	}
+	second, err := StartSession(db, "agent", "/nonexistent/./project", "sonnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = UpdateSessionTokens(db, second, 600)
+	var budgetErr *BudgetExceededError
+	if !errors.As(err, &budgetErr) || budgetErr.Exceeded[0].Consumed != 1200 {
+		t.Fatalf("error = %v, want the workspace budget exceeded at 1200 tokens", err)
+	}
+
+	statuses, err := GetBudgetConsumption(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || statuses[0].Consumed != 1200 || !statuses[0].Exceeded {
+		t.Errorf("consumption = %+v, want 1200 tokens across both spellings", statuses)
+	}
+}
@@ -0,0 +1,56 @@
+// Command agent-tracking-dashboard shows live agent activity in the terminal.
+// It fits a tmux pane:
+//
+//	tmux split-window -h 'agent-tracking-dashboard -db "$PWD/.beads/beads.db"'
+//
+// Usage:
+//
+//	agent-tracking-dashboard [-db .beads/beads.db] [-refresh 2s] [-window 24h] [-recent 5]
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/justSteve/agents/plugins/beads-workflows/lib/agent_tracking"
+)
+
+func main() {
+	dbPath := flag.String("db", ".beads/beads.db", "path to the beads database")
+	refresh := flag.Duration("refresh", 0, "how often the screen is redrawn (default 2s)")
+	window := flag.Duration("window", 0, "period covered by the top agents and skills (default 24h)")
+	recent := flag.Int("recent", 0, "number of recent completions shown (default 5)")
+	flag.Parse()
+
+	log.SetPrefix("agent-tracking-dashboard: ")
+	log.SetFlags(0)
+
+	// Agents, hooks and bd write the same file; wait for their locks.
+	db, err := sql.Open("sqlite3", *dbPath+"?_busy_timeout=5000")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := agent_tracking.Initialize(db); err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err = agent_tracking.RunDashboard(ctx, db, agent_tracking.DashboardOptions{
+		Refresh:     *refresh,
+		StatsWindow: *window,
+		Recent:      *recent,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
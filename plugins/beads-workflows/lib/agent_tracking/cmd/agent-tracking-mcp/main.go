@@ -0,0 +1,55 @@
+// Command agent-tracking-mcp serves the agent tracking MCP tools over stdio.
+//
+// Usage:
+//
+//	agent-tracking-mcp [-db .beads/beads.db]
+//
+// Register it with Claude Code:
+//
+//	claude mcp add agent-tracking -- agent-tracking-mcp -db /path/to/.beads/beads.db
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/justSteve/agents/plugins/beads-workflows/lib/agent_tracking"
+)
+
+func main() {
+	dbPath := flag.String("db", ".beads/beads.db", "path to the beads database")
+	flag.Parse()
+
+	// Stdout carries the protocol; logs go to stderr.
+	log.SetOutput(os.Stderr)
+	log.SetPrefix("agent-tracking-mcp: ")
+	log.SetFlags(0)
+
+	// Agents, hooks and bd write the same file; wait for their locks.
+	db, err := sql.Open("sqlite3", *dbPath+"?_busy_timeout=5000")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := agent_tracking.Initialize(db); err != nil {
+		log.Fatal(err)
+	}
+	server, err := agent_tracking.NewMCPServer(db, agent_tracking.MCPServerOptions{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := server.Serve(ctx, os.Stdin, os.Stdout); err != nil && err != context.Canceled {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,388 @@
+package agent_tracking
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// ANSI escape sequences used by the terminal dashboard.
+const (
+	ansiClear = "\x1b[H\x1b[2J"
+	ansiBold  = "\x1b[1m"
+	ansiDim   = "\x1b[2m"
+	ansiReset = "\x1b[0m"
+)
+
+// DashboardOptions configures the terminal dashboard.
+type DashboardOptions struct {
+	Refresh     time.Duration // How often the screen is redrawn (default 2s)
+	StatsWindow time.Duration // Period covered by the top agents and skills (default 24h)
+	Recent      int           // Number of recent completions shown (default 5)
+	In          io.Reader     // Command input (default os.Stdin)
+	Out         io.Writer     // Screen output (default os.Stdout)
+}
+
+// ActiveSession is an active session as shown on the dashboard.
+type ActiveSession struct {
+	Session      *Session      `json:"session"`
+	Elapsed      time.Duration `json:"elapsed"`
+	CurrentIssue string        `json:"current_issue,omitempty"`
+}
+
+// Dashboard is a snapshot of live agent activity.
+type Dashboard struct {
+	GeneratedAt       time.Time       `json:"generated_at"`
+	Active            []ActiveSession `json:"active"`
+	RecentCompletions []*Work         `json:"recent_completions"`
+	Stats             *OverallStats   `json:"stats"`
+}
+
+// TimelineEntry is one step in a session's work and skill timeline.
+type TimelineEntry struct {
+	Time    time.Time `json:"time"`
+	Kind    string    `json:"kind"` // "work_started", "work_completed" or "skill_loaded"
+	IssueID string    `json:"issue_id,omitempty"`
+	Detail  string    `json:"detail,omitempty"`
+}
+
+// GetDashboard collects active sessions with their current issue, the most recent
+// completions, and overall stats for the given window.
+//
+// Example:
+//
+//	d, err := agent_tracking.GetDashboard(db, 24*time.Hour, 5)
+//	for _, a := range d.Active {
+//	    fmt.Printf("%s on %s for %v\n", a.Session.AgentName, a.CurrentIssue, a.Elapsed)
+//	}
+func GetDashboard(db *sql.DB, statsWindow time.Duration, recent int) (*Dashboard, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	now := time.Now().UTC()
+	d := &Dashboard{GeneratedAt: now}
+
+	sessions, err := ListActiveSessions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	// The current issue is the one from the session's latest unfinished work.
+	rows, err := db.Query(`
+		SELECT w.session_id, w.issue_id
+		FROM agent_issue_work w
+		JOIN agent_sessions s ON s.session_id = w.session_id
+		WHERE s.ended_at IS NULL AND w.completed = 0
+		ORDER BY w.started_at, w.rowid
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current issues: %w", err)
+	}
+	defer rows.Close()
+
+	current := make(map[string]string)
+	for rows.Next() {
+		var sessionID, issueID string
+		if err := rows.Scan(&sessionID, &issueID); err != nil {
+			return nil, fmt.Errorf("failed to scan current issue: %w", err)
+		}
+		current[sessionID] = issueID
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating current issues: %w", err)
+	}
+
+	for _, s := range sessions {
+		d.Active = append(d.Active, ActiveSession{
+			Session:      s,
+			Elapsed:      now.Sub(s.StartedAt),
+			CurrentIssue: current[s.SessionID],
+		})
+	}
+
+	d.RecentCompletions, err = ListCompletedWork(db, recent)
+	if err != nil {
+		return nil, err
+	}
+
+	d.Stats, err = GetOverallStats(db, now.Add(-statsWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// GetSessionTimeline returns a session's work and skill loads in time order.
+//
+// Example:
+//
+//	timeline, err := agent_tracking.GetSessionTimeline(db, sessionID)
+//	for _, entry := range timeline {
+//	    fmt.Printf("%s %s %s\n", entry.Time.Format(time.Kitchen), entry.Kind, entry.IssueID)
+//	}
+func GetSessionTimeline(db *sql.DB, sessionID string) ([]TimelineEntry, error) {
+	work, err := ListWorkBySession(db, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	skills, err := ListSkillUsageBySession(db, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var timeline []TimelineEntry
+	for _, w := range work {
+		timeline = append(timeline, TimelineEntry{
+			Time:    w.StartedAt,
+			Kind:    "work_started",
+			IssueID: w.IssueID,
+			Detail:  w.DecisionRationale,
+		})
+		if w.Completed && w.EndedAt != nil {
+			timeline = append(timeline, TimelineEntry{
+				Time:    *w.EndedAt,
+				Kind:    "work_completed",
+				IssueID: w.IssueID,
+				Detail:  w.WorkNotes,
+			})
+		}
+	}
+	for _, s := range skills {
+		timeline = append(timeline, TimelineEntry{
+			Time:    s.LoadedAt,
+			Kind:    "skill_loaded",
+			IssueID: s.UsedForIssueID,
+			Detail:  fmt.Sprintf("%s (+%d tokens)", s.SkillName, s.ContextAdded),
+		})
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Time.Before(timeline[j].Time)
+	})
+
+	return timeline, nil
+}
+
+// RenderDashboard writes the dashboard overview. Active sessions are numbered
+// for drill-down.
+func RenderDashboard(w io.Writer, d *Dashboard) {
+	fmt.Fprintf(w, "%sAgent activity%s  %s%s%s\n\n", ansiBold, ansiReset, ansiDim, d.GeneratedAt.Local().Format("2006-01-02 15:04:05"), ansiReset)
+
+	fmt.Fprintf(w, "%sActive sessions (%d)%s\n", ansiBold, len(d.Active), ansiReset)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if len(d.Active) == 0 {
+		fmt.Fprintln(tw, "  none")
+	} else {
+		fmt.Fprintln(tw, "  #\tAGENT\tTIER\tELAPSED\tTOKENS\tISSUE\tWORKSPACE")
+	}
+	for i, a := range d.Active {
+		issue := a.CurrentIssue
+		if issue == "" {
+			issue = "-"
+		}
+		fmt.Fprintf(tw, "  %d\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			i+1, truncateText(a.Session.AgentName, 32), a.Session.ModelTier, formatElapsed(a.Elapsed),
+			a.Session.ContextTokens, issue, truncateText(a.Session.WorkspacePath, 40))
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\n%sRecent completions%s\n", ansiBold, ansiReset)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if len(d.RecentCompletions) == 0 {
+		fmt.Fprintln(tw, "  none")
+	}
+	for _, work := range d.RecentCompletions {
+		finished := "-"
+		if work.EndedAt != nil {
+			finished = work.EndedAt.Local().Format("Jan 02 15:04")
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", finished, work.IssueID, truncateText(work.AgentName, 32), truncateText(work.WorkNotes, 50))
+	}
+	tw.Flush()
+
+	if d.Stats != nil {
+		fmt.Fprintf(w, "\n%sSince %s%s: %d sessions, %d of %d issues completed, %d tokens\n",
+			ansiBold, d.Stats.Since.Local().Format("Jan 02 15:04"), ansiReset,
+			d.Stats.TotalSessions, d.Stats.CompletedIssues, d.Stats.TotalIssues, d.Stats.TotalTokens)
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  TOP AGENTS\tSESSIONS\tTOP SKILLS\tLOADS")
+		rowCount := len(d.Stats.TopAgents)
+		if len(d.Stats.TopSkills) > rowCount {
+			rowCount = len(d.Stats.TopSkills)
+		}
+		for i := 0; i < rowCount; i++ {
+			agent, sessions, skill, loads := "", "", "", ""
+			if i < len(d.Stats.TopAgents) {
+				agent = truncateText(d.Stats.TopAgents[i].AgentName, 32)
+				sessions = strconv.Itoa(d.Stats.TopAgents[i].Count)
+			}
+			if i < len(d.Stats.TopSkills) {
+				skill = truncateText(d.Stats.TopSkills[i].SkillName, 32)
+				loads = strconv.Itoa(d.Stats.TopSkills[i].Count)
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", agent, sessions, skill, loads)
+		}
+		tw.Flush()
+	}
+}
+
+// RenderSessionTimeline writes a session's details and timeline.
+func RenderSessionTimeline(w io.Writer, s *Session, timeline []TimelineEntry, now time.Time) {
+	end := now
+	status := "active"
+	if s.EndedAt != nil {
+		end = *s.EndedAt
+		status = "ended: " + string(s.ExitReason)
+	}
+
+	fmt.Fprintf(w, "%s%s%s  %s (%s)\n", ansiBold, s.AgentName, ansiReset, s.SessionID, status)
+	fmt.Fprintf(w, "%s  tier %s  elapsed %s  tokens %d\n\n", s.WorkspacePath, s.ModelTier, formatElapsed(end.Sub(s.StartedAt)), s.ContextTokens)
+
+	fmt.Fprintf(w, "%sTimeline%s\n", ansiBold, ansiReset)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "  %s\tsession_started\t\t\n", s.StartedAt.Local().Format("15:04:05"))
+	for _, entry := range timeline {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", entry.Time.Local().Format("15:04:05"), entry.Kind, entry.IssueID, truncateText(entry.Detail, 60))
+	}
+	if s.EndedAt != nil {
+		fmt.Fprintf(tw, "  %s\tsession_ended\t\t%s\n", s.EndedAt.Local().Format("15:04:05"), s.ExitReason)
+	}
+	tw.Flush()
+}
+
+// RunDashboard shows a live dashboard in the terminal until the context is
+// canceled or "q" is entered. Enter an active session's number to drill into its
+// timeline, and an empty line or "b" to go back.
+//
+// Example:
+//
+//	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+//	defer stop()
+//	err := agent_tracking.RunDashboard(ctx, db, agent_tracking.DashboardOptions{Refresh: 5 * time.Second})
+func RunDashboard(ctx context.Context, db *sql.DB, opts DashboardOptions) error {
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+	if opts.Refresh <= 0 {
+		opts.Refresh = 2 * time.Second
+	}
+	if opts.StatsWindow <= 0 {
+		opts.StatsWindow = 24 * time.Hour
+	}
+	if opts.Recent <= 0 {
+		opts.Recent = 5
+	}
+	if opts.In == nil {
+		opts.In = os.Stdin
+	}
+	if opts.Out == nil {
+		opts.Out = os.Stdout
+	}
+
+	commands := make(chan string)
+	go func() {
+		defer close(commands)
+		scanner := bufio.NewScanner(opts.In)
+		for scanner.Scan() {
+			select {
+			case commands <- strings.TrimSpace(scanner.Text()):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(opts.Refresh)
+	defer ticker.Stop()
+
+	var selected string // Session shown in the drill-down, if any
+	message := ""
+	for {
+		var screen strings.Builder
+		screen.WriteString(ansiClear)
+
+		d, err := GetDashboard(db, opts.StatsWindow, opts.Recent)
+		if err != nil {
+			return err
+		}
+		if selected == "" {
+			RenderDashboard(&screen, d)
+			screen.WriteString("\n" + ansiDim + "Enter a session number for its timeline, q to quit." + ansiReset + "\n")
+		} else {
+			session, err := GetSession(db, selected)
+			if err != nil {
+				return err
+			}
+			timeline, err := GetSessionTimeline(db, selected)
+			if err != nil {
+				return err
+			}
+			RenderSessionTimeline(&screen, session, timeline, d.GeneratedAt)
+			screen.WriteString("\n" + ansiDim + "Press Enter to go back, q to quit." + ansiReset + "\n")
+		}
+		if message != "" {
+			screen.WriteString(message + "\n")
+			message = ""
+		}
+		if _, err := io.WriteString(opts.Out, screen.String()); err != nil {
+			return fmt.Errorf("failed to draw dashboard: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case cmd, ok := <-commands:
+			if !ok {
+				// Input closed; keep refreshing without commands.
+				commands = nil
+				continue
+			}
+			switch {
+			case cmd == "q":
+				return nil
+			case cmd == "" || cmd == "b":
+				selected = ""
+			default:
+				n, err := strconv.Atoi(cmd)
+				if err != nil || n < 1 || n > len(d.Active) {
+					message = fmt.Sprintf("No active session %q", cmd)
+					continue
+				}
+				selected = d.Active[n-1].Session.SessionID
+			}
+		}
+	}
+}
+
+// formatElapsed formats a duration compactly, e.g. "2h05m" or "4m30s".
+func formatElapsed(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	d = d.Round(time.Second)
+	if d >= time.Hour {
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+// truncateText shortens s to at most n characters, marking cut text with "…".
+func truncateText(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,99 @@
+package agent_tracking
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetDashboard(t *testing.T) {
+	db := openTestDB(t)
+	sessionID, err := StartSession(db, "orchestrator", "/ws", "opus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	workID, err := RecordWork(db, sessionID, "agents-1", "orchestrator", "highest priority")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordSkillUsage(db, sessionID, "dependency-thinking", "agents-1", 400); err != nil {
+		t.Fatal(err)
+	}
+	if err := CompleteWork(db, workID, "fixed the parser"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RecordWork(db, sessionID, "agents-2", "orchestrator", "next up"); err != nil {
+		t.Fatal(err)
+	}
+	ended, err := StartSession(db, "reviewer", "/ws", "haiku")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := EndSession(db, ended, ExitCompleted); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := GetDashboard(db, time.Hour, 5)
+	if err != nil {
+		t.Fatalf("GetDashboard failed: %v", err)
+	}
+	if len(d.Active) != 1 || d.Active[0].Session.SessionID != sessionID || d.Active[0].CurrentIssue != "agents-2" {
+		t.Fatalf("active = %+v, want the orchestrator on agents-2", d.Active)
+	}
+	if len(d.RecentCompletions) != 1 || d.RecentCompletions[0].WorkNotes != "fixed the parser" {
+		t.Errorf("recent completions = %+v", d.RecentCompletions)
+	}
+	if d.Stats.TotalSessions != 2 || len(d.Stats.TopSkills) != 1 || d.Stats.TopSkills[0].SkillName != "dependency-thinking" {
+		t.Errorf("stats = %+v", d.Stats)
+	}
+
+	mustExec(t, db, `UPDATE agent_skill_usage SET loaded_at = ?`, formatTime(time.Now().Add(time.Minute)))
+	timeline, err := GetSessionTimeline(db, sessionID)
+	if err != nil {
+		t.Fatalf("GetSessionTimeline failed: %v", err)
+	}
+	var kinds []string
+	for _, entry := range timeline {
+		kinds = append(kinds, entry.Kind+":"+entry.IssueID)
+	}
+	if got := strings.Join(kinds, ","); got != "work_started:agents-1,work_completed:agents-1,work_started:agents-2,skill_loaded:agents-1" {
+		t.Errorf("timeline = %s", got)
+	}
+}
+
+func TestRunDashboardDrillDown(t *testing.T) {
+	db := openTestDB(t)
+	sessionID, err := StartSession(db, "orchestrator", "/ws", "opus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RecordWork(db, sessionID, "agents-7", "orchestrator", "unblocks three issues"); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	err = RunDashboard(context.Background(), db, DashboardOptions{
+		Refresh: time.Hour,
+		In:      strings.NewReader("9\n1\nq\n"),
+		Out:     &out,
+	})
+	if err != nil {
+		t.Fatalf("RunDashboard failed: %v", err)
+	}
+
+	screens := strings.Split(out.String(), ansiClear)[1:]
+	if len(screens) != 3 {
+		t.Fatalf("drew %d screens, want 3", len(screens))
+	}
+	if !strings.Contains(screens[0], "Active sessions (1)") || !strings.Contains(screens[0], "agents-7") {
+		t.Errorf("overview screen:\n%s", screens[0])
+	}
+	if !strings.Contains(screens[1], `No active session "9"`) {
+		t.Errorf("invalid selection screen:\n%s", screens[1])
+	}
+	if !strings.Contains(screens[2], sessionID+" (active)") || !strings.Contains(screens[2], "unblocks three issues") {
+		t.Errorf("timeline screen:\n%s", screens[2])
+	}
+}
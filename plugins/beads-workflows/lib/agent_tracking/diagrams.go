@@ -0,0 +1,302 @@
+package agent_tracking
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DiagramOptions selects the activity a diagram covers. AgentName, Since and Limit
+// filter sessions the same way as GetSessionDurations.
+type DiagramOptions struct {
+	Title     string    // Diagram title (default "Agent activity")
+	AgentName string    // Only sessions of this agent
+	Since     time.Time // Only sessions started at or after this time
+	Limit     int       // Most recent sessions to include (default 50)
+	Now       time.Time // End time used for sessions and work still in progress (default time.Now())
+}
+
+// mermaidTimeLayout matches the dateFormat declared in Mermaid Gantt charts.
+const mermaidTimeLayout = "2006-01-02T15:04:05"
+
+// WriteMermaidGantt writes a Mermaid Gantt chart with a section per session,
+// showing the session and its work items. Completed work is marked done, work
+// still in progress active, and sessions that ended with an error critical.
+// Output is deterministic for a given database and Now.
+//
+// Example:
+//
+//	var b strings.Builder
+//	err := agent_tracking.WriteMermaidGantt(db, &b, agent_tracking.DiagramOptions{
+//	    Since: time.Now().AddDate(0, 0, -1),
+//	})
+//	fmt.Printf("```mermaid\n%s```\n", b.String())
+func WriteMermaidGantt(db *sql.DB, w io.Writer, opts DiagramOptions) error {
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+	opts = opts.withDefaults()
+
+	sessions, err := getDiagramSessions(db, opts)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString("gantt\n")
+	fmt.Fprintf(&b, "    title %s\n", mermaidText(opts.Title))
+	b.WriteString("    dateFormat YYYY-MM-DDTHH:mm:ss\n")
+	b.WriteString("    axisFormat %m-%d %H:%M\n")
+
+	for i, session := range sessions {
+		work, err := ListWorkBySession(db, session.SessionID)
+		if err != nil {
+			return err
+		}
+
+		end := opts.Now
+		if session.EndedAt != nil {
+			end = *session.EndedAt
+		}
+
+		fmt.Fprintf(&b, "\n    section %s %s\n", mermaidText(session.AgentName), shortID(session.SessionID))
+
+		var tags []string
+		switch {
+		case session.EndedAt == nil:
+			tags = append(tags, "active")
+		case session.ExitReason == ExitError || session.ExitReason == ExitTimeout:
+			tags = append(tags, "crit")
+		default:
+			tags = append(tags, "done")
+		}
+		sessionLabel := "session"
+		if session.ExitReason != "" {
+			sessionLabel = "session (" + string(session.ExitReason) + ")"
+		}
+		writeMermaidTask(&b, mermaidText(sessionLabel), tags, fmt.Sprintf("s%d", i+1), session.StartedAt, end)
+
+		for j, item := range work {
+			workEnd := end
+			if item.EndedAt != nil {
+				workEnd = *item.EndedAt
+			}
+			var workTags []string
+			if item.Completed {
+				workTags = append(workTags, "done")
+			} else if session.EndedAt == nil {
+				workTags = append(workTags, "active")
+			}
+			writeMermaidTask(&b, mermaidText(item.IssueID), workTags, fmt.Sprintf("s%dw%d", i+1, j+1), item.StartedAt, workEnd)
+		}
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write Gantt chart: %w", err)
+	}
+	return nil
+}
+
+// WriteIssueGraph writes a Graphviz DOT graph linking agents to the issues they
+// worked on in the selected sessions. Edges are weighted and labeled by the time
+// each agent spent on each issue in those sessions, with work still in progress
+// counted up to opts.Now. Nodes and edges are sorted by name, so output is
+// deterministic for a given database and Now.
+//
+// Example:
+//
+//	f, err := os.Create("agents.dot")
+//	if err != nil {
+//	    return err
+//	}
+//	defer f.Close()
+//	err = agent_tracking.WriteIssueGraph(db, f, agent_tracking.DiagramOptions{Since: time.Now().AddDate(0, 0, -7)})
+//	// dot -Tsvg agents.dot -o agents.svg
+func WriteIssueGraph(db *sql.DB, w io.Writer, opts DiagramOptions) error {
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+	opts = opts.withDefaults()
+
+	sessions, err := getDiagramSessions(db, opts)
+	if err != nil {
+		return err
+	}
+
+	type graphEdge struct {
+		agent, issue string
+		time         time.Duration
+		completed    bool
+	}
+	edgeSet := make(map[[2]string]*graphEdge)
+	agentSet := make(map[string]bool)
+	completedIssues := make(map[string]bool)
+	for _, session := range sessions {
+		work, err := ListWorkBySession(db, session.SessionID)
+		if err != nil {
+			return err
+		}
+
+		end := opts.Now
+		if session.EndedAt != nil && session.EndedAt.Before(end) {
+			end = *session.EndedAt
+		}
+		for _, item := range work {
+			workEnd := end
+			if item.EndedAt != nil && item.EndedAt.Before(end) {
+				workEnd = *item.EndedAt
+			}
+			spent := workEnd.Sub(item.StartedAt)
+			if spent < 0 {
+				spent = 0
+			}
+
+			key := [2]string{item.AgentName, item.IssueID}
+			e, ok := edgeSet[key]
+			if !ok {
+				e = &graphEdge{agent: item.AgentName, issue: item.IssueID}
+				edgeSet[key] = e
+			}
+			e.time += spent
+			e.completed = e.completed || item.Completed
+			agentSet[item.AgentName] = true
+			completedIssues[item.IssueID] = completedIssues[item.IssueID] || item.Completed
+		}
+	}
+
+	issues := make([]string, 0, len(completedIssues))
+	for id := range completedIssues {
+		issues = append(issues, id)
+	}
+	sort.Strings(issues)
+
+	edges := make([]graphEdge, 0, len(edgeSet))
+	var maxTime time.Duration
+	for _, e := range edgeSet {
+		edges = append(edges, *e)
+		if e.time > maxTime {
+			maxTime = e.time
+		}
+	}
+	agents := make([]string, 0, len(agentSet))
+	for name := range agentSet {
+		agents = append(agents, name)
+	}
+	sort.Strings(agents)
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].agent != edges[j].agent {
+			return edges[i].agent < edges[j].agent
+		}
+		return edges[i].issue < edges[j].issue
+	})
+
+	var b strings.Builder
+	b.WriteString("graph agent_issues {\n")
+	fmt.Fprintf(&b, "  label=%s;\n", dotQuote(opts.Title))
+	b.WriteString("  labelloc=t;\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [fontname=\"Helvetica\"];\n")
+	b.WriteString("  edge [fontname=\"Helvetica\", fontsize=10];\n")
+
+	b.WriteString("\n  subgraph cluster_agents {\n    label=\"Agents\";\n")
+	for _, name := range agents {
+		fmt.Fprintf(&b, "    %s [label=%s, shape=box, style=rounded];\n", dotQuote("agent:"+name), dotQuote(name))
+	}
+	b.WriteString("  }\n")
+
+	b.WriteString("\n  subgraph cluster_issues {\n    label=\"Issues\";\n")
+	for _, id := range issues {
+		style := "solid"
+		if completedIssues[id] {
+			style = "filled"
+		}
+		fmt.Fprintf(&b, "    %s [label=%s, shape=ellipse, style=%s, fillcolor=\"#d1f0d8\"];\n", dotQuote("issue:"+id), dotQuote(id), style)
+	}
+	b.WriteString("  }\n\n")
+
+	for _, e := range edges {
+		minutes := int(math.Ceil(e.time.Minutes()))
+		if minutes < 1 {
+			minutes = 1
+		}
+		penwidth := 1.0
+		if maxTime > 0 {
+			penwidth += 4 * float64(e.time) / float64(maxTime)
+		}
+		style := "dashed"
+		if e.completed {
+			style = "solid"
+		}
+		fmt.Fprintf(&b, "  %s -- %s [label=%s, weight=%d, penwidth=%.1f, style=%s];\n",
+			dotQuote("agent:"+e.agent), dotQuote("issue:"+e.issue), dotQuote(formatElapsed(e.time)), minutes, penwidth, style)
+	}
+	b.WriteString("}\n")
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write issue graph: %w", err)
+	}
+	return nil
+}
+
+// withDefaults fills in unset diagram options.
+func (opts DiagramOptions) withDefaults() DiagramOptions {
+	if opts.Title == "" {
+		opts.Title = "Agent activity"
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 50
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	return opts
+}
+
+// getDiagramSessions returns the selected sessions, oldest first.
+func getDiagramSessions(db *sql.DB, opts DiagramOptions) ([]*Session, error) {
+	durations, err := GetSessionDurations(db, opts.AgentName, opts.Since, opts.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*Session, 0, len(durations))
+	for _, d := range durations {
+		session, err := GetSession(db, d.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].StartedAt.Equal(sessions[j].StartedAt) {
+			return sessions[i].StartedAt.Before(sessions[j].StartedAt)
+		}
+		return sessions[i].SessionID < sessions[j].SessionID
+	})
+
+	return sessions, nil
+}
+
+// writeMermaidTask writes one Gantt task line.
+func writeMermaidTask(b *strings.Builder, name string, tags []string, id string, start, end time.Time) {
+	if end.Before(start) {
+		end = start
+	}
+	fields := append(append([]string{}, tags...), id, start.UTC().Format(mermaidTimeLayout), end.UTC().Format(mermaidTimeLayout))
+	fmt.Fprintf(b, "    %s :%s\n", name, strings.Join(fields, ", "))
+}
+
+// mermaidText removes characters that end a Mermaid Gantt name or start a comment.
+func mermaidText(s string) string {
+	s = strings.NewReplacer(":", " ", ";", " ", "#", "", "%", "").Replace(s)
+	return markdownInline(s)
+}
+
+// dotQuote returns s as a quoted DOT identifier.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
@@ -0,0 +1,121 @@
+package agent_tracking
+
+import (
+	"bytes"
+	"database/sql"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// seedDiagramActivity inserts sessions and work with fixed IDs and times, so
+// diagram output can be compared byte for byte.
+func seedDiagramActivity(t *testing.T, db *sql.DB, day time.Time) {
+	t.Helper()
+	at := func(hour, minute int) string {
+		return formatTime(day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute))
+	}
+
+	sessions := []struct {
+		id, agent, tier, started string
+		ended, exit              interface{}
+	}{
+		{"0a000001-0000-4000-8000-000000000000", "orchestrator", "opus", at(8, 0), at(8, 30), "error"},
+		{"0a000002-0000-4000-8000-000000000000", "orchestrator", "opus", at(9, 0), at(11, 0), "completed"},
+		{"0a000003-0000-4000-8000-000000000000", "reviewer", "haiku", at(10, 30), nil, nil},
+	}
+	for _, s := range sessions {
+		mustExec(t, db, `INSERT INTO agent_sessions (session_id, agent_name, workspace_path, started_at, ended_at, exit_reason, model_tier)
+			VALUES (?, ?, '/ws', ?, ?, ?, ?)`, s.id, s.agent, s.started, s.ended, s.exit, s.tier)
+	}
+
+	work := []struct {
+		id, issue, session, agent, started string
+		ended                              interface{}
+		completed                          bool
+	}{
+		{"0b000001-0000-4000-8000-000000000000", "agents-3", sessions[0].id, "orchestrator", at(8, 0), at(8, 20), false},
+		{"0b000002-0000-4000-8000-000000000000", "agents-1", sessions[1].id, "orchestrator", at(9, 5), at(10, 0), true},
+		{"0b000003-0000-4000-8000-000000000000", "agents-2", sessions[1].id, "orchestrator", at(10, 0), nil, false},
+		{"0b000004-0000-4000-8000-000000000000", "agents-1", sessions[2].id, "reviewer", at(10, 30), nil, false},
+	}
+	for _, w := range work {
+		mustExec(t, db, `INSERT INTO agent_issue_work (work_id, issue_id, session_id, agent_name, started_at, ended_at, completed)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`, w.id, w.issue, w.session, w.agent, w.started, w.ended, w.completed)
+	}
+}
+
+// checkGolden compares got with testdata/name, rewriting the file with -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from golden file:\n--- got\n%s\n--- want\n%s", name, got, want)
+	}
+}
+
+func TestWriteMermaidGanttGolden(t *testing.T) {
+	db := openTestDB(t)
+	day := time.Date(2026, 9, 14, 0, 0, 0, 0, time.UTC)
+	seedDiagramActivity(t, db, day)
+
+	var b bytes.Buffer
+	err := WriteMermaidGantt(db, &b, DiagramOptions{Since: day, Now: day.Add(12 * time.Hour)})
+	if err != nil {
+		t.Fatalf("WriteMermaidGantt failed: %v", err)
+	}
+	checkGolden(t, "gantt.golden", b.Bytes())
+}
+
+func TestWriteIssueGraphGolden(t *testing.T) {
+	db := openTestDB(t)
+	day := time.Date(2026, 9, 14, 0, 0, 0, 0, time.UTC)
+	seedDiagramActivity(t, db, day)
+
+	var b bytes.Buffer
+	err := WriteIssueGraph(db, &b, DiagramOptions{Since: day, Now: day.Add(12 * time.Hour)})
+	if err != nil {
+		t.Fatalf("WriteIssueGraph failed: %v", err)
+	}
+	checkGolden(t, "issue_graph.golden", b.Bytes())
+}
+
+func TestWriteIssueGraphOnlyCountsSelectedSessions(t *testing.T) {
+	db := openTestDB(t)
+	day := time.Date(2026, 9, 14, 0, 0, 0, 0, time.UTC)
+	seedDiagramActivity(t, db, day)
+
+	// From 9:00 the failed 8:00 session and its issue drop out; agents-1 keeps
+	// only the time spent on it in the selected sessions.
+	var b bytes.Buffer
+	err := WriteIssueGraph(db, &b, DiagramOptions{Since: day.Add(9 * time.Hour), Now: day.Add(12 * time.Hour)})
+	if err != nil {
+		t.Fatalf("WriteIssueGraph failed: %v", err)
+	}
+	out := b.String()
+	if bytes.Contains(b.Bytes(), []byte("agents-3")) {
+		t.Errorf("graph includes an issue only worked on before Since:\n%s", out)
+	}
+	for _, edge := range []string{
+		`"agent:orchestrator" -- "issue:agents-1" [label="55m00s"`,
+		`"agent:orchestrator" -- "issue:agents-2" [label="1h00m"`,
+		`"agent:reviewer" -- "issue:agents-1" [label="1h30m"`,
+	} {
+		if !bytes.Contains(b.Bytes(), []byte(edge)) {
+			t.Errorf("graph is missing %s:\n%s", edge, out)
+		}
+	}
+}
@@ -0,0 +1,458 @@
+package agent_tracking
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Thresholds for the anomalies listed in a digest.
+const (
+	digestLongSession     = 12 * time.Hour // Sessions running longer than this
+	digestStalledAttempts = 3              // Work attempts on an issue without completion
+	digestTokenOutlier    = 3.0            // Sessions using this many times the median tokens
+	digestMinTokenSamples = 5              // Sessions needed before token outliers are reported
+)
+
+// DigestAgent summarizes one agent's activity in a digest.
+type DigestAgent struct {
+	AgentName       string        `json:"agent_name"`
+	Sessions        int           `json:"sessions"`
+	CompletedIssues int           `json:"completed_issues"`
+	Tokens          int           `json:"tokens"`
+	TotalTime       time.Duration `json:"total_time"`
+}
+
+// DigestCompletion is an issue completed during the digest range.
+type DigestCompletion struct {
+	IssueID     string    `json:"issue_id"`
+	Title       string    `json:"title,omitempty"`
+	AgentName   string    `json:"agent_name"`
+	CompletedAt time.Time `json:"completed_at"`
+	Notes       string    `json:"notes,omitempty"`
+}
+
+// DigestCount is a labeled count, used for exit reasons and token usage by tier.
+type DigestCount struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// Digest summarizes tracking activity between From (inclusive) and To (exclusive).
+type Digest struct {
+	From        time.Time          `json:"from"`
+	To          time.Time          `json:"to"`
+	Sessions    int                `json:"sessions"`
+	Agents      []DigestAgent      `json:"agents"`
+	Completions []DigestCompletion `json:"completions"`
+	ExitReasons []DigestCount      `json:"exit_reasons"`
+	TierTokens  []DigestCount      `json:"tier_tokens"`
+	Tokens      int                `json:"tokens"`
+	Anomalies   []string           `json:"anomalies"`
+}
+
+// digestSession is the per-session data a digest is built from.
+type digestSession struct {
+	sessionID  string
+	agentName  string
+	modelTier  string
+	startedAt  time.Time
+	endedAt    *time.Time
+	exitReason string
+	tokens     int
+}
+
+// WeekRange returns the Monday-to-Monday UTC range of the ISO week containing t.
+//
+// Example:
+//
+//	from, to := agent_tracking.WeekRange(time.Now().AddDate(0, 0, -7))
+//	digest, err := agent_tracking.BuildDigest(db, from, to)
+func WeekRange(t time.Time) (time.Time, time.Time) {
+	from := budgetPeriodStart(BudgetPeriodWeek, t)
+	return from, from.AddDate(0, 0, 7)
+}
+
+// WeekDigestPath returns the conventional path of the digest for the ISO week
+// containing t, e.g. "docs/agent-activity/2026-W42.md".
+func WeekDigestPath(t time.Time) string {
+	year, week := t.UTC().ISOWeek()
+	return fmt.Sprintf("docs/agent-activity/%d-W%02d.md", year, week)
+}
+
+// BuildDigest summarizes sessions started in [from, to): per-agent activity,
+// issues completed in the range with their work notes, exit reasons, token
+// usage and anomalies worth a look.
+//
+// Example:
+//
+//	from, to := agent_tracking.WeekRange(time.Now().AddDate(0, 0, -7))
+//	digest, err := agent_tracking.BuildDigest(db, from, to)
+func BuildDigest(db *sql.DB, from, to time.Time) (*Digest, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	if !to.After(from) {
+		return nil, fmt.Errorf("digest range end must be after its start")
+	}
+
+	d := &Digest{From: from.UTC(), To: to.UTC()}
+	fromStr, toStr := formatTime(from), formatTime(to)
+
+	rows, err := db.Query(`
+		SELECT session_id, agent_name, COALESCE(model_tier, ''), started_at, ended_at,
+		       COALESCE(exit_reason, ''), COALESCE(context_tokens, 0)
+		FROM agent_sessions
+		WHERE started_at >= ? AND started_at < ?
+		ORDER BY started_at, session_id
+	`, fromStr, toStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []digestSession
+	for rows.Next() {
+		var s digestSession
+		var startedAtStr string
+		var endedAtStr sql.NullString
+		if err := rows.Scan(&s.sessionID, &s.agentName, &s.modelTier, &startedAtStr, &endedAtStr, &s.exitReason, &s.tokens); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		s.startedAt, err = parseTime(startedAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse started_at: %w", err)
+		}
+		s.endedAt, err = parseNullableTime(endedAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ended_at: %w", err)
+		}
+		sessions = append(sessions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sessions: %w", err)
+	}
+
+	d.Completions, err = getDigestCompletions(db, fromStr, toStr)
+	if err != nil {
+		return nil, err
+	}
+
+	agents := make(map[string]*DigestAgent)
+	exitReasons := make(map[string]int)
+	tierTokens := make(map[string]int)
+	for _, s := range sessions {
+		a, ok := agents[s.agentName]
+		if !ok {
+			a = &DigestAgent{AgentName: s.agentName}
+			agents[s.agentName] = a
+		}
+		a.Sessions++
+		a.Tokens += s.tokens
+		if s.endedAt != nil {
+			a.TotalTime += s.endedAt.Sub(s.startedAt)
+		}
+
+		reason := s.exitReason
+		if s.endedAt == nil {
+			reason = "active"
+		} else if reason == "" {
+			reason = "unknown"
+		}
+		exitReasons[reason]++
+
+		tier := s.modelTier
+		if tier == "" {
+			tier = "unknown"
+		}
+		tierTokens[tier] += s.tokens
+		d.Tokens += s.tokens
+	}
+	completedIssues := make(map[string]map[string]bool)
+	for _, c := range d.Completions {
+		if _, ok := agents[c.AgentName]; !ok {
+			continue
+		}
+		if completedIssues[c.AgentName] == nil {
+			completedIssues[c.AgentName] = make(map[string]bool)
+		}
+		completedIssues[c.AgentName][c.IssueID] = true
+	}
+	for name, issues := range completedIssues {
+		agents[name].CompletedIssues = len(issues)
+	}
+
+	d.Sessions = len(sessions)
+	for _, a := range agents {
+		d.Agents = append(d.Agents, *a)
+	}
+	sort.Slice(d.Agents, func(i, j int) bool {
+		return d.Agents[i].AgentName < d.Agents[j].AgentName
+	})
+	d.ExitReasons = sortedDigestCounts(exitReasons)
+	d.TierTokens = sortedDigestCounts(tierTokens)
+
+	d.Anomalies, err = getDigestAnomalies(db, sessions, fromStr, toStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// getDigestCompletions returns work completed in the range, oldest first, with
+// issue titles from beads where available.
+func getDigestCompletions(db *sql.DB, fromStr, toStr string) ([]DigestCompletion, error) {
+	rows, err := db.Query(`
+		SELECT w.issue_id, COALESCE(i.title, ''), w.agent_name, w.ended_at, COALESCE(w.work_notes, '')
+		FROM agent_issue_work w
+		LEFT JOIN issues i ON i.id = w.issue_id
+		WHERE w.completed = 1 AND w.ended_at >= ? AND w.ended_at < ?
+		ORDER BY w.ended_at, w.issue_id, w.work_id
+	`, fromStr, toStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get completed work: %w", err)
+	}
+	defer rows.Close()
+
+	var completions []DigestCompletion
+	for rows.Next() {
+		var c DigestCompletion
+		var endedAtStr string
+		if err := rows.Scan(&c.IssueID, &c.Title, &c.AgentName, &endedAtStr, &c.Notes); err != nil {
+			return nil, fmt.Errorf("failed to scan completed work: %w", err)
+		}
+		c.CompletedAt, err = parseTime(endedAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ended_at: %w", err)
+		}
+		completions = append(completions, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating completed work: %w", err)
+	}
+
+	return completions, nil
+}
+
+// getDigestAnomalies lists sessions that failed, ran long or used far more tokens
+// than usual, and issues attempted repeatedly without completion.
+func getDigestAnomalies(db *sql.DB, sessions []digestSession, fromStr, toStr string) ([]string, error) {
+	var anomalies []string
+
+	for _, s := range sessions {
+		if s.exitReason != "error" && s.exitReason != "timeout" {
+			continue
+		}
+		// An exit reason can be written without ended_at by hand or by older
+		// tools; report it without a date rather than guessing one.
+		if s.endedAt == nil {
+			anomalies = append(anomalies, fmt.Sprintf("Session `%s` (%s) ended with `%s`",
+				shortID(s.sessionID), s.agentName, s.exitReason))
+			continue
+		}
+		anomalies = append(anomalies, fmt.Sprintf("Session `%s` (%s) ended with `%s` on %s",
+			shortID(s.sessionID), s.agentName, s.exitReason, s.endedAt.UTC().Format("2006-01-02")))
+	}
+
+	for _, s := range sessions {
+		if s.endedAt != nil && s.endedAt.Sub(s.startedAt) > digestLongSession {
+			anomalies = append(anomalies, fmt.Sprintf("Session `%s` (%s) ran for %s",
+				shortID(s.sessionID), s.agentName, formatElapsed(s.endedAt.Sub(s.startedAt))))
+		}
+	}
+
+	if len(sessions) >= digestMinTokenSamples {
+		tokens := make([]int, len(sessions))
+		for i, s := range sessions {
+			tokens[i] = s.tokens
+		}
+		sort.Ints(tokens)
+		median := tokens[len(tokens)/2]
+		if median > 0 {
+			for _, s := range sessions {
+				if float64(s.tokens) > digestTokenOutlier*float64(median) {
+					anomalies = append(anomalies, fmt.Sprintf("Session `%s` (%s) used %d tokens, %.1f× the median of %d",
+						shortID(s.sessionID), s.agentName, s.tokens, float64(s.tokens)/float64(median), median))
+				}
+			}
+		}
+	}
+
+	rows, err := db.Query(`
+		SELECT w.issue_id, COUNT(*) as attempts, COUNT(DISTINCT w.agent_name) as agents
+		FROM agent_issue_work w
+		JOIN agent_sessions s ON s.session_id = w.session_id
+		WHERE s.started_at >= ? AND s.started_at < ?
+		GROUP BY w.issue_id
+		HAVING attempts >= ? AND MAX(w.completed) = 0
+		ORDER BY w.issue_id
+	`, fromStr, toStr, digestStalledAttempts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stalled issues: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var issueID string
+		var attempts, agents int
+		if err := rows.Scan(&issueID, &attempts, &agents); err != nil {
+			return nil, fmt.Errorf("failed to scan stalled issue: %w", err)
+		}
+		anomalies = append(anomalies, fmt.Sprintf("Issue `%s` had %s by %s without completion",
+			issueID, pluralize(attempts, "attempt"), pluralize(agents, "agent")))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stalled issues: %w", err)
+	}
+
+	return anomalies, nil
+}
+
+// WriteMarkdownDigest writes a digest as Markdown. The output depends only on the
+// digest's contents and lists everything in a fixed order, so digests of
+// consecutive weeks diff cleanly.
+//
+// Example:
+//
+//	from, to := agent_tracking.WeekRange(time.Now().AddDate(0, 0, -7))
+//	digest, err := agent_tracking.BuildDigest(db, from, to)
+//	if err != nil {
+//	    return err
+//	}
+//	f, err := os.Create(agent_tracking.WeekDigestPath(from))
+//	if err != nil {
+//	    return err
+//	}
+//	defer f.Close()
+//	err = agent_tracking.WriteMarkdownDigest(f, digest)
+func WriteMarkdownDigest(w io.Writer, d *Digest) error {
+	if d == nil {
+		return fmt.Errorf("digest is required")
+	}
+
+	var b strings.Builder
+	year, week := d.From.ISOWeek()
+	last := d.To.Add(-time.Second)
+	if d.To.Sub(d.From) == 7*24*time.Hour && d.From.Weekday() == time.Monday && d.From.Equal(d.From.Truncate(24*time.Hour)) {
+		fmt.Fprintf(&b, "# Agent Activity %d-W%02d\n\n", year, week)
+	} else {
+		b.WriteString("# Agent Activity\n\n")
+	}
+	fmt.Fprintf(&b, "%s to %s (UTC)\n\n", d.From.Format("2006-01-02"), last.Format("2006-01-02"))
+
+	b.WriteString("## Summary\n\n")
+	fmt.Fprintf(&b, "- Sessions: %d\n", d.Sessions)
+	fmt.Fprintf(&b, "- Agents: %d\n", len(d.Agents))
+	completed := make(map[string]bool)
+	for _, c := range d.Completions {
+		completed[c.IssueID] = true
+	}
+	fmt.Fprintf(&b, "- Issues completed: %d\n", len(completed))
+	fmt.Fprintf(&b, "- Tokens: %d\n", d.Tokens)
+	fmt.Fprintf(&b, "- Anomalies: %d\n\n", len(d.Anomalies))
+
+	b.WriteString("## Sessions by Agent\n\n")
+	if len(d.Agents) == 0 {
+		b.WriteString("No sessions.\n\n")
+	} else {
+		b.WriteString("| Agent | Sessions | Completed | Tokens | Time |\n")
+		b.WriteString("|-------|---------:|----------:|-------:|-----:|\n")
+		for _, a := range d.Agents {
+			fmt.Fprintf(&b, "| %s | %d | %d | %d | %s |\n",
+				markdownCell(a.AgentName), a.Sessions, a.CompletedIssues, a.Tokens, formatElapsed(a.TotalTime))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## Completed Issues\n\n")
+	if len(d.Completions) == 0 {
+		b.WriteString("No issues completed.\n\n")
+	} else {
+		for _, c := range d.Completions {
+			fmt.Fprintf(&b, "- **%s**", c.IssueID)
+			if c.Title != "" {
+				fmt.Fprintf(&b, " %s", markdownInline(c.Title))
+			}
+			fmt.Fprintf(&b, " (%s, %s)\n", c.AgentName, c.CompletedAt.Format("2006-01-02"))
+			if notes := markdownInline(c.Notes); notes != "" {
+				fmt.Fprintf(&b, "  - %s\n", notes)
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## Exit Reasons\n\n")
+	if len(d.ExitReasons) == 0 {
+		b.WriteString("No sessions.\n\n")
+	} else {
+		b.WriteString("| Reason | Sessions |\n")
+		b.WriteString("|--------|---------:|\n")
+		for _, c := range d.ExitReasons {
+			fmt.Fprintf(&b, "| %s | %d |\n", markdownCell(c.Label), c.Count)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## Token Usage by Model Tier\n\n")
+	if len(d.TierTokens) == 0 {
+		b.WriteString("No sessions.\n\n")
+	} else {
+		b.WriteString("| Tier | Tokens |\n")
+		b.WriteString("|------|-------:|\n")
+		for _, c := range d.TierTokens {
+			fmt.Fprintf(&b, "| %s | %d |\n", markdownCell(c.Label), c.Count)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## Anomalies\n\n")
+	if len(d.Anomalies) == 0 {
+		b.WriteString("None.\n")
+	} else {
+		for _, a := range d.Anomalies {
+			fmt.Fprintf(&b, "- %s\n", a)
+		}
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write digest: %w", err)
+	}
+	return nil
+}
+
+// sortedDigestCounts returns counts by descending count, then label.
+func sortedDigestCounts(counts map[string]int) []DigestCount {
+	var result []DigestCount
+	for label, count := range counts {
+		result = append(result, DigestCount{Label: label, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Label < result[j].Label
+	})
+	return result
+}
+
+// shortID returns the first block of a UUID, enough to find a session.
+func shortID(id string) string {
+	if i := strings.IndexByte(id, '-'); i > 0 {
+		return id[:i]
+	}
+	return id
+}
+
+// markdownInline collapses text onto one line for a list item.
+func markdownInline(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// markdownCell makes text safe for a table cell.
+func markdownCell(s string) string {
+	return strings.ReplaceAll(markdownInline(s), "|", `\|`)
+}
@@ -0,0 +1,77 @@
+package agent_tracking
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildDigestCountsDistinctCompletedIssues(t *testing.T) {
+	db := openTestDB(t)
+	mustExec(t, db, `INSERT INTO issues (id, title) VALUES ('agents-1', 'Fix the | parser')`)
+
+	sessionID, err := StartSession(db, "orchestrator", "/ws", "opus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The same issue completed twice, e.g. reopened and fixed again, is one issue.
+	for _, notes := range []string{"first pass", "reopened, fixed the edge case"} {
+		workID, err := RecordWork(db, sessionID, "agents-1", "orchestrator", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := CompleteWork(db, workID, notes); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := EndSession(db, sessionID, ExitCompleted); err != nil {
+		t.Fatal(err)
+	}
+
+	from, to := WeekRange(time.Now())
+	d, err := BuildDigest(db, from, to)
+	if err != nil {
+		t.Fatalf("BuildDigest failed: %v", err)
+	}
+	if len(d.Agents) != 1 || d.Agents[0].CompletedIssues != 1 {
+		t.Fatalf("agents = %+v, want one completed issue", d.Agents)
+	}
+	if len(d.Completions) != 2 {
+		t.Errorf("completions = %+v, want both completions listed", d.Completions)
+	}
+
+	var b bytes.Buffer
+	if err := WriteMarkdownDigest(&b, d); err != nil {
+		t.Fatalf("WriteMarkdownDigest failed: %v", err)
+	}
+	out := b.String()
+	if !strings.Contains(out, "- Issues completed: 1\n") {
+		t.Errorf("summary doesn't count distinct issues:\n%s", out)
+	}
+	if !strings.Contains(out, "| orchestrator | 1 | 1 |") {
+		t.Errorf("agent row doesn't count distinct issues:\n%s", out)
+	}
+	if !strings.Contains(out, "**agents-1** Fix the | parser") {
+		t.Errorf("completion is missing its beads title:\n%s", out)
+	}
+}
+
+func TestBuildDigestExitReasonWithoutEndedAt(t *testing.T) {
+	db := openTestDB(t)
+	sessionID, err := StartSession(db, "reviewer", "/ws", "haiku")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustExec(t, db, `UPDATE agent_sessions SET exit_reason = 'error' WHERE session_id = ?`, sessionID)
+
+	from, to := WeekRange(time.Now())
+	d, err := BuildDigest(db, from, to)
+	if err != nil {
+		t.Fatalf("BuildDigest failed: %v", err)
+	}
+	want := "Session `" + shortID(sessionID) + "` (reviewer) ended with `error`"
+	if len(d.Anomalies) != 1 || d.Anomalies[0] != want {
+		t.Errorf("anomalies = %q, want %q", d.Anomalies, want)
+	}
+}
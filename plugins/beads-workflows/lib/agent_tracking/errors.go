@@ -0,0 +1,303 @@
+package agent_tracking
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Common error categories. Any non-empty category can be recorded.
+const (
+	ErrorCategoryTool       = "tool"       // A tool call failed
+	ErrorCategoryCommand    = "command"    // A shell or bd command exited with an error
+	ErrorCategoryBuild      = "build"      // Compilation or packaging failed
+	ErrorCategoryTest       = "test"       // Tests failed
+	ErrorCategoryNetwork    = "network"    // A network request failed or timed out
+	ErrorCategoryPermission = "permission" // An action was denied
+)
+
+// Error groupings for GetErrorStats.
+const (
+	ErrorGroupAgent   = "agent"
+	ErrorGroupCommand = "command"
+	ErrorGroupIssue   = "issue"
+)
+
+// SessionError is an error an agent ran into during a session, optionally while
+// working on a work item. Command is the tool or command that failed and
+// RetryCount how many times it was retried before giving up or succeeding.
+type SessionError struct {
+	ErrorID    string    `json:"error_id"`
+	SessionID  string    `json:"session_id"`
+	WorkID     string    `json:"work_id,omitempty"`
+	IssueID    string    `json:"issue_id,omitempty"`
+	AgentName  string    `json:"agent_name,omitempty"`
+	Category   string    `json:"category"`
+	Message    string    `json:"message"`
+	Command    string    `json:"command,omitempty"`
+	RetryCount int       `json:"retry_count"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// ErrorFilter selects errors for ListErrors. Empty fields match everything.
+type ErrorFilter struct {
+	SessionID string
+	WorkID    string
+	IssueID   string
+	AgentName string
+	Category  string
+	Command   string
+	Since     time.Time // Only errors at or after this time
+	Limit     int       // Most recent errors to return (0 for all)
+}
+
+// ErrorStats is the error frequency for one agent, command or issue.
+type ErrorStats struct {
+	GroupBy    string         `json:"group_by"`
+	Group      string         `json:"group"`
+	Errors     int            `json:"errors"`
+	Sessions   int            `json:"sessions"`
+	Retries    int            `json:"retries"`
+	Categories map[string]int `json:"categories"`
+	LastSeen   time.Time      `json:"last_seen"`
+}
+
+// RecordError records an error hit during a session and returns its ID. When a
+// work ID is given, the issue defaults to the work's issue. OccurredAt defaults
+// to now.
+//
+// Example:
+//
+//	errorID, err := agent_tracking.RecordError(db, agent_tracking.SessionError{
+//	    SessionID:  sessionID,
+//	    WorkID:     workID,
+//	    Category:   agent_tracking.ErrorCategoryCommand,
+//	    Message:    "database is locked",
+//	    Command:    "bd update agents-42 --status in_progress",
+//	    RetryCount: 2,
+//	})
+func RecordError(db *sql.DB, e SessionError) (string, error) {
+	if db == nil {
+		return "", fmt.Errorf("database connection is nil")
+	}
+	if e.SessionID == "" {
+		return "", fmt.Errorf("session ID is required")
+	}
+	if e.Category == "" {
+		return "", fmt.Errorf("error category is required")
+	}
+	if e.RetryCount < 0 {
+		return "", fmt.Errorf("retry count cannot be negative: %d", e.RetryCount)
+	}
+
+	if e.WorkID != "" {
+		issueID, err := getWorkIssueForSession(db, e.SessionID, e.WorkID)
+		if err != nil {
+			return "", err
+		}
+		if e.IssueID == "" {
+			e.IssueID = issueID
+		}
+	}
+	if e.OccurredAt.IsZero() {
+		e.OccurredAt = time.Now()
+	}
+
+	errorID := generateID()
+
+	_, err := db.Exec(`
+		INSERT INTO agent_errors (error_id, session_id, work_id, issue_id, category, message, command, retry_count, occurred_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, errorID, e.SessionID, e.WorkID, e.IssueID, e.Category, e.Message, e.Command, e.RetryCount, formatTime(e.OccurredAt))
+	if err != nil {
+		return "", fmt.Errorf("failed to record error: %w", err)
+	}
+
+	return errorID, nil
+}
+
+// ListErrorsBySession returns the errors recorded in a session, oldest first.
+//
+// Example:
+//
+//	errs, err := agent_tracking.ListErrorsBySession(db, sessionID)
+//	for _, e := range errs {
+//	    fmt.Printf("%s %s: %s\n", e.Category, e.Command, e.Message)
+//	}
+func ListErrorsBySession(db *sql.DB, sessionID string) ([]*SessionError, error) {
+	if sessionID == "" {
+		return nil, fmt.Errorf("session ID is required")
+	}
+
+	errs, err := ListErrors(db, ErrorFilter{SessionID: sessionID})
+	if err != nil {
+		return nil, err
+	}
+
+	// ListErrors returns the most recent first.
+	for i, j := 0, len(errs)-1; i < j; i, j = i+1, j-1 {
+		errs[i], errs[j] = errs[j], errs[i]
+	}
+	return errs, nil
+}
+
+// ListErrors returns the errors matching a filter, most recent first.
+//
+// Example:
+//
+//	errs, err := agent_tracking.ListErrors(db, agent_tracking.ErrorFilter{
+//	    IssueID: "agents-42",
+//	    Since:   time.Now().AddDate(0, 0, -7),
+//	})
+func ListErrors(db *sql.DB, filter ErrorFilter) ([]*SessionError, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	conditions := []string{"e.occurred_at >= ?"}
+	args := []interface{}{formatTime(filter.Since)}
+	for _, c := range []struct {
+		column, value string
+	}{
+		{"e.session_id", filter.SessionID},
+		{"e.work_id", filter.WorkID},
+		{"e.issue_id", filter.IssueID},
+		{"s.agent_name", filter.AgentName},
+		{"e.category", filter.Category},
+		{"e.command", filter.Command},
+	} {
+		if c.value != "" {
+			conditions = append(conditions, c.column+" = ?")
+			args = append(args, c.value)
+		}
+	}
+	limit := ""
+	if filter.Limit > 0 {
+		limit = "LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := db.Query(`
+		SELECT e.error_id, e.session_id, COALESCE(e.work_id, ''), COALESCE(e.issue_id, ''),
+		       COALESCE(s.agent_name, ''), e.category, COALESCE(e.message, ''), COALESCE(e.command, ''),
+		       e.retry_count, e.occurred_at
+		FROM agent_errors e
+		LEFT JOIN agent_sessions s ON s.session_id = e.session_id
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY e.occurred_at DESC, e.rowid DESC
+		`+limit, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list errors: %w", err)
+	}
+	defer rows.Close()
+
+	var errs []*SessionError
+	for rows.Next() {
+		var e SessionError
+		var occurredAtStr string
+		err := rows.Scan(&e.ErrorID, &e.SessionID, &e.WorkID, &e.IssueID, &e.AgentName,
+			&e.Category, &e.Message, &e.Command, &e.RetryCount, &occurredAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan error: %w", err)
+		}
+		e.OccurredAt, err = parseTime(occurredAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse occurred_at: %w", err)
+		}
+		errs = append(errs, &e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating errors: %w", err)
+	}
+
+	return errs, nil
+}
+
+// GetErrorStats returns how often errors occurred since a given time per agent,
+// command or issue (one of the ErrorGroup constants), most errors first. Errors
+// without a command or issue are left out of those groupings. A command that
+// fails across many sessions, or needs many retries, points at flaky tooling.
+//
+// Example:
+//
+//	stats, err := agent_tracking.GetErrorStats(db, agent_tracking.ErrorGroupCommand, time.Now().AddDate(0, 0, -7))
+//	for _, s := range stats {
+//	    fmt.Printf("%s: %d errors in %d sessions, %d retries\n", s.Group, s.Errors, s.Sessions, s.Retries)
+//	}
+func GetErrorStats(db *sql.DB, groupBy string, since time.Time) ([]ErrorStats, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	var column string
+	switch groupBy {
+	case ErrorGroupAgent:
+		column = "s.agent_name"
+	case ErrorGroupCommand:
+		column = "e.command"
+	case ErrorGroupIssue:
+		column = "e.issue_id"
+	default:
+		return nil, fmt.Errorf("invalid error grouping: %q", groupBy)
+	}
+
+	rows, err := db.Query(`
+		SELECT `+column+`, e.category, e.session_id, COUNT(*), SUM(e.retry_count), MAX(e.occurred_at)
+		FROM agent_errors e
+		JOIN agent_sessions s ON s.session_id = e.session_id
+		WHERE e.occurred_at >= ? AND `+column+` IS NOT NULL AND `+column+` != ''
+		GROUP BY 1, 2, 3
+	`, formatTime(since))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get error stats: %w", err)
+	}
+	defer rows.Close()
+
+	groups := make(map[string]*ErrorStats)
+	sessions := make(map[string]map[string]bool)
+	for rows.Next() {
+		var group, category, sessionID, lastSeenStr string
+		var count, retries int
+		if err := rows.Scan(&group, &category, &sessionID, &count, &retries, &lastSeenStr); err != nil {
+			return nil, fmt.Errorf("failed to scan error stats: %w", err)
+		}
+		lastSeen, err := parseTime(lastSeenStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse occurred_at: %w", err)
+		}
+
+		stats, ok := groups[group]
+		if !ok {
+			stats = &ErrorStats{GroupBy: groupBy, Group: group, Categories: make(map[string]int)}
+			groups[group] = stats
+			sessions[group] = make(map[string]bool)
+		}
+		stats.Errors += count
+		stats.Retries += retries
+		stats.Categories[category] += count
+		sessions[group][sessionID] = true
+		if lastSeen.After(stats.LastSeen) {
+			stats.LastSeen = lastSeen
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating error stats: %w", err)
+	}
+
+	result := make([]ErrorStats, 0, len(groups))
+	for group, stats := range groups {
+		stats.Sessions = len(sessions[group])
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Errors != result[j].Errors {
+			return result[i].Errors > result[j].Errors
+		}
+		return result[i].Group < result[j].Group
+	})
+
+	return result, nil
+}
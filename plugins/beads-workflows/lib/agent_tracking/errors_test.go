@@ -0,0 +1,135 @@
+package agent_tracking
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecordErrorDefaultsIssueFromWork(t *testing.T) {
+	db := openTestDB(t)
+	sessionID, err := StartSession(db, "orchestrator", "/ws", "opus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	workID, err := RecordWork(db, sessionID, "agents-1", "orchestrator", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := StartSession(db, "reviewer", "/ws", "haiku")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errorID, err := RecordError(db, SessionError{
+		SessionID:  sessionID,
+		WorkID:     workID,
+		Category:   ErrorCategoryCommand,
+		Message:    "database is locked",
+		Command:    "bd update agents-1 --status in_progress",
+		RetryCount: 2,
+	})
+	if err != nil {
+		t.Fatalf("RecordError failed: %v", err)
+	}
+
+	errs, err := ListErrorsBySession(db, sessionID)
+	if err != nil {
+		t.Fatalf("ListErrorsBySession failed: %v", err)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("errors = %+v", errs)
+	}
+	e := errs[0]
+	if e.ErrorID != errorID || e.IssueID != "agents-1" || e.AgentName != "orchestrator" || e.RetryCount != 2 || e.OccurredAt.IsZero() {
+		t.Errorf("error = %+v", e)
+	}
+
+	for name, bad := range map[string]SessionError{
+		"no session":           {Category: ErrorCategoryTool},
+		"no category":          {SessionID: sessionID},
+		"negative retries":     {SessionID: sessionID, Category: ErrorCategoryTool, RetryCount: -1},
+		"unknown work":         {SessionID: sessionID, Category: ErrorCategoryTool, WorkID: "no-such-work"},
+		"other session's work": {SessionID: other, Category: ErrorCategoryTool, WorkID: workID},
+	} {
+		if _, err := RecordError(db, bad); err == nil {
+			t.Errorf("%s: RecordError succeeded", name)
+		}
+	}
+}
+
+func TestListErrorsAndErrorStats(t *testing.T) {
+	db := openTestDB(t)
+	base := time.Date(2026, 9, 14, 9, 0, 0, 0, time.UTC)
+
+	first, err := StartSession(db, "orchestrator", "/ws", "opus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := StartSession(db, "orchestrator", "/ws", "opus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reviewer, err := StartSession(db, "reviewer", "/ws", "haiku")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flaky := "bd sync"
+	record := func(sessionID, category, command string, retries int, at time.Time) {
+		t.Helper()
+		_, err := RecordError(db, SessionError{
+			SessionID: sessionID, Category: category, Message: "failed", Command: command,
+			RetryCount: retries, OccurredAt: at,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	record(first, ErrorCategoryCommand, flaky, 1, base)
+	record(second, ErrorCategoryNetwork, flaky, 3, base.Add(time.Hour))
+	record(second, ErrorCategoryCommand, flaky, 0, base.Add(2*time.Hour))
+	record(reviewer, ErrorCategoryTest, "go test ./...", 0, base.Add(3*time.Hour))
+	record(reviewer, ErrorCategoryPermission, "", 0, base.AddDate(0, 0, -7))
+
+	recent, err := ListErrors(db, ErrorFilter{Command: flaky, Limit: 2})
+	if err != nil {
+		t.Fatalf("ListErrors failed: %v", err)
+	}
+	if len(recent) != 2 || !recent[0].OccurredAt.Equal(base.Add(2*time.Hour)) || recent[1].Category != ErrorCategoryNetwork {
+		t.Errorf("recent = %+v, want the two latest bd sync errors, newest first", recent)
+	}
+	byAgent, err := ListErrors(db, ErrorFilter{AgentName: "reviewer", Since: base})
+	if err != nil {
+		t.Fatalf("ListErrors failed: %v", err)
+	}
+	if len(byAgent) != 1 || byAgent[0].Category != ErrorCategoryTest {
+		t.Errorf("reviewer errors since base = %+v", byAgent)
+	}
+
+	stats, err := GetErrorStats(db, ErrorGroupCommand, base)
+	if err != nil {
+		t.Fatalf("GetErrorStats failed: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("stats = %+v, want bd sync and go test; errors without a command are skipped", stats)
+	}
+	sync := stats[0]
+	if sync.Group != flaky || sync.Errors != 3 || sync.Sessions != 2 || sync.Retries != 4 || !sync.LastSeen.Equal(base.Add(2*time.Hour)) {
+		t.Errorf("bd sync = %+v", sync)
+	}
+	if want := map[string]int{ErrorCategoryCommand: 2, ErrorCategoryNetwork: 1}; !reflect.DeepEqual(sync.Categories, want) {
+		t.Errorf("bd sync categories = %v, want %v", sync.Categories, want)
+	}
+
+	agents, err := GetErrorStats(db, ErrorGroupAgent, time.Time{})
+	if err != nil {
+		t.Fatalf("GetErrorStats failed: %v", err)
+	}
+	if len(agents) != 2 || agents[0].Group != "orchestrator" || agents[1].Errors != 2 {
+		t.Errorf("agent stats = %+v", agents)
+	}
+	if _, err := GetErrorStats(db, "tool", time.Time{}); err == nil {
+		t.Error("GetErrorStats accepted an unknown grouping")
+	}
+}
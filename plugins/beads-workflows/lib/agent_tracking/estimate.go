@@ -0,0 +1,242 @@
+package agent_tracking
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// minEstimateSamples is the number of completed issues a group needs before an
+// estimate is based on it; smaller groups fall back to a broader grouping.
+const minEstimateSamples = 5
+
+// EffortEstimate is the expected agent effort for an issue, learned from
+// completed work on similar issues.
+type EffortEstimate struct {
+	IssueID           string        `json:"issue_id,omitempty"`
+	IssueType         string        `json:"issue_type"`
+	Priority          int           `json:"priority"`
+	DescriptionLength string        `json:"description_length"`
+	Basis             string        `json:"basis"`
+	SampleSize        int           `json:"sample_size"`
+	Duration          DurationRange `json:"duration"`
+	Tokens            TokenRange    `json:"tokens"`
+	AvgSessions       float64       `json:"avg_sessions"`
+	AvgSkills         float64       `json:"avg_skills"`
+}
+
+// DurationRange is an estimated duration with a P10-P90 confidence range.
+type DurationRange struct {
+	Low      time.Duration `json:"low"`
+	Expected time.Duration `json:"expected"`
+	High     time.Duration `json:"high"`
+}
+
+// TokenRange is an estimated token count with a P10-P90 confidence range.
+type TokenRange struct {
+	Low      int `json:"low"`
+	Expected int `json:"expected"`
+	High     int `json:"high"`
+}
+
+// issueEffort is the observed effort for one completed issue.
+type issueEffort struct {
+	issueType         string
+	priority          int
+	descriptionLength string
+	seconds           float64
+	tokens            float64
+	sessions          int
+	skills            int
+}
+
+// descriptionLengthBucket groups description lengths the same way the
+// beads-workflow-orchestrator judges description quality.
+func descriptionLengthBucket(description string) string {
+	switch n := len(description); {
+	case n < 100:
+		return "short"
+	case n < 500:
+		return "medium"
+	default:
+		return "long"
+	}
+}
+
+// EstimateIssueEffort estimates the effort for an existing beads issue.
+//
+// Example:
+//
+//	estimate, err := agent_tracking.EstimateIssueEffort(db, "agents-42")
+//	fmt.Printf("Expected %v (%v-%v), ~%d tokens\n",
+//	    estimate.Duration.Expected, estimate.Duration.Low, estimate.Duration.High, estimate.Tokens.Expected)
+func EstimateIssueEffort(db *sql.DB, issueID string) (*EffortEstimate, error) {
+	issue, err := GetBeadsIssue(db, issueID)
+	if err != nil {
+		return nil, err
+	}
+	return EstimateEffort(db, issue)
+}
+
+// EstimateEffort estimates the effort for an issue that may not exist in beads yet.
+// Only IssueType, Priority and Description are used to find similar issues.
+//
+// History is grouped by issue type, priority and description length. When the
+// matching group has fewer than five completed issues, the estimate falls back to
+// type and priority, then type alone, then all completed issues; Basis records
+// which grouping was used.
+func EstimateEffort(db *sql.DB, issue *BeadsIssue) (*EffortEstimate, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	if issue == nil {
+		return nil, fmt.Errorf("issue is required")
+	}
+
+	history, err := getIssueEffortHistory(db)
+	if err != nil {
+		return nil, err
+	}
+
+	estimate := &EffortEstimate{
+		IssueID:           issue.ID,
+		IssueType:         issue.IssueType,
+		Priority:          issue.Priority,
+		DescriptionLength: descriptionLengthBucket(issue.Description),
+	}
+
+	groupings := []struct {
+		basis string
+		match func(e issueEffort) bool
+	}{
+		{"issue_type+priority+description_length", func(e issueEffort) bool {
+			return e.issueType == estimate.IssueType && e.priority == estimate.Priority &&
+				e.descriptionLength == estimate.DescriptionLength
+		}},
+		{"issue_type+priority", func(e issueEffort) bool {
+			return e.issueType == estimate.IssueType && e.priority == estimate.Priority
+		}},
+		{"issue_type", func(e issueEffort) bool {
+			return e.issueType == estimate.IssueType
+		}},
+		{"all", func(e issueEffort) bool {
+			return true
+		}},
+	}
+
+	var samples []issueEffort
+	for _, g := range groupings {
+		samples = samples[:0]
+		for _, e := range history {
+			if g.match(e) {
+				samples = append(samples, e)
+			}
+		}
+		estimate.Basis = g.basis
+		if len(samples) >= minEstimateSamples {
+			break
+		}
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no completed work to estimate from")
+	}
+
+	estimate.SampleSize = len(samples)
+
+	seconds := make([]float64, len(samples))
+	tokens := make([]float64, len(samples))
+	var sessions, skills int
+	for i, e := range samples {
+		seconds[i] = e.seconds
+		tokens[i] = e.tokens
+		sessions += e.sessions
+		skills += e.skills
+	}
+	sort.Float64s(seconds)
+	sort.Float64s(tokens)
+
+	estimate.Duration = DurationRange{
+		Low:      secondsToDuration(seconds[percentileIndex(len(seconds), 10)]),
+		Expected: secondsToDuration(seconds[percentileIndex(len(seconds), 50)]),
+		High:     secondsToDuration(seconds[percentileIndex(len(seconds), 90)]),
+	}
+	estimate.Tokens = TokenRange{
+		Low:      int(tokens[percentileIndex(len(tokens), 10)]),
+		Expected: int(tokens[percentileIndex(len(tokens), 50)]),
+		High:     int(tokens[percentileIndex(len(tokens), 90)]),
+	}
+	estimate.AvgSessions = float64(sessions) / float64(len(samples))
+	estimate.AvgSkills = float64(skills) / float64(len(samples))
+
+	return estimate, nil
+}
+
+// getIssueEffortHistory returns the observed effort of every issue with completed work.
+// Session tokens are split evenly between the issues worked on in that session.
+func getIssueEffortHistory(db *sql.DB) ([]issueEffort, error) {
+	rows, err := db.Query(`
+		SELECT
+			i.issue_type,
+			i.priority,
+			COALESCE(i.description, ''),
+			COALESCE(w.total_seconds, 0),
+			COALESCE(t.tokens, 0),
+			w.sessions,
+			COALESCE(k.skills, 0)
+		FROM (
+			SELECT
+				issue_id,
+				SUM(CASE WHEN ended_at IS NOT NULL
+					THEN (JULIANDAY(ended_at) - JULIANDAY(started_at)) * 86400 ELSE 0 END) as total_seconds,
+				COUNT(DISTINCT session_id) as sessions
+			FROM agent_issue_work
+			GROUP BY issue_id
+			HAVING MAX(completed) = 1
+		) w
+		JOIN issues i ON i.id = w.issue_id
+		LEFT JOIN (
+			SELECT iw.issue_id, SUM(s.context_tokens * 1.0 / n.issue_count) as tokens
+			FROM (SELECT DISTINCT issue_id, session_id FROM agent_issue_work) iw
+			JOIN agent_sessions s ON s.session_id = iw.session_id
+			JOIN (
+				SELECT session_id, COUNT(DISTINCT issue_id) as issue_count
+				FROM agent_issue_work
+				GROUP BY session_id
+			) n ON n.session_id = iw.session_id
+			GROUP BY iw.issue_id
+		) t ON t.issue_id = w.issue_id
+		LEFT JOIN (
+			SELECT used_for_issue_id as issue_id, COUNT(*) as skills
+			FROM agent_skill_usage
+			WHERE used_for_issue_id IS NOT NULL AND used_for_issue_id != ''
+			GROUP BY used_for_issue_id
+		) k ON k.issue_id = w.issue_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get effort history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []issueEffort
+	for rows.Next() {
+		var e issueEffort
+		var description string
+		if err := rows.Scan(&e.issueType, &e.priority, &description, &e.seconds, &e.tokens, &e.sessions, &e.skills); err != nil {
+			return nil, fmt.Errorf("failed to scan effort history: %w", err)
+		}
+		e.descriptionLength = descriptionLengthBucket(description)
+		history = append(history, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating effort history: %w", err)
+	}
+
+	return history, nil
+}
+
+// secondsToDuration converts a number of seconds from a JULIANDAY calculation to a duration.
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
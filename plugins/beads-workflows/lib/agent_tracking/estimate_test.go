@@ -0,0 +1,77 @@
+package agent_tracking
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// seedCompletedIssue records a beads issue completed in one session that took
+// the given time and tokens.
+func seedCompletedIssue(t *testing.T, db *sql.DB, id, issueType string, priority int, took time.Duration, tokens int) {
+	t.Helper()
+	mustExec(t, db, `INSERT INTO issues (id, title, issue_type, priority) VALUES (?, ?, ?, ?)`, id, id, issueType, priority)
+	sessionID, err := StartSession(db, "agent", "/ws", "sonnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateSessionTokens(db, sessionID, tokens); err != nil {
+		t.Fatal(err)
+	}
+	workID, err := RecordWork(db, sessionID, id, "agent", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	end := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	mustExec(t, db, `UPDATE agent_issue_work SET completed = 1, started_at = ?, ended_at = ? WHERE work_id = ?`,
+		formatTime(end.Add(-took)), formatTime(end), workID)
+}
+
+func TestEstimateEffort(t *testing.T) {
+	db := openTestDB(t)
+
+	if _, err := EstimateEffort(db, &BeadsIssue{IssueType: "bug", Priority: 1}); err == nil {
+		t.Error("expected an error without completed work")
+	}
+
+	for i := 1; i <= 5; i++ {
+		seedCompletedIssue(t, db, fmt.Sprintf("bug-%d", i), "bug", 1, time.Duration(i)*time.Hour, 1000*i)
+	}
+	seedCompletedIssue(t, db, "task-1", "task", 2, 10*time.Hour, 20000)
+	seedCompletedIssue(t, db, "task-2", "task", 2, 20*time.Hour, 40000)
+
+	estimate, err := EstimateEffort(db, &BeadsIssue{IssueType: "bug", Priority: 1})
+	if err != nil {
+		t.Fatalf("EstimateEffort failed: %v", err)
+	}
+	if estimate.Basis != "issue_type+priority+description_length" || estimate.SampleSize != 5 {
+		t.Errorf("basis = %s over %d issues, want the full grouping over 5", estimate.Basis, estimate.SampleSize)
+	}
+	if got := estimate.Duration; got.Low.Round(time.Second) != time.Hour ||
+		got.Expected.Round(time.Second) != 3*time.Hour || got.High.Round(time.Second) != 5*time.Hour {
+		t.Errorf("duration = %+v, want 1h/3h/5h", got)
+	}
+	if got := estimate.Tokens; got.Low != 1000 || got.Expected != 3000 || got.High != 5000 {
+		t.Errorf("tokens = %+v, want 1000/3000/5000", got)
+	}
+	if estimate.AvgSessions != 1 {
+		t.Errorf("AvgSessions = %v, want 1", estimate.AvgSessions)
+	}
+
+	// Two task samples are too few for any task grouping.
+	estimate, err = EstimateEffort(db, &BeadsIssue{IssueType: "task", Priority: 2})
+	if err != nil {
+		t.Fatalf("EstimateEffort failed: %v", err)
+	}
+	if estimate.Basis != "all" || estimate.SampleSize != 7 {
+		t.Errorf("basis = %s over %d issues, want all over 7", estimate.Basis, estimate.SampleSize)
+	}
+}
+
+func TestEstimateIssueEffortUnknownIssue(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := EstimateIssueEffort(db, "agents-404"); err == nil {
+		t.Error("expected an error for an unknown issue")
+	}
+}
@@ -0,0 +1,297 @@
+package agent_tracking
+
+import (
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Event types published by the tracking functions.
+const (
+	EventSessionStarted = "session_started"
+	EventSessionEnded   = "session_ended"
+	EventSessionUpdated = "session_updated"
+	EventWorkStarted    = "work_started"
+	EventWorkCompleted  = "work_completed"
+	EventSkillUsed      = "skill_used"
+	EventTokensUpdated  = "tokens_updated"
+	EventBudgetExceeded = "budget_exceeded"
+)
+
+// Event describes a change to the tracking tables. Only the fields relevant to
+// the event type are set, plus the session's agent, workspace and model tier and,
+// for work events, the work's session and issue. ID is the change log position
+// and is only set on events read from the change log.
+//
+// Events published in this process also remember the database they were written
+// to, so subscriptions can be scoped to one database with SubscribeOptions.DB.
+type Event struct {
+	ID            int64          `json:"id,omitempty"`
+	Type          string         `json:"type"`
+	Time          time.Time      `json:"time"`
+	SessionID     string         `json:"session_id,omitempty"`
+	AgentName     string         `json:"agent_name,omitempty"`
+	WorkspacePath string         `json:"workspace_path,omitempty"`
+	ModelTier     string         `json:"model_tier,omitempty"`
+	ExitReason    ExitReason     `json:"exit_reason,omitempty"`
+	WorkID        string         `json:"work_id,omitempty"`
+	IssueID       string         `json:"issue_id,omitempty"`
+	Notes         string         `json:"notes,omitempty"`
+	SkillName     string         `json:"skill_name,omitempty"`
+	ContextAdded  int            `json:"context_added,omitempty"`
+	Tokens        int            `json:"tokens,omitempty"`
+	Issues        []string       `json:"issues,omitempty"`
+	Skills        []string       `json:"skills,omitempty"`
+	Budgets       []BudgetStatus `json:"budgets,omitempty"`
+
+	db *sql.DB
+}
+
+// eventHooks holds the functions called for every published event.
+var eventHooks = struct {
+	sync.RWMutex
+	next  int
+	hooks map[int]func(Event)
+}{hooks: make(map[int]func(Event))}
+
+// AddEventHook registers a function that is called synchronously, after the
+// database write committed, for every event published in this process, whatever
+// database it was written to. Hooks must return quickly. The returned function
+// removes the hook.
+//
+// Example:
+//
+//	remove := agent_tracking.AddEventHook(func(e agent_tracking.Event) {
+//	    log.Printf("%s %s", e.Type, e.SessionID)
+//	})
+//	defer remove()
+func AddEventHook(hook func(Event)) (remove func()) {
+	eventHooks.Lock()
+	id := eventHooks.next
+	eventHooks.next++
+	eventHooks.hooks[id] = hook
+	eventHooks.Unlock()
+
+	return func() {
+		eventHooks.Lock()
+		delete(eventHooks.hooks, id)
+		eventHooks.Unlock()
+	}
+}
+
+// hasEventHooks reports whether any hook would receive a published event.
+func hasEventHooks() bool {
+	eventHooks.RLock()
+	defer eventHooks.RUnlock()
+	return len(eventHooks.hooks) > 0
+}
+
+// publishEvent stamps an event with its time and source database and passes it
+// to every registered hook. It must only be called once the write the event
+// describes has committed.
+func publishEvent(db *sql.DB, e Event) {
+	e.db = db
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	eventHooks.RLock()
+	hooks := make([]func(Event), 0, len(eventHooks.hooks))
+	for _, hook := range eventHooks.hooks {
+		hooks = append(hooks, hook)
+	}
+	eventHooks.RUnlock()
+
+	for _, hook := range hooks {
+		hook(e)
+	}
+}
+
+// publishSessionEvent fills in the session and work context of an event from the
+// database and publishes it. Lookups are skipped when nothing is listening, and
+// an event whose lookup fails is still published with the fields it has.
+func publishSessionEvent(db *sql.DB, e Event) {
+	if !hasEventHooks() {
+		return
+	}
+
+	if e.WorkID != "" && (e.SessionID == "" || e.IssueID == "" || e.AgentName == "") {
+		var sessionID, issueID, agentName string
+		err := db.QueryRow(`
+			SELECT session_id, issue_id, agent_name FROM agent_issue_work WHERE work_id = ?
+		`, e.WorkID).Scan(&sessionID, &issueID, &agentName)
+		if err == nil {
+			if e.SessionID == "" {
+				e.SessionID = sessionID
+			}
+			if e.IssueID == "" {
+				e.IssueID = issueID
+			}
+			if e.AgentName == "" {
+				e.AgentName = agentName
+			}
+		}
+	}
+
+	if e.SessionID != "" && (e.AgentName == "" || e.WorkspacePath == "") {
+		var agentName, workspacePath string
+		var modelTier sql.NullString
+		err := db.QueryRow(`
+			SELECT agent_name, workspace_path, model_tier FROM agent_sessions WHERE session_id = ?
+		`, e.SessionID).Scan(&agentName, &workspacePath, &modelTier)
+		if err == nil {
+			if e.AgentName == "" {
+				e.AgentName = agentName
+			}
+			e.WorkspacePath = workspacePath
+			if e.ModelTier == "" {
+				e.ModelTier = modelTier.String
+			}
+		}
+	}
+
+	publishEvent(db, e)
+}
+
+// EventFilter selects events for a subscription. Empty fields match everything;
+// Types matches any of the listed event types, and WorkspacePath matches every
+// spelling of the path that NormalizeWorkspacePath maps to the same workspace.
+type EventFilter struct {
+	Types         []string
+	SessionID     string
+	AgentName     string
+	WorkspacePath string
+	IssueID       string
+}
+
+// Matches reports whether an event passes the filter.
+func (f EventFilter) Matches(e Event) bool {
+	if len(f.Types) > 0 {
+		found := false
+		for _, t := range f.Types {
+			if t == e.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.SessionID != "" && f.SessionID != e.SessionID {
+		return false
+	}
+	if f.AgentName != "" && f.AgentName != e.AgentName {
+		return false
+	}
+	if f.WorkspacePath != "" && NormalizeWorkspacePath(f.WorkspacePath) != NormalizeWorkspacePath(e.WorkspacePath) {
+		return false
+	}
+	if f.IssueID != "" && f.IssueID != e.IssueID {
+		return false
+	}
+	return true
+}
+
+// SubscribeOptions configures a subscription.
+type SubscribeOptions struct {
+	DB         *sql.DB // Only events written to this database (nil for every database in the process)
+	Filter     EventFilter
+	BufferSize int         // Events buffered before new ones are dropped (default 256)
+	OnDrop     func(Event) // Called, from the publishing goroutine, for each dropped event
+}
+
+// Subscription receives published events on a buffered channel. Publishing never
+// blocks on a slow subscriber: when the buffer is full, the event is dropped.
+type Subscription struct {
+	db      *sql.DB
+	filter  EventFilter
+	onDrop  func(Event)
+	events  chan Event
+	mu      sync.Mutex
+	closed  bool
+	dropped int64
+	remove  func()
+}
+
+// Subscribe starts delivering events that match the filter. Events arrive in the
+// order they were published, and only after the write they describe has committed.
+// Call Close to stop the subscription; the channel is closed once it stops.
+//
+// Example:
+//
+//	sub := agent_tracking.Subscribe(agent_tracking.SubscribeOptions{
+//	    DB: db,
+//	    Filter: agent_tracking.EventFilter{
+//	        Types:     []string{agent_tracking.EventWorkStarted, agent_tracking.EventWorkCompleted},
+//	        AgentName: "beads-workflow-orchestrator",
+//	    },
+//	})
+//	defer sub.Close()
+//	for e := range sub.Events() {
+//	    fmt.Printf("%s %s\n", e.Type, e.IssueID)
+//	}
+func Subscribe(opts SubscribeOptions) *Subscription {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 256
+	}
+
+	sub := &Subscription{
+		db:     opts.DB,
+		filter: opts.Filter,
+		onDrop: opts.OnDrop,
+		events: make(chan Event, opts.BufferSize),
+	}
+	sub.remove = AddEventHook(sub.publish)
+	return sub
+}
+
+// Events returns the channel events are delivered on.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped returns how many matching events were dropped because the buffer was full.
+func (s *Subscription) Dropped() int64 {
+	return atomic.LoadInt64(&s.dropped)
+}
+
+// Close stops the subscription and closes its channel. Buffered events can still
+// be read after Close.
+func (s *Subscription) Close() {
+	s.remove()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+}
+
+// publish delivers an event to the subscription without blocking.
+func (s *Subscription) publish(e Event) {
+	if s.db != nil && s.db != e.db {
+		return
+	}
+	if !s.filter.Matches(e) {
+		return
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	select {
+	case s.events <- e:
+		s.mu.Unlock()
+	default:
+		s.mu.Unlock()
+		atomic.AddInt64(&s.dropped, 1)
+		if s.onDrop != nil {
+			s.onDrop(e)
+		}
+	}
+}
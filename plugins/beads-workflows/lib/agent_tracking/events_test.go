@@ -0,0 +1,146 @@
+package agent_tracking
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// nextEvent reads one event from a subscription, failing the test on timeout.
+func nextEvent(t *testing.T, sub *Subscription) Event {
+	t.Helper()
+	select {
+	case e, ok := <-sub.Events():
+		if !ok {
+			t.Fatal("subscription closed")
+		}
+		return e
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+	return Event{}
+}
+
+func TestSubscribeDeliversEventsInOrder(t *testing.T) {
+	db := openTestDB(t)
+	sub := Subscribe(SubscribeOptions{DB: db})
+	defer sub.Close()
+
+	sessionID, err := StartSession(db, "agent", "/ws", "sonnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	workID, err := RecordWork(db, sessionID, "agents-1", "agent", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CompleteWork(db, workID, "done"); err != nil {
+		t.Fatal(err)
+	}
+	if err := EndSession(db, sessionID, ExitCompleted); err != nil {
+		t.Fatal(err)
+	}
+
+	var types []string
+	for i := 0; i < 4; i++ {
+		e := nextEvent(t, sub)
+		types = append(types, e.Type)
+		if e.SessionID != sessionID || e.AgentName != "agent" || e.WorkspacePath != "/ws" || e.ModelTier != "sonnet" {
+			t.Errorf("%s event context = %+v", e.Type, e)
+		}
+	}
+	if got := strings.Join(types, ","); got != "session_started,work_started,work_completed,session_ended" {
+		t.Errorf("events = %s", got)
+	}
+}
+
+func TestSubscribeScopedToDatabase(t *testing.T) {
+	first := openTestDB(t)
+	second := openTestDB(t)
+
+	scoped := Subscribe(SubscribeOptions{DB: first})
+	defer scoped.Close()
+	everywhere := Subscribe(SubscribeOptions{Filter: EventFilter{Types: []string{EventSessionStarted}}})
+	defer everywhere.Close()
+
+	if _, err := StartSession(second, "other", "/b", "haiku"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := StartSession(first, "mine", "/a", "haiku"); err != nil {
+		t.Fatal(err)
+	}
+
+	if e := nextEvent(t, scoped); e.AgentName != "mine" {
+		t.Errorf("scoped subscription got an event from %s, want only mine", e.AgentName)
+	}
+	if a, b := nextEvent(t, everywhere), nextEvent(t, everywhere); a.AgentName != "other" || b.AgentName != "mine" {
+		t.Errorf("unscoped subscription got %s and %s", a.AgentName, b.AgentName)
+	}
+}
+
+func TestSubscribeDropsWhenFull(t *testing.T) {
+	db := openTestDB(t)
+	var dropped []string
+	sub := Subscribe(SubscribeOptions{
+		DB:         db,
+		Filter:     EventFilter{AgentName: "agent"},
+		BufferSize: 1,
+		OnDrop:     func(e Event) { dropped = append(dropped, e.Type) },
+	})
+
+	sessionID, err := StartSession(db, "agent", "/ws", "sonnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := StartSession(db, "someone-else", "/ws", "sonnet"); err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateSessionTokens(db, sessionID, 100); err != nil {
+		t.Fatal(err)
+	}
+	sub.Close()
+
+	if sub.Dropped() != 1 || len(dropped) != 1 || dropped[0] != EventTokensUpdated {
+		t.Errorf("dropped %d events (%v), want the tokens update", sub.Dropped(), dropped)
+	}
+	if e := nextEvent(t, sub); e.Type != EventSessionStarted {
+		t.Errorf("buffered event = %s, want session_started", e.Type)
+	}
+	if _, ok := <-sub.Events(); ok {
+		t.Error("channel still open after Close")
+	}
+}
+
+func TestNotifierIgnoresOtherDatabases(t *testing.T) {
+	db := openTestDB(t)
+	other := openTestDB(t)
+	receiver, server := newWebhookReceiver(t, "")
+
+	var errs []error
+	notifier, err := NewNotifier(db, NotifierConfig{
+		Webhooks: []Webhook{{Name: "test", URL: server.URL}},
+		OnError:  func(err error) { errs = append(errs, err) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Work in another database must not be looked up in the notifier's.
+	for _, agent := range []string{"a", "b", "c"} {
+		sessionID, err := StartSession(other, agent, "/ws", "sonnet")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := RecordWork(other, sessionID, "agents-1", agent, ""); err != nil {
+			t.Fatal(err)
+		}
+		if err := EndSession(other, sessionID, ExitError); err != nil {
+			t.Fatal(err)
+		}
+	}
+	notifier.Close()
+
+	if rules := receiver.rules(); len(rules) != 0 || len(errs) != 0 {
+		t.Errorf("notifier reacted to another database: notifications %v, errors %v", rules, errs)
+	}
+}
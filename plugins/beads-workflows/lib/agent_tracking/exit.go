@@ -0,0 +1,364 @@
+package agent_tracking
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ExitReason is the reason a session ended.
+type ExitReason string
+
+// Exit reasons accepted by EndSession.
+const (
+	ExitCompleted    ExitReason = "completed"     // Session ended normally after finishing work
+	ExitInterrupted  ExitReason = "interrupted"   // Session was interrupted by user or system
+	ExitError        ExitReason = "error"         // Session ended due to an error
+	ExitTimeout      ExitReason = "timeout"       // Session exceeded time limit
+	ExitContextLimit ExitReason = "context_limit" // Session ended due to context window limit
+)
+
+// ExitReasons returns the exit reasons accepted by EndSession.
+func ExitReasons() []ExitReason {
+	return []ExitReason{ExitCompleted, ExitInterrupted, ExitError, ExitTimeout, ExitContextLimit}
+}
+
+// Valid reports whether r is one of the known exit reasons.
+func (r ExitReason) Valid() bool {
+	for _, known := range ExitReasons() {
+		if r == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Failed reports whether r means the session failed: it ended with an error,
+// timed out or ran out of context.
+func (r ExitReason) Failed() bool {
+	return r == ExitError || r == ExitTimeout || r == ExitContextLimit
+}
+
+// ParseExitReason converts a string, such as a command-line flag, to an exit reason.
+//
+// Example:
+//
+//	reason, err := agent_tracking.ParseExitReason(*exitFlag)
+//	if err != nil {
+//	    return err
+//	}
+//	err = agent_tracking.EndSession(db, sessionID, reason)
+func ParseExitReason(s string) (ExitReason, error) {
+	r := ExitReason(strings.TrimSpace(s))
+	if !r.Valid() {
+		return "", fmt.Errorf("invalid exit reason: %q", s)
+	}
+	return r, nil
+}
+
+// Exit reason groupings for GetExitReasonStats.
+const (
+	ExitGroupAgent     = "agent"
+	ExitGroupModelTier = "model_tier"
+	ExitGroupWorkspace = "workspace"
+)
+
+// Failure trend periods for GetFailureTrend. Periods start at midnight UTC
+// (weeks on Monday).
+const (
+	FailurePeriodDay  = "day"
+	FailurePeriodWeek = "week"
+)
+
+// unclassifiedFailure is the class reported for failed sessions ended without
+// failure details.
+const unclassifiedFailure = "unclassified"
+
+// FailureDetails describes what went wrong in a session that did not complete.
+type FailureDetails struct {
+	Class   string `json:"class"`             // Error class, e.g. "tool_error", "test_failure", "merge_conflict"
+	Message string `json:"message,omitempty"` // Error message
+	Phase   string `json:"phase,omitempty"`   // Phase that failed, e.g. "planning", "implementation", "verification"
+}
+
+// SessionFailure is the failure recorded for an ended session.
+type SessionFailure struct {
+	SessionID  string         `json:"session_id"`
+	ExitReason ExitReason     `json:"exit_reason"`
+	Details    FailureDetails `json:"details"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// ExitReasonStats is the distribution of exit reasons for ended sessions in one
+// group. Failure rate is the share of sessions that ended with a failed reason.
+type ExitReasonStats struct {
+	GroupBy     string             `json:"group_by"`
+	Group       string             `json:"group"`
+	Sessions    int                `json:"sessions"`
+	Reasons     map[ExitReason]int `json:"reasons"`
+	Failed      int                `json:"failed"`
+	FailureRate float64            `json:"failure_rate"`
+}
+
+// FailureClassCount is the number of failed sessions with a failure class.
+type FailureClassCount struct {
+	Class string `json:"class"`
+	Count int    `json:"count"`
+}
+
+// FailurePeriod contains the failure classes of sessions that ended in one period,
+// most common first.
+type FailurePeriod struct {
+	Start   time.Time           `json:"start"`
+	Failed  int                 `json:"failed"`
+	Classes []FailureClassCount `json:"classes"`
+}
+
+// FailureTrend contains failure classes over time. Classes totals every period,
+// most common first; failed sessions ended without details count as "unclassified".
+type FailureTrend struct {
+	Period  string              `json:"period"`
+	Since   time.Time           `json:"since"`
+	Classes []FailureClassCount `json:"classes"`
+	Periods []FailurePeriod     `json:"periods"`
+}
+
+// EndSessionWithFailure ends a session like EndSession and records what went
+// wrong. Details can't be recorded for completed sessions.
+//
+// Example:
+//
+//	err := agent_tracking.EndSessionWithFailure(db, sessionID, agent_tracking.ExitError, agent_tracking.FailureDetails{
+//	    Class:   "test_failure",
+//	    Message: "go test ./... failed: 3 tests",
+//	    Phase:   "verification",
+//	})
+func EndSessionWithFailure(db *sql.DB, sessionID string, exitReason ExitReason, details FailureDetails) error {
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+	if sessionID == "" {
+		return fmt.Errorf("session ID is required")
+	}
+	if !exitReason.Valid() {
+		return fmt.Errorf("invalid exit reason: %q", exitReason)
+	}
+	if exitReason == ExitCompleted {
+		return fmt.Errorf("failure details cannot be recorded for a completed session")
+	}
+	if details.Class == "" {
+		return fmt.Errorf("error class is required")
+	}
+
+	return endSession(db, sessionID, exitReason, &details)
+}
+
+// GetSessionFailure returns the failure recorded for a session, or nil if the
+// session was ended without failure details.
+//
+// Example:
+//
+//	failure, err := agent_tracking.GetSessionFailure(db, sessionID)
+//	if err == nil && failure != nil {
+//	    fmt.Printf("%s during %s: %s\n", failure.Details.Class, failure.Details.Phase, failure.Details.Message)
+//	}
+func GetSessionFailure(db *sql.DB, sessionID string) (*SessionFailure, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	if sessionID == "" {
+		return nil, fmt.Errorf("session ID is required")
+	}
+
+	var failure SessionFailure
+	var exitReason, message, phase sql.NullString
+	var createdAtStr string
+	err := db.QueryRow(`
+		SELECT f.session_id, s.exit_reason, f.error_class, f.error_message, f.failed_phase, f.created_at
+		FROM agent_session_failures f
+		JOIN agent_sessions s ON s.session_id = f.session_id
+		WHERE f.session_id = ?
+	`, sessionID).Scan(&failure.SessionID, &exitReason, &failure.Details.Class, &message, &phase, &createdAtStr)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session failure: %w", err)
+	}
+
+	failure.ExitReason = ExitReason(exitReason.String)
+	failure.Details.Message = message.String
+	failure.Details.Phase = phase.String
+	failure.CreatedAt, err = parseTime(createdAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse created_at: %w", err)
+	}
+
+	return &failure, nil
+}
+
+// GetExitReasonStats returns the exit reason distribution of sessions started
+// since a given time and ended, grouped by agent, model tier or workspace (one of
+// the ExitGroup constants). Workspaces are grouped by NormalizeWorkspacePath.
+// Groups are ordered by session count.
+//
+// Example:
+//
+//	stats, err := agent_tracking.GetExitReasonStats(db, agent_tracking.ExitGroupAgent, time.Now().AddDate(0, -1, 0))
+//	for _, s := range stats {
+//	    fmt.Printf("%s: %d sessions, %.0f%% failed, %d timeouts\n",
+//	        s.Group, s.Sessions, s.FailureRate*100, s.Reasons[agent_tracking.ExitTimeout])
+//	}
+func GetExitReasonStats(db *sql.DB, groupBy string, since time.Time) ([]ExitReasonStats, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	var column string
+	switch groupBy {
+	case ExitGroupAgent:
+		column = "agent_name"
+	case ExitGroupModelTier:
+		column = "COALESCE(model_tier, '')"
+	case ExitGroupWorkspace:
+		column = "workspace_path"
+	default:
+		return nil, fmt.Errorf("invalid exit reason grouping: %q", groupBy)
+	}
+
+	rows, err := db.Query(`
+		SELECT `+column+`, COALESCE(exit_reason, ''), COUNT(*)
+		FROM agent_sessions
+		WHERE ended_at IS NOT NULL AND started_at >= ?
+		GROUP BY 1, 2
+	`, formatTime(since))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exit reasons: %w", err)
+	}
+	defer rows.Close()
+
+	groups := make(map[string]*ExitReasonStats)
+	for rows.Next() {
+		var group, reason string
+		var count int
+		if err := rows.Scan(&group, &reason, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan exit reason: %w", err)
+		}
+		if groupBy == ExitGroupWorkspace {
+			group = NormalizeWorkspacePath(group)
+		}
+
+		stats, ok := groups[group]
+		if !ok {
+			stats = &ExitReasonStats{GroupBy: groupBy, Group: group, Reasons: make(map[ExitReason]int)}
+			groups[group] = stats
+		}
+		stats.Sessions += count
+		stats.Reasons[ExitReason(reason)] += count
+		if ExitReason(reason).Failed() {
+			stats.Failed += count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating exit reasons: %w", err)
+	}
+
+	result := make([]ExitReasonStats, 0, len(groups))
+	for _, stats := range groups {
+		stats.FailureRate = float64(stats.Failed) / float64(stats.Sessions)
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Sessions != result[j].Sessions {
+			return result[i].Sessions > result[j].Sessions
+		}
+		return result[i].Group < result[j].Group
+	})
+
+	return result, nil
+}
+
+// GetFailureTrend returns the failure classes of sessions started since a given
+// time, per day or week they ended in (FailurePeriodDay or FailurePeriodWeek).
+// A session counts as failed when it ended with a failed exit reason or has
+// failure details. Periods without failures are omitted.
+//
+// Example:
+//
+//	trend, err := agent_tracking.GetFailureTrend(db, time.Now().AddDate(0, -2, 0), agent_tracking.FailurePeriodWeek)
+//	for _, p := range trend.Periods {
+//	    fmt.Printf("%s: %d failed, most often %s\n", p.Start.Format("2006-01-02"), p.Failed, p.Classes[0].Class)
+//	}
+func GetFailureTrend(db *sql.DB, since time.Time, period string) (*FailureTrend, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	if period != FailurePeriodDay && period != FailurePeriodWeek {
+		return nil, fmt.Errorf("invalid failure period: %q", period)
+	}
+
+	rows, err := db.Query(`
+		SELECT s.ended_at, COALESCE(f.error_class, '')
+		FROM agent_sessions s
+		LEFT JOIN agent_session_failures f ON f.session_id = s.session_id
+		WHERE s.ended_at IS NOT NULL AND s.started_at >= ?
+		  AND (f.session_id IS NOT NULL OR s.exit_reason IN (?, ?, ?))
+		ORDER BY s.ended_at
+	`, formatTime(since), ExitError, ExitTimeout, ExitContextLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get failures: %w", err)
+	}
+	defer rows.Close()
+
+	trend := &FailureTrend{Period: period, Since: since, Classes: []FailureClassCount{}, Periods: []FailurePeriod{}}
+	totals := make(map[string]int)
+	var periodStarts []time.Time
+	periodCounts := make(map[time.Time]map[string]int)
+	for rows.Next() {
+		var endedAtStr, class string
+		if err := rows.Scan(&endedAtStr, &class); err != nil {
+			return nil, fmt.Errorf("failed to scan failure: %w", err)
+		}
+		endedAt, err := parseTime(endedAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ended_at: %w", err)
+		}
+		if class == "" {
+			class = unclassifiedFailure
+		}
+
+		start := budgetPeriodStart(period, endedAt)
+		if periodCounts[start] == nil {
+			periodCounts[start] = make(map[string]int)
+			periodStarts = append(periodStarts, start)
+		}
+		periodCounts[start][class]++
+		totals[class]++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating failures: %w", err)
+	}
+
+	trend.Classes = failureClassCounts(totals)
+	for _, start := range periodStarts {
+		p := FailurePeriod{Start: start, Classes: failureClassCounts(periodCounts[start])}
+		for _, c := range p.Classes {
+			p.Failed += c.Count
+		}
+		trend.Periods = append(trend.Periods, p)
+	}
+
+	return trend, nil
+}
+
+// failureClassCounts returns counts by class, most common first.
+func failureClassCounts(counts map[string]int) []FailureClassCount {
+	sorted := sortedDigestCounts(counts)
+	classes := make([]FailureClassCount, len(sorted))
+	for i, c := range sorted {
+		classes[i] = FailureClassCount{Class: c.Label, Count: c.Count}
+	}
+	return classes
+}
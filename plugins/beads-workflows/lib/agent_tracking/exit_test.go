@@ -0,0 +1,165 @@
+package agent_tracking
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseExitReason(t *testing.T) {
+	for _, r := range ExitReasons() {
+		got, err := ParseExitReason(" " + string(r) + "\n")
+		if err != nil || got != r {
+			t.Errorf("ParseExitReason(%q) = %q, %v", r, got, err)
+		}
+	}
+	if _, err := ParseExitReason("crashed"); err == nil {
+		t.Error("ParseExitReason accepted an unknown reason")
+	}
+}
+
+func TestEndSessionRejectsUnknownReason(t *testing.T) {
+	db := openTestDB(t)
+	sessionID, err := StartSession(db, "orchestrator", "/ws", "opus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := EndSession(db, sessionID, ExitReason("crashed")); err == nil {
+		t.Fatal("EndSession accepted an unknown reason")
+	}
+	session, err := GetSession(db, sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.EndedAt != nil {
+		t.Error("session ended despite the rejected reason")
+	}
+}
+
+func TestEndSessionReplacesFailure(t *testing.T) {
+	db := openTestDB(t)
+	sessionID, err := StartSession(db, "orchestrator", "/ws", "opus")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := EndSessionWithFailure(db, sessionID, ExitCompleted, FailureDetails{Class: "tool_error"}); err == nil {
+		t.Error("EndSessionWithFailure accepted details for a completed session")
+	}
+	if err := EndSessionWithFailure(db, sessionID, ExitError, FailureDetails{}); err == nil {
+		t.Error("EndSessionWithFailure accepted details without a class")
+	}
+
+	details := FailureDetails{Class: "test_failure", Message: "3 tests failed", Phase: "verification"}
+	if err := EndSessionWithFailure(db, sessionID, ExitError, details); err != nil {
+		t.Fatalf("EndSessionWithFailure failed: %v", err)
+	}
+	failure, err := GetSessionFailure(db, sessionID)
+	if err != nil {
+		t.Fatalf("GetSessionFailure failed: %v", err)
+	}
+	if failure == nil || failure.ExitReason != ExitError || failure.Details != details {
+		t.Fatalf("failure = %+v, want %+v", failure, details)
+	}
+
+	// A retry that finishes cleanly leaves no failure behind.
+	if err := EndSession(db, sessionID, ExitCompleted); err != nil {
+		t.Fatalf("EndSession failed: %v", err)
+	}
+	failure, err = GetSessionFailure(db, sessionID)
+	if err != nil {
+		t.Fatalf("GetSessionFailure failed: %v", err)
+	}
+	if failure != nil {
+		t.Errorf("failure = %+v after a plain EndSession, want none", failure)
+	}
+}
+
+func TestEndSessionWithFailureIsAtomic(t *testing.T) {
+	db := openTestDB(t)
+	sessionID, err := StartSession(db, "orchestrator", "/ws", "opus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustExec(t, db, `DROP TABLE agent_session_failures`)
+
+	if err := EndSessionWithFailure(db, sessionID, ExitError, FailureDetails{Class: "tool_error"}); err == nil {
+		t.Fatal("expected an error recording the failure")
+	}
+	session, err := GetSession(db, sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.EndedAt != nil || session.ExitReason != "" {
+		t.Errorf("session = %+v, want it left open when the failure can't be recorded", session)
+	}
+
+	if err := EndSession(db, "no-such-session", ExitCompleted); err == nil {
+		t.Error("EndSession of an unknown session succeeded")
+	}
+}
+
+func TestExitReasonStatsAndFailureTrend(t *testing.T) {
+	db := openTestDB(t)
+	monday := time.Date(2026, 9, 14, 10, 0, 0, 0, time.UTC)
+
+	end := func(agent, tier string, endedAt time.Time, reason ExitReason, details *FailureDetails) {
+		t.Helper()
+		sessionID, err := StartSession(db, agent, "/ws", tier)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if details != nil {
+			err = EndSessionWithFailure(db, sessionID, reason, *details)
+		} else {
+			err = EndSession(db, sessionID, reason)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		mustExec(t, db, `UPDATE agent_sessions SET started_at = ?, ended_at = ? WHERE session_id = ?`,
+			formatTime(endedAt.Add(-time.Hour)), formatTime(endedAt), sessionID)
+	}
+	end("orchestrator", "opus", monday, ExitCompleted, nil)
+	end("orchestrator", "opus", monday.Add(time.Hour), ExitError, &FailureDetails{Class: "test_failure"})
+	end("orchestrator", "opus", monday.AddDate(0, 0, 1), ExitTimeout, nil)
+	end("reviewer", "haiku", monday.AddDate(0, 0, 7), ExitContextLimit, &FailureDetails{Class: "context"})
+	end("reviewer", "haiku", monday.AddDate(0, 0, 8), ExitInterrupted, nil)
+	if _, err := StartSession(db, "reviewer", "/ws", "haiku"); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := GetExitReasonStats(db, ExitGroupAgent, monday.AddDate(0, 0, -1))
+	if err != nil {
+		t.Fatalf("GetExitReasonStats failed: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	orchestrator := stats[0]
+	if orchestrator.Group != "orchestrator" || orchestrator.Sessions != 3 || orchestrator.Failed != 2 {
+		t.Errorf("orchestrator = %+v", orchestrator)
+	}
+	if want := map[ExitReason]int{ExitCompleted: 1, ExitError: 1, ExitTimeout: 1}; !reflect.DeepEqual(orchestrator.Reasons, want) {
+		t.Errorf("orchestrator reasons = %v, want %v", orchestrator.Reasons, want)
+	}
+	if stats[1].Group != "reviewer" || stats[1].FailureRate != 0.5 {
+		t.Errorf("reviewer = %+v, want the open session ignored", stats[1])
+	}
+	if _, err := GetExitReasonStats(db, "team", time.Time{}); err == nil {
+		t.Error("GetExitReasonStats accepted an unknown grouping")
+	}
+
+	trend, err := GetFailureTrend(db, monday.AddDate(0, 0, -1), FailurePeriodWeek)
+	if err != nil {
+		t.Fatalf("GetFailureTrend failed: %v", err)
+	}
+	wantClasses := []FailureClassCount{{"context", 1}, {"test_failure", 1}, {"unclassified", 1}}
+	if !reflect.DeepEqual(trend.Classes, wantClasses) {
+		t.Errorf("classes = %+v, want %+v", trend.Classes, wantClasses)
+	}
+	if len(trend.Periods) != 2 || !trend.Periods[0].Start.Equal(time.Date(2026, 9, 14, 0, 0, 0, 0, time.UTC)) ||
+		trend.Periods[0].Failed != 2 || trend.Periods[1].Failed != 1 {
+		t.Errorf("periods = %+v", trend.Periods)
+	}
+}
@@ -0,0 +1,337 @@
+package agent_tracking
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export formats.
+const (
+	ExportFormatCSV = "csv"
+	ExportFormatTSV = "tsv"
+)
+
+// ExportOptions controls a bulk export.
+type ExportOptions struct {
+	Format string    // ExportFormatCSV (default) or ExportFormatTSV
+	Since  time.Time // Only rows from sessions started at or after this time
+}
+
+// Column schemas of the exports. Columns are only ever appended, so spreadsheets
+// and scripts that address columns by position keep working.
+var (
+	SessionExportColumns = []string{
+		"session_id", "agent_name", "workspace_path", "model_tier", "started_at", "ended_at",
+		"duration_seconds", "exit_reason", "context_tokens", "issues_claimed", "skills_used",
+	}
+	WorkExportColumns = []string{
+		"work_id", "issue_id", "session_id", "agent_name", "started_at", "ended_at",
+		"duration_seconds", "completed", "decision_rationale", "work_notes",
+	}
+	SkillUsageExportColumns = []string{
+		"usage_id", "session_id", "agent_name", "skill_name", "loaded_at", "used_for_issue_id", "context_added",
+	}
+	AgentStatsExportColumns = []string{
+		"agent_name", "total_sessions", "active_sessions", "total_issues", "completed_issues",
+		"total_skill_uses", "avg_session_seconds", "total_tokens",
+	}
+	SkillStatsExportColumns = []string{
+		"skill_name", "total_uses", "unique_sessions", "unique_agents", "total_context", "avg_context",
+	}
+	IssueStatsExportColumns = []string{
+		"issue_id", "agent_name", "work_sessions", "total_seconds", "completed",
+	}
+)
+
+// ExportSessions streams sessions, oldest first, as CSV or TSV. Claimed issues
+// and skills are joined with ";". Timestamps are ISO 8601 in UTC and durations
+// are whole seconds; both are empty for sessions that haven't ended.
+//
+// Example:
+//
+//	f, err := os.Create("sessions.csv")
+//	if err != nil {
+//	    return err
+//	}
+//	defer f.Close()
+//	err = agent_tracking.ExportSessions(db, f, agent_tracking.ExportOptions{Since: time.Now().AddDate(0, -1, 0)})
+func ExportSessions(db *sql.DB, w io.Writer, opts ExportOptions) error {
+	return exportRows(db, w, opts, SessionExportColumns, `
+		SELECT session_id, agent_name, workspace_path, COALESCE(model_tier, ''), started_at, ended_at,
+		       COALESCE(exit_reason, ''), COALESCE(context_tokens, 0),
+		       COALESCE(issues_claimed, '[]'), COALESCE(skills_used, '[]')
+		FROM agent_sessions
+		WHERE started_at >= ?
+		ORDER BY started_at, session_id
+	`, func(rows *sql.Rows) ([]string, error) {
+		var sessionID, agentName, workspacePath, modelTier, startedAt, exitReason, issues, skills string
+		var endedAt sql.NullString
+		var tokens int
+		err := rows.Scan(&sessionID, &agentName, &workspacePath, &modelTier, &startedAt, &endedAt,
+			&exitReason, &tokens, &issues, &skills)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+
+		start, end, duration, err := exportTimes(startedAt, endedAt)
+		if err != nil {
+			return nil, err
+		}
+		return []string{
+			sessionID, agentName, workspacePath, modelTier, start, end, duration, exitReason,
+			strconv.Itoa(tokens), exportList(issues), exportList(skills),
+		}, nil
+	})
+}
+
+// ExportWork streams work entries of sessions started since opts.Since, oldest
+// first. Work notes and rationales are quoted per RFC 4180 when they contain
+// separators, quotes or line breaks.
+//
+// Example:
+//
+//	err := agent_tracking.ExportWork(db, os.Stdout, agent_tracking.ExportOptions{Format: agent_tracking.ExportFormatTSV})
+func ExportWork(db *sql.DB, w io.Writer, opts ExportOptions) error {
+	return exportRows(db, w, opts, WorkExportColumns, `
+		SELECT w.work_id, w.issue_id, w.session_id, w.agent_name, w.started_at, w.ended_at,
+		       w.completed, COALESCE(w.decision_rationale, ''), COALESCE(w.work_notes, '')
+		FROM agent_issue_work w
+		JOIN agent_sessions s ON s.session_id = w.session_id
+		WHERE s.started_at >= ?
+		ORDER BY w.started_at, w.work_id
+	`, func(rows *sql.Rows) ([]string, error) {
+		var workID, issueID, sessionID, agentName, startedAt, rationale, notes string
+		var endedAt sql.NullString
+		var completed bool
+		err := rows.Scan(&workID, &issueID, &sessionID, &agentName, &startedAt, &endedAt, &completed, &rationale, &notes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan work: %w", err)
+		}
+
+		start, end, duration, err := exportTimes(startedAt, endedAt)
+		if err != nil {
+			return nil, err
+		}
+		return []string{
+			workID, issueID, sessionID, agentName, start, end, duration,
+			strconv.FormatBool(completed), rationale, notes,
+		}, nil
+	})
+}
+
+// ExportSkillUsage streams skill loads of sessions started since opts.Since,
+// oldest first.
+func ExportSkillUsage(db *sql.DB, w io.Writer, opts ExportOptions) error {
+	return exportRows(db, w, opts, SkillUsageExportColumns, `
+		SELECT u.usage_id, u.session_id, s.agent_name, u.skill_name, u.loaded_at,
+		       COALESCE(u.used_for_issue_id, ''), COALESCE(u.context_added, 0)
+		FROM agent_skill_usage u
+		JOIN agent_sessions s ON s.session_id = u.session_id
+		WHERE s.started_at >= ?
+		ORDER BY u.loaded_at, u.usage_id
+	`, func(rows *sql.Rows) ([]string, error) {
+		var usageID, sessionID, agentName, skillName, loadedAt, issueID string
+		var contextAdded int
+		if err := rows.Scan(&usageID, &sessionID, &agentName, &skillName, &loadedAt, &issueID, &contextAdded); err != nil {
+			return nil, fmt.Errorf("failed to scan skill usage: %w", err)
+		}
+
+		loaded, _, _, err := exportTimes(loadedAt, sql.NullString{})
+		if err != nil {
+			return nil, err
+		}
+		return []string{usageID, sessionID, agentName, skillName, loaded, issueID, strconv.Itoa(contextAdded)}, nil
+	})
+}
+
+// ExportAgentStats streams one row per agent with the same figures as
+// GetAgentStats, sorted by agent name.
+func ExportAgentStats(db *sql.DB, w io.Writer, opts ExportOptions) error {
+	return exportRows(db, w, opts, AgentStatsExportColumns, `
+		WITH s AS (
+			SELECT * FROM agent_sessions WHERE started_at >= ?1
+		)
+		SELECT
+			s.agent_name,
+			COUNT(*),
+			SUM(CASE WHEN s.ended_at IS NULL THEN 1 ELSE 0 END),
+			COALESCE((SELECT COUNT(DISTINCT w.issue_id) FROM agent_issue_work w JOIN s s2 ON s2.session_id = w.session_id
+			          WHERE w.agent_name = s.agent_name), 0),
+			COALESCE((SELECT COUNT(DISTINCT CASE WHEN w.completed = 1 THEN w.issue_id END) FROM agent_issue_work w JOIN s s2 ON s2.session_id = w.session_id
+			          WHERE w.agent_name = s.agent_name), 0),
+			COALESCE((SELECT COUNT(*) FROM agent_skill_usage u JOIN s s2 ON s2.session_id = u.session_id
+			          WHERE s2.agent_name = s.agent_name), 0),
+			AVG(CASE WHEN s.ended_at IS NOT NULL THEN (JULIANDAY(s.ended_at) - JULIANDAY(s.started_at)) * 86400 END),
+			COALESCE(SUM(s.context_tokens), 0)
+		FROM s
+		GROUP BY s.agent_name
+		ORDER BY s.agent_name
+	`, func(rows *sql.Rows) ([]string, error) {
+		var agentName string
+		var sessions, active, issues, completed, skillUses, tokens int
+		var avgSeconds sql.NullFloat64
+		if err := rows.Scan(&agentName, &sessions, &active, &issues, &completed, &skillUses, &avgSeconds, &tokens); err != nil {
+			return nil, fmt.Errorf("failed to scan agent stats: %w", err)
+		}
+
+		avg := ""
+		if avgSeconds.Valid {
+			avg = strconv.FormatInt(int64(avgSeconds.Float64+0.5), 10)
+		}
+		return []string{
+			agentName, strconv.Itoa(sessions), strconv.Itoa(active), strconv.Itoa(issues),
+			strconv.Itoa(completed), strconv.Itoa(skillUses), avg, strconv.Itoa(tokens),
+		}, nil
+	})
+}
+
+// ExportSkillStats streams one row per skill with the same figures as
+// GetSkillStats, for skills loaded since opts.Since, sorted by skill name.
+func ExportSkillStats(db *sql.DB, w io.Writer, opts ExportOptions) error {
+	return exportRows(db, w, opts, SkillStatsExportColumns, `
+		SELECT
+			u.skill_name,
+			COUNT(*),
+			COUNT(DISTINCT u.session_id),
+			COUNT(DISTINCT s.agent_name),
+			COALESCE(SUM(u.context_added), 0),
+			COALESCE(AVG(u.context_added), 0)
+		FROM agent_skill_usage u
+		JOIN agent_sessions s ON u.session_id = s.session_id
+		WHERE u.loaded_at >= ?
+		GROUP BY u.skill_name
+		ORDER BY u.skill_name
+	`, func(rows *sql.Rows) ([]string, error) {
+		var skillName string
+		var uses, sessions, agents, totalContext int
+		var avgContext float64
+		if err := rows.Scan(&skillName, &uses, &sessions, &agents, &totalContext, &avgContext); err != nil {
+			return nil, fmt.Errorf("failed to scan skill stats: %w", err)
+		}
+		return []string{
+			skillName, strconv.Itoa(uses), strconv.Itoa(sessions), strconv.Itoa(agents),
+			strconv.Itoa(totalContext), strconv.FormatFloat(avgContext, 'f', 1, 64),
+		}, nil
+	})
+}
+
+// ExportIssueStats streams GetIssueStats' agent breakdown for every issue worked
+// on in sessions started since opts.Since: one row per issue and agent, sorted by
+// issue then agent. Time on work still in progress counts up to now.
+func ExportIssueStats(db *sql.DB, w io.Writer, opts ExportOptions) error {
+	return exportRows(db, w, opts, IssueStatsExportColumns, `
+		SELECT
+			w.issue_id,
+			w.agent_name,
+			COUNT(*),
+			SUM(JULIANDAY(COALESCE(w.ended_at, datetime('now'))) - JULIANDAY(w.started_at)) * 86400,
+			SUM(w.completed)
+		FROM agent_issue_work w
+		WHERE w.issue_id IN (
+			SELECT w2.issue_id FROM agent_issue_work w2
+			JOIN agent_sessions s ON s.session_id = w2.session_id
+			WHERE s.started_at >= ?
+		)
+		GROUP BY w.issue_id, w.agent_name
+		ORDER BY w.issue_id, w.agent_name
+	`, func(rows *sql.Rows) ([]string, error) {
+		var issueID, agentName string
+		var workSessions, completed int
+		var totalSeconds float64
+		if err := rows.Scan(&issueID, &agentName, &workSessions, &totalSeconds, &completed); err != nil {
+			return nil, fmt.Errorf("failed to scan issue stats: %w", err)
+		}
+		return []string{
+			issueID, agentName, strconv.Itoa(workSessions),
+			strconv.FormatInt(int64(totalSeconds+0.5), 10), strconv.Itoa(completed),
+		}, nil
+	})
+}
+
+// exportRows writes the header and then each row as it is read, flushing
+// periodically so that memory use doesn't grow with the table.
+func exportRows(db *sql.DB, w io.Writer, opts ExportOptions, columns []string, query string, scan func(*sql.Rows) ([]string, error)) error {
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	out := csv.NewWriter(w)
+	switch opts.Format {
+	case "", ExportFormatCSV:
+	case ExportFormatTSV:
+		out.Comma = '\t'
+	default:
+		return fmt.Errorf("invalid export format: %s", opts.Format)
+	}
+	out.UseCRLF = true
+
+	rows, err := db.Query(query, formatTime(opts.Since))
+	if err != nil {
+		return fmt.Errorf("failed to query export rows: %w", err)
+	}
+	defer rows.Close()
+
+	if err := out.Write(columns); err != nil {
+		return fmt.Errorf("failed to write export header: %w", err)
+	}
+
+	count := 0
+	for rows.Next() {
+		record, err := scan(rows)
+		if err != nil {
+			return err
+		}
+		if err := out.Write(record); err != nil {
+			return fmt.Errorf("failed to write export row: %w", err)
+		}
+		count++
+		if count%1000 == 0 {
+			out.Flush()
+			if err := out.Error(); err != nil {
+				return fmt.Errorf("failed to write export row: %w", err)
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating export rows: %w", err)
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	return nil
+}
+
+// exportTimes normalizes a start and optional end time to ISO 8601 UTC and
+// returns the whole seconds between them, or empty strings when there is no end.
+func exportTimes(startedAt string, endedAt sql.NullString) (string, string, string, error) {
+	start, err := parseTime(startedAt)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to parse start time: %w", err)
+	}
+	end, err := parseNullableTime(endedAt)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to parse end time: %w", err)
+	}
+	if end == nil {
+		return formatTime(start), "", "", nil
+	}
+	seconds := int64(end.Sub(start).Round(time.Second) / time.Second)
+	return formatTime(start), formatTime(*end), strconv.FormatInt(seconds, 10), nil
+}
+
+// exportList joins a stored JSON array of strings with ";".
+func exportList(jsonList string) string {
+	var items []string
+	if err := json.Unmarshal([]byte(jsonList), &items); err != nil {
+		return ""
+	}
+	return strings.Join(items, ";")
+}
@@ -0,0 +1,148 @@
+package agent_tracking
+
+import (
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readExport parses an export back into records, checking the header.
+func readExport(t *testing.T, out string, comma rune, columns []string) [][]string {
+	t.Helper()
+	r := csv.NewReader(strings.NewReader(out))
+	r.Comma = comma
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("export doesn't parse: %v\n%s", err, out)
+	}
+	if len(records) == 0 || !reflect.DeepEqual(records[0], columns) {
+		t.Fatalf("header = %v, want %v", records, columns)
+	}
+	return records[1:]
+}
+
+func TestExportSessionsAndWork(t *testing.T) {
+	db := openTestDB(t)
+	start := time.Date(2026, 9, 14, 9, 0, 0, 0, time.UTC)
+
+	old, err := StartSession(db, "orchestrator", "/ws", "opus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustExec(t, db, `UPDATE agent_sessions SET started_at = ? WHERE session_id = ?`, formatTime(start.AddDate(0, 0, -7)), old)
+
+	sessionID, err := StartSession(db, "orchestrator", "/ws", "opus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateSessionIssues(db, sessionID, []string{"agents-1", "agents-2"}); err != nil {
+		t.Fatal(err)
+	}
+	workID, err := RecordWork(db, sessionID, "agents-1", "orchestrator", "ready, highest priority")
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes := "Fixed the \"parser\".\nAdded a test."
+	if err := CompleteWork(db, workID, notes); err != nil {
+		t.Fatal(err)
+	}
+	if err := EndSession(db, sessionID, ExitCompleted); err != nil {
+		t.Fatal(err)
+	}
+	mustExec(t, db, `UPDATE agent_sessions SET started_at = ?, ended_at = ? WHERE session_id = ?`,
+		formatTime(start), formatTime(start.Add(90*time.Minute)), sessionID)
+	mustExec(t, db, `UPDATE agent_issue_work SET started_at = ?, ended_at = ? WHERE work_id = ?`,
+		formatTime(start.Add(time.Minute)), formatTime(start.Add(31*time.Minute)), workID)
+
+	var b strings.Builder
+	if err := ExportSessions(db, &b, ExportOptions{Since: start}); err != nil {
+		t.Fatalf("ExportSessions failed: %v", err)
+	}
+	sessions := readExport(t, b.String(), ',', SessionExportColumns)
+	want := []string{
+		sessionID, "orchestrator", "/ws", "opus", "2026-09-14T09:00:00Z", "2026-09-14T10:30:00Z",
+		"5400", "completed", "0", "agents-1;agents-2", "",
+	}
+	if len(sessions) != 1 || !reflect.DeepEqual(sessions[0], want) {
+		t.Errorf("sessions = %q, want only %q", sessions, want)
+	}
+	if !strings.HasSuffix(b.String(), "\r\n") {
+		t.Error("rows don't end with CRLF")
+	}
+
+	b.Reset()
+	if err := ExportWork(db, &b, ExportOptions{Format: ExportFormatTSV, Since: start}); err != nil {
+		t.Fatalf("ExportWork failed: %v", err)
+	}
+	work := readExport(t, b.String(), '\t', WorkExportColumns)
+	want = []string{
+		workID, "agents-1", sessionID, "orchestrator", "2026-09-14T09:01:00Z", "2026-09-14T09:31:00Z",
+		"1800", "true", "ready, highest priority", notes,
+	}
+	if len(work) != 1 || !reflect.DeepEqual(work[0], want) {
+		t.Errorf("work = %q, want %q", work, want)
+	}
+}
+
+func TestExportStats(t *testing.T) {
+	db := openTestDB(t)
+	sessionID, err := StartSession(db, "orchestrator", "/ws", "opus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	workID, err := RecordWork(db, sessionID, "agents-1", "orchestrator", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CompleteWork(db, workID, "done"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordSkillUsage(db, sessionID, "dependency-thinking", "agents-1", 300); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordSkillUsage(db, sessionID, "dependency-thinking", "", 100); err != nil {
+		t.Fatal(err)
+	}
+	since := time.Now().Add(-time.Hour)
+
+	var b strings.Builder
+	if err := ExportAgentStats(db, &b, ExportOptions{Since: since}); err != nil {
+		t.Fatalf("ExportAgentStats failed: %v", err)
+	}
+	agents := readExport(t, b.String(), ',', AgentStatsExportColumns)
+	if want := []string{"orchestrator", "1", "1", "1", "1", "2", "", "0"}; len(agents) != 1 || !reflect.DeepEqual(agents[0], want) {
+		t.Errorf("agent stats = %q, want %q", agents, want)
+	}
+
+	b.Reset()
+	if err := ExportSkillStats(db, &b, ExportOptions{Since: since}); err != nil {
+		t.Fatalf("ExportSkillStats failed: %v", err)
+	}
+	skills := readExport(t, b.String(), ',', SkillStatsExportColumns)
+	if want := []string{"dependency-thinking", "2", "1", "1", "400", "200.0"}; len(skills) != 1 || !reflect.DeepEqual(skills[0], want) {
+		t.Errorf("skill stats = %q, want %q", skills, want)
+	}
+
+	b.Reset()
+	if err := ExportIssueStats(db, &b, ExportOptions{Since: since}); err != nil {
+		t.Fatalf("ExportIssueStats failed: %v", err)
+	}
+	issues := readExport(t, b.String(), ',', IssueStatsExportColumns)
+	if len(issues) != 1 || issues[0][0] != "agents-1" || issues[0][2] != "1" || issues[0][4] != "1" {
+		t.Errorf("issue stats = %q", issues)
+	}
+}
+
+func TestExportRejectsUnknownFormat(t *testing.T) {
+	db := openTestDB(t)
+	var b strings.Builder
+	err := ExportSessions(db, &b, ExportOptions{Format: "xlsx"})
+	if err == nil || !strings.Contains(err.Error(), "invalid export format") {
+		t.Errorf("err = %v, want an invalid format error", err)
+	}
+	if b.Len() != 0 {
+		t.Errorf("wrote %q before rejecting the format", b.String())
+	}
+}
@@ -0,0 +1,429 @@
+package agent_tracking
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Project is one beads database in a federation.
+type Project struct {
+	Name string
+	DB   *sql.DB
+}
+
+// ProjectOverallStats is one project's share of federated overall stats.
+type ProjectOverallStats struct {
+	Project string        `json:"project"`
+	Stats   *OverallStats `json:"stats"`
+}
+
+// FederatedOverallStats combines overall stats across projects.
+type FederatedOverallStats struct {
+	Combined *OverallStats         `json:"combined"`
+	Projects []ProjectOverallStats `json:"projects"`
+}
+
+// ProjectAgentStats is one project's share of federated agent stats.
+type ProjectAgentStats struct {
+	Project string      `json:"project"`
+	Stats   *AgentStats `json:"stats"`
+}
+
+// FederatedAgentStats combines an agent's stats across projects.
+type FederatedAgentStats struct {
+	Combined *AgentStats         `json:"combined"`
+	Projects []ProjectAgentStats `json:"projects"`
+}
+
+// Federation runs stats queries across several beads databases, one connection
+// pool per project, and merges the results. Projects without agent tracking
+// tables count as having no activity.
+type Federation struct {
+	projects []Project
+	owned    []*sql.DB
+}
+
+// NewFederation creates a federation over databases the caller has opened.
+// Project names must be unique.
+//
+// Example:
+//
+//	fed, err := agent_tracking.NewFederation([]agent_tracking.Project{
+//	    {Name: "agents", DB: agentsDB},
+//	    {Name: "website", DB: websiteDB},
+//	})
+func NewFederation(projects []Project) (*Federation, error) {
+	if len(projects) == 0 {
+		return nil, fmt.Errorf("at least one project is required")
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range projects {
+		if p.Name == "" {
+			return nil, fmt.Errorf("project name is required")
+		}
+		if p.DB == nil {
+			return nil, fmt.Errorf("database connection is nil for project %s", p.Name)
+		}
+		if seen[p.Name] {
+			return nil, fmt.Errorf("duplicate project name: %s", p.Name)
+		}
+		seen[p.Name] = true
+	}
+
+	return &Federation{projects: append([]Project(nil), projects...)}, nil
+}
+
+// OpenFederation opens each beads database read-only with the given SQLite
+// driver and creates a federation over them. Projects are named after the
+// directory containing .beads, e.g. "agents" for /src/agents/.beads/beads.db.
+// Projects whose directories share a name get parent directories added until
+// the names differ, e.g. "a/api" and "b/api". Use NewFederation to choose names.
+// Close releases the databases.
+//
+// Example:
+//
+//	import _ "github.com/mattn/go-sqlite3"
+//
+//	paths, _ := filepath.Glob("/src/*/.beads/beads.db")
+//	fed, err := agent_tracking.OpenFederation("sqlite3", paths)
+//	if err != nil {
+//	    return err
+//	}
+//	defer fed.Close()
+func OpenFederation(driverName string, paths []string) (*Federation, error) {
+	if driverName == "" {
+		return nil, fmt.Errorf("driver name is required")
+	}
+
+	var projects []Project
+	var owned []*sql.DB
+	closeAll := func() {
+		for _, db := range owned {
+			db.Close()
+		}
+	}
+
+	absPaths := make([]string, len(paths))
+	for i, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", path, err)
+		}
+		absPaths[i] = abs
+	}
+	names := projectNames(absPaths)
+
+	for i, path := range paths {
+		abs := absPaths[i]
+
+		dsn := (&url.URL{Scheme: "file", Path: abs, RawQuery: "mode=ro"}).String()
+		db, err := sql.Open(driverName, dsn)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		owned = append(owned, db)
+		if err := db.Ping(); err != nil {
+			closeAll()
+			return nil, fmt.Errorf("failed to open %s: %w", path, err)
+		}
+
+		projects = append(projects, Project{Name: names[i], DB: db})
+	}
+
+	fed, err := NewFederation(projects)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	fed.owned = owned
+	return fed, nil
+}
+
+// Projects returns the names of the federated projects.
+func (f *Federation) Projects() []string {
+	names := make([]string, len(f.projects))
+	for i, p := range f.projects {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// Close closes the databases opened by OpenFederation.
+func (f *Federation) Close() error {
+	var firstErr error
+	for _, db := range f.owned {
+		if err := db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	f.owned = nil
+	return firstErr
+}
+
+// GetOverallStats returns overall stats for every project and combined. Counts
+// are summed; unique agents and skills and the top agents and skills are
+// computed across all projects.
+//
+// Example:
+//
+//	stats, err := fed.GetOverallStats(time.Now().AddDate(0, -1, 0))
+//	fmt.Printf("Org-wide: %d sessions\n", stats.Combined.TotalSessions)
+//	for _, p := range stats.Projects {
+//	    fmt.Printf("  %s: %d sessions\n", p.Project, p.Stats.TotalSessions)
+//	}
+func (f *Federation) GetOverallStats(since time.Time) (*FederatedOverallStats, error) {
+	result := &FederatedOverallStats{Projects: make([]ProjectOverallStats, len(f.projects))}
+	agentCounts := make([]map[string]int, len(f.projects))
+	skillCounts := make([]map[string]int, len(f.projects))
+
+	err := f.forEachProject(func(i int, p Project) error {
+		result.Projects[i] = ProjectOverallStats{Project: p.Name, Stats: &OverallStats{Since: since}}
+		if ok, err := TableExists(p.DB, "agent_sessions"); err != nil || !ok {
+			return err
+		}
+
+		stats, err := GetOverallStats(p.DB, since)
+		if err != nil {
+			return err
+		}
+		result.Projects[i].Stats = stats
+
+		agentCounts[i], err = queryCounts(p.DB, `
+			SELECT agent_name, COUNT(*) FROM agent_sessions
+			WHERE started_at >= ?
+			GROUP BY agent_name
+		`, formatTime(since))
+		if err != nil {
+			return fmt.Errorf("failed to get agent counts: %w", err)
+		}
+		skillCounts[i], err = queryCounts(p.DB, `
+			SELECT skill_name, COUNT(*) FROM agent_skill_usage
+			WHERE loaded_at >= ?
+			GROUP BY skill_name
+		`, formatTime(since))
+		if err != nil {
+			return fmt.Errorf("failed to get skill counts: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	combined := &OverallStats{Since: since}
+	for _, p := range result.Projects {
+		combined.TotalSessions += p.Stats.TotalSessions
+		combined.ActiveSessions += p.Stats.ActiveSessions
+		combined.TotalIssues += p.Stats.TotalIssues
+		combined.CompletedIssues += p.Stats.CompletedIssues
+		combined.TotalTokens += p.Stats.TotalTokens
+	}
+
+	agents := mergeCounts(agentCounts)
+	combined.UniqueAgents = len(agents)
+	for _, c := range topCounts(agents, 5) {
+		combined.TopAgents = append(combined.TopAgents, AgentCount{AgentName: c.Label, Count: c.Count})
+	}
+
+	skills := mergeCounts(skillCounts)
+	combined.UniqueSkills = len(skills)
+	for _, c := range topCounts(skills, 5) {
+		combined.TopSkills = append(combined.TopSkills, SkillCount{SkillName: c.Label, Count: c.Count})
+	}
+
+	result.Combined = combined
+	return result, nil
+}
+
+// GetAgentStats returns an agent's stats in every project and combined. The
+// combined average session time is weighted by each project's ended sessions.
+//
+// Example:
+//
+//	stats, err := fed.GetAgentStats("beads-workflow-orchestrator", time.Now().AddDate(0, -1, 0))
+func (f *Federation) GetAgentStats(agentName string, since time.Time) (*FederatedAgentStats, error) {
+	if agentName == "" {
+		return nil, fmt.Errorf("agent name is required")
+	}
+
+	result := &FederatedAgentStats{Projects: make([]ProjectAgentStats, len(f.projects))}
+	skillCounts := make([]map[string]int, len(f.projects))
+
+	err := f.forEachProject(func(i int, p Project) error {
+		result.Projects[i] = ProjectAgentStats{Project: p.Name, Stats: &AgentStats{AgentName: agentName, Since: since}}
+		if ok, err := TableExists(p.DB, "agent_sessions"); err != nil || !ok {
+			return err
+		}
+
+		stats, err := GetAgentStats(p.DB, agentName, since)
+		if err != nil {
+			return err
+		}
+		result.Projects[i].Stats = stats
+
+		skillCounts[i], err = queryCounts(p.DB, `
+			SELECT u.skill_name, COUNT(*)
+			FROM agent_skill_usage u
+			JOIN agent_sessions s ON u.session_id = s.session_id
+			WHERE s.agent_name = ? AND s.started_at >= ?
+			GROUP BY u.skill_name
+		`, agentName, formatTime(since))
+		if err != nil {
+			return fmt.Errorf("failed to get skill counts: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	combined := &AgentStats{AgentName: agentName, Since: since}
+	var totalSessionTime time.Duration
+	endedSessions := 0
+	for _, p := range result.Projects {
+		combined.TotalSessions += p.Stats.TotalSessions
+		combined.ActiveSessions += p.Stats.ActiveSessions
+		combined.TotalIssues += p.Stats.TotalIssues
+		combined.CompletedIssues += p.Stats.CompletedIssues
+		combined.TotalSkillUses += p.Stats.TotalSkillUses
+		combined.TotalTokens += p.Stats.TotalTokens
+
+		ended := p.Stats.TotalSessions - p.Stats.ActiveSessions
+		totalSessionTime += p.Stats.AvgSessionTime * time.Duration(ended)
+		endedSessions += ended
+	}
+	if endedSessions > 0 {
+		combined.AvgSessionTime = totalSessionTime / time.Duration(endedSessions)
+	}
+	for _, c := range topCounts(mergeCounts(skillCounts), 5) {
+		combined.MostUsedSkills = append(combined.MostUsedSkills, SkillCount{SkillName: c.Label, Count: c.Count})
+	}
+
+	result.Combined = combined
+	return result, nil
+}
+
+// forEachProject calls fn for every project in parallel and returns the first
+// error, prefixed with the project name.
+func (f *Federation) forEachProject(fn func(i int, p Project) error) error {
+	errs := make([]error, len(f.projects))
+	var wg sync.WaitGroup
+	for i, p := range f.projects {
+		wg.Add(1)
+		go func(i int, p Project) {
+			defer wg.Done()
+			if err := fn(i, p); err != nil {
+				errs[i] = fmt.Errorf("project %s: %w", p.Name, err)
+			}
+		}(i, p)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// projectNames names projects after the directory holding their .beads
+// directory, or after the database file when it isn't in one. Names shared by
+// several paths get one more parent directory at a time until they differ or
+// run out of directories.
+func projectNames(dbPaths []string) []string {
+	names := make([]string, len(dbPaths))
+	depths := make([]int, len(dbPaths))
+	for i, path := range dbPaths {
+		depths[i] = 1
+		names[i] = projectName(path, 1)
+	}
+
+	for {
+		counts := make(map[string]int)
+		for _, name := range names {
+			counts[name]++
+		}
+		grew := false
+		for i, path := range dbPaths {
+			if counts[names[i]] < 2 {
+				continue
+			}
+			if name := projectName(path, depths[i]+1); name != names[i] {
+				depths[i]++
+				names[i] = name
+				grew = true
+			}
+		}
+		if !grew {
+			return names
+		}
+	}
+}
+
+// projectName returns the last depth path elements of a project's directory,
+// or of the database file when it isn't in a .beads directory.
+func projectName(dbPath string, depth int) string {
+	path := dbPath
+	if dir := filepath.Dir(dbPath); filepath.Base(dir) == ".beads" {
+		path = filepath.Dir(dir)
+	}
+
+	var parts []string
+	for ; depth > 0; depth-- {
+		base := filepath.Base(path)
+		if base == string(filepath.Separator) || base == "." {
+			break
+		}
+		parts = append([]string{base}, parts...)
+		path = filepath.Dir(path)
+	}
+	return strings.Join(parts, "/")
+}
+
+// queryCounts runs a query returning name and count columns.
+func queryCounts(db *sql.DB, query string, args ...interface{}) (map[string]int, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var name string
+		var count int
+		if err := rows.Scan(&name, &count); err != nil {
+			return nil, err
+		}
+		counts[name] += count
+	}
+	return counts, rows.Err()
+}
+
+// mergeCounts sums counts by name.
+func mergeCounts(all []map[string]int) map[string]int {
+	merged := make(map[string]int)
+	for _, counts := range all {
+		for name, count := range counts {
+			merged[name] += count
+		}
+	}
+	return merged
+}
+
+// topCounts returns the n highest counts, ties broken by name.
+func topCounts(counts map[string]int, n int) []DigestCount {
+	sorted := sortedDigestCounts(counts)
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
@@ -0,0 +1,139 @@
+package agent_tracking
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// createProjectDB creates dir/.beads/beads.db with agent tracking and the given
+// number of completed orchestrator sessions.
+func createProjectDB(t *testing.T, dir string, sessions int) string {
+	t.Helper()
+	path := filepath.Join(dir, ".beads", "beads.db")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	db := openRawTestDB(t, path)
+	if err := Initialize(db); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < sessions; i++ {
+		sessionID, err := StartSession(db, "orchestrator", dir, "opus")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := RecordSkillUsage(db, sessionID, "dependency-thinking", "", 100); err != nil {
+			t.Fatal(err)
+		}
+		if err := UpdateSessionTokens(db, sessionID, 500); err != nil {
+			t.Fatal(err)
+		}
+		if err := EndSession(db, sessionID, ExitCompleted); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestOpenFederationMergesProjects(t *testing.T) {
+	root := t.TempDir()
+	agents := createProjectDB(t, filepath.Join(root, "agents"), 2)
+	website := createProjectDB(t, filepath.Join(root, "website"), 1)
+
+	// A beads database that never initialized agent tracking.
+	bare := filepath.Join(root, "docs", ".beads", "beads.db")
+	if err := os.MkdirAll(filepath.Dir(bare), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	db := openRawTestDB(t, bare)
+	mustExec(t, db, `CREATE TABLE issues (id TEXT PRIMARY KEY)`)
+	db.Close()
+
+	fed, err := OpenFederation("sqlite", []string{agents, website, bare})
+	if err != nil {
+		t.Fatalf("OpenFederation failed: %v", err)
+	}
+	defer fed.Close()
+
+	if got := fed.Projects(); !reflect.DeepEqual(got, []string{"agents", "website", "docs"}) {
+		t.Errorf("projects = %v", got)
+	}
+
+	overall, err := fed.GetOverallStats(time.Time{})
+	if err != nil {
+		t.Fatalf("GetOverallStats failed: %v", err)
+	}
+	if overall.Combined.TotalSessions != 3 || overall.Combined.UniqueAgents != 1 || overall.Combined.TotalTokens != 1500 {
+		t.Errorf("combined = %+v", overall.Combined)
+	}
+	perProject := make(map[string]int)
+	for _, p := range overall.Projects {
+		perProject[p.Project] = p.Stats.TotalSessions
+	}
+	if want := map[string]int{"agents": 2, "website": 1, "docs": 0}; !reflect.DeepEqual(perProject, want) {
+		t.Errorf("sessions per project = %v, want %v", perProject, want)
+	}
+
+	agent, err := fed.GetAgentStats("orchestrator", time.Time{})
+	if err != nil {
+		t.Fatalf("GetAgentStats failed: %v", err)
+	}
+	if agent.Combined.TotalSessions != 3 || agent.Combined.TotalSkillUses != 3 {
+		t.Errorf("combined agent stats = %+v", agent.Combined)
+	}
+}
+
+func TestOpenFederationDisambiguatesProjectNames(t *testing.T) {
+	root := t.TempDir()
+	paths := []string{
+		createProjectDB(t, filepath.Join(root, "a", "api"), 1),
+		createProjectDB(t, filepath.Join(root, "b", "api"), 1),
+		createProjectDB(t, filepath.Join(root, "b", "web"), 1),
+	}
+
+	fed, err := OpenFederation("sqlite", paths)
+	if err != nil {
+		t.Fatalf("OpenFederation failed: %v", err)
+	}
+	defer fed.Close()
+
+	if got, want := fed.Projects(), []string{"a/api", "b/api", "web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("projects = %v, want %v", got, want)
+	}
+}
+
+func TestProjectNames(t *testing.T) {
+	tests := []struct {
+		paths []string
+		want  []string
+	}{
+		{[]string{"/src/agents/.beads/beads.db"}, []string{"agents"}},
+		{[]string{"/data/tracking.db"}, []string{"tracking.db"}},
+		{[]string{"/x/a/api/.beads/beads.db", "/y/a/api/.beads/beads.db"}, []string{"x/a/api", "y/a/api"}},
+		{[]string{"/api/.beads/beads.db", "/src/api/.beads/beads.db"}, []string{"api", "src/api"}},
+		// The same database twice can't be told apart; NewFederation rejects it.
+		{[]string{"/src/agents/.beads/beads.db", "/src/agents/.beads/beads.db"}, []string{"src/agents", "src/agents"}},
+	}
+	for _, tt := range tests {
+		if got := projectNames(tt.paths); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("projectNames(%v) = %v, want %v", tt.paths, got, tt.want)
+		}
+	}
+}
+
+func TestNewFederationRejectsDuplicateNames(t *testing.T) {
+	db := openTestDB(t)
+	_, err := NewFederation([]Project{{Name: "api", DB: db}, {Name: "api", DB: db}})
+	if err == nil {
+		t.Fatal("expected an error for duplicate project names")
+	}
+	if _, err := NewFederation([]Project{{Name: "a/api", DB: db}, {Name: "b/api", DB: db}}); err != nil {
+		t.Errorf("NewFederation with distinct names failed: %v", err)
+	}
+}
@@ -0,0 +1,296 @@
+package agent_tracking
+
+import (
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// ForecastOptions controls how an epic completion forecast is simulated.
+type ForecastOptions struct {
+	Iterations  int       // Number of Monte Carlo runs (default 10000)
+	Seed        int64     // Random seed; runs with the same seed and inputs produce identical forecasts
+	HistoryDays int       // Days of completion history sampled for throughput (default 28)
+	MaxDays     int       // Simulation horizon; runs that don't finish are reported as incomplete (default 365)
+	Start       time.Time // Day the forecast starts from (default today)
+}
+
+// ForecastPercentile is the date by which the epic completes in the given share of runs.
+// When fewer than that share of runs finish within MaxDays, Incomplete is set and
+// Days and Date are left zero.
+type ForecastPercentile struct {
+	Percentile int       `json:"percentile"`
+	Days       int       `json:"days"`
+	Date       time.Time `json:"date"`
+	Incomplete bool      `json:"incomplete,omitempty"`
+}
+
+// BurndownPoint is the projected number of open child issues at the end of a day.
+type BurndownPoint struct {
+	Date        time.Time `json:"date"`
+	Remaining50 int       `json:"remaining_p50"`
+	Remaining85 int       `json:"remaining_p85"`
+}
+
+// EpicForecast contains the simulated completion forecast for an epic.
+type EpicForecast struct {
+	EpicID          string               `json:"epic_id"`
+	TotalIssues     int                  `json:"total_issues"`
+	ClosedIssues    int                  `json:"closed_issues"`
+	RemainingIssues int                  `json:"remaining_issues"`
+	Throughput      []int                `json:"throughput"`
+	Iterations      int                  `json:"iterations"`
+	IncompleteRuns  int                  `json:"incomplete_runs"`
+	Percentiles     []ForecastPercentile `json:"percentiles"`
+	Burndown        []BurndownPoint      `json:"burndown"`
+	Start           time.Time            `json:"start"`
+	Seed            int64                `json:"seed"`
+}
+
+// forecastPercentiles are the completion percentiles reported by a forecast.
+var forecastPercentiles = []int{50, 70, 85, 95}
+
+// ForecastEpic forecasts when an epic's remaining child issues will be completed.
+// Daily throughput is sampled from the issues first completed in agent_issue_work
+// during the history window, and the epic's open children are burned down with
+// that throughput in a Monte Carlo simulation.
+//
+// Example:
+//
+//	forecast, err := agent_tracking.ForecastEpic(db, "agents-10", agent_tracking.ForecastOptions{Seed: 42})
+//	for _, p := range forecast.Percentiles {
+//	    fmt.Printf("P%d: %s\n", p.Percentile, p.Date.Format("2006-01-02"))
+//	}
+func ForecastEpic(db *sql.DB, epicID string, opts ForecastOptions) (*EpicForecast, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	if epicID == "" {
+		return nil, fmt.Errorf("epic ID is required")
+	}
+	opts = opts.withDefaults()
+
+	children, err := ListEpicChildren(db, epicID)
+	if err != nil {
+		return nil, err
+	}
+	if len(children) == 0 {
+		return nil, fmt.Errorf("epic has no child issues: %s", epicID)
+	}
+
+	remaining := 0
+	for _, child := range children {
+		if child.Status != "closed" {
+			remaining++
+		}
+	}
+
+	throughput, err := getDailyThroughput(db, opts.Start, opts.HistoryDays)
+	if err != nil {
+		return nil, err
+	}
+
+	forecast, err := SimulateCompletion(remaining, throughput, opts)
+	if err != nil {
+		return nil, err
+	}
+	forecast.EpicID = epicID
+	forecast.TotalIssues = len(children)
+	forecast.ClosedIssues = len(children) - remaining
+
+	return forecast, nil
+}
+
+// SimulateCompletion runs the Monte Carlo burndown for a number of remaining issues
+// given a list of historical daily completion counts. ForecastEpic calls it after
+// loading its inputs from the database; it is exported so forecasts can be run on
+// hypothetical scopes.
+func SimulateCompletion(remaining int, throughput []int, opts ForecastOptions) (*EpicForecast, error) {
+	if remaining < 0 {
+		return nil, fmt.Errorf("remaining issues must not be negative")
+	}
+	if len(throughput) == 0 {
+		return nil, fmt.Errorf("throughput history is empty")
+	}
+	opts = opts.withDefaults()
+
+	forecast := &EpicForecast{
+		RemainingIssues: remaining,
+		Throughput:      throughput,
+		Iterations:      opts.Iterations,
+		Start:           opts.Start,
+		Seed:            opts.Seed,
+	}
+
+	if remaining == 0 {
+		for _, p := range forecastPercentiles {
+			forecast.Percentiles = append(forecast.Percentiles, ForecastPercentile{Percentile: p, Date: opts.Start})
+		}
+		return forecast, nil
+	}
+
+	total := 0
+	for _, n := range throughput {
+		total += n
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("no issues completed in the last %d days", len(throughput))
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+
+	// remainingByDay[d][r] counts the runs with r issues open at the end of day d+1.
+	remainingByDay := make([][]int, opts.MaxDays)
+	for d := range remainingByDay {
+		remainingByDay[d] = make([]int, remaining+1)
+	}
+
+	days := make([]int, opts.Iterations)
+	for i := 0; i < opts.Iterations; i++ {
+		left := remaining
+		day := 0
+		for left > 0 && day < opts.MaxDays {
+			left -= throughput[rng.Intn(len(throughput))]
+			if left < 0 {
+				left = 0
+			}
+			remainingByDay[day][left]++
+			day++
+		}
+		for d := day; d < opts.MaxDays; d++ {
+			remainingByDay[d][left]++
+		}
+		if left > 0 {
+			// Sorts after every finished run.
+			forecast.IncompleteRuns++
+			day = opts.MaxDays + 1
+		}
+		days[i] = day
+	}
+
+	sort.Ints(days)
+	for _, p := range forecastPercentiles {
+		d := days[percentileIndex(len(days), p)]
+		if d > opts.MaxDays {
+			forecast.Percentiles = append(forecast.Percentiles, ForecastPercentile{Percentile: p, Incomplete: true})
+			continue
+		}
+		forecast.Percentiles = append(forecast.Percentiles, ForecastPercentile{
+			Percentile: p,
+			Days:       d,
+			Date:       opts.Start.AddDate(0, 0, d),
+		})
+	}
+
+	// Stop the burndown once the pessimistic line reaches zero.
+	forecast.Burndown = append(forecast.Burndown, BurndownPoint{
+		Date:        opts.Start,
+		Remaining50: remaining,
+		Remaining85: remaining,
+	})
+	for d, counts := range remainingByDay {
+		point := BurndownPoint{
+			Date:        opts.Start.AddDate(0, 0, d+1),
+			Remaining50: remainingAtPercentile(counts, opts.Iterations, 50),
+			Remaining85: remainingAtPercentile(counts, opts.Iterations, 85),
+		}
+		forecast.Burndown = append(forecast.Burndown, point)
+		if point.Remaining85 == 0 {
+			break
+		}
+	}
+
+	return forecast, nil
+}
+
+// withDefaults fills in unset forecast options.
+func (o ForecastOptions) withDefaults() ForecastOptions {
+	if o.Iterations <= 0 {
+		o.Iterations = 10000
+	}
+	if o.HistoryDays <= 0 {
+		o.HistoryDays = 28
+	}
+	if o.MaxDays <= 0 {
+		o.MaxDays = 365
+	}
+	if o.Start.IsZero() {
+		o.Start = time.Now()
+	}
+	y, m, d := o.Start.UTC().Date()
+	o.Start = time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	return o
+}
+
+// percentileIndex returns the index of the p-th percentile in a sorted slice of length n.
+func percentileIndex(n, p int) int {
+	idx := (n*p+99)/100 - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= n {
+		idx = n - 1
+	}
+	return idx
+}
+
+// remainingAtPercentile returns the open issue count that p percent of runs are at or below,
+// given a histogram of run counts indexed by open issues.
+func remainingAtPercentile(counts []int, runs, p int) int {
+	target := percentileIndex(runs, p) + 1
+	seen := 0
+	for r, n := range counts {
+		seen += n
+		if seen >= target {
+			return r
+		}
+	}
+	return len(counts) - 1
+}
+
+// getDailyThroughput returns the number of issues first completed on each of the
+// historyDays days before start, oldest first, including days with no completions.
+func getDailyThroughput(db *sql.DB, start time.Time, historyDays int) ([]int, error) {
+	from := start.AddDate(0, 0, -historyDays)
+
+	rows, err := db.Query(`
+		SELECT date(first_completed) as day, COUNT(*) as cnt
+		FROM (
+			SELECT issue_id, MIN(ended_at) as first_completed
+			FROM agent_issue_work
+			WHERE completed = 1 AND ended_at IS NOT NULL
+			GROUP BY issue_id
+		)
+		WHERE first_completed >= ? AND first_completed < ?
+		GROUP BY day
+	`, formatTime(from), formatTime(start))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get completion throughput: %w", err)
+	}
+	defer rows.Close()
+
+	throughput := make([]int, historyDays)
+	for rows.Next() {
+		var dayStr string
+		var count int
+		if err := rows.Scan(&dayStr, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan throughput: %w", err)
+		}
+		day, err := time.Parse("2006-01-02", dayStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse throughput day: %w", err)
+		}
+		idx := int(day.Sub(from).Hours() / 24)
+		if idx >= 0 && idx < historyDays {
+			throughput[idx] = count
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating throughput: %w", err)
+	}
+
+	return throughput, nil
+}
@@ -0,0 +1,123 @@
+package agent_tracking
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSimulateCompletionIsDeterministic(t *testing.T) {
+	throughput := []int{0, 1, 2, 0, 3, 1, 0, 2}
+	opts := ForecastOptions{Iterations: 2000, Seed: 42, Start: time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)}
+
+	first, err := SimulateCompletion(12, throughput, opts)
+	if err != nil {
+		t.Fatalf("SimulateCompletion failed: %v", err)
+	}
+	second, err := SimulateCompletion(12, throughput, opts)
+	if err != nil {
+		t.Fatalf("SimulateCompletion failed: %v", err)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("same seed produced different forecasts:\n%+v\n%+v", first, second)
+	}
+
+	if !first.Start.Equal(time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("start = %v, want truncated to the day", first.Start)
+	}
+	for i, p := range first.Percentiles {
+		if p.Incomplete {
+			t.Fatalf("P%d unexpectedly incomplete", p.Percentile)
+		}
+		if i > 0 && p.Days < first.Percentiles[i-1].Days {
+			t.Errorf("P%d (%d days) before P%d (%d days)", p.Percentile, p.Days, first.Percentiles[i-1].Percentile, first.Percentiles[i-1].Days)
+		}
+		if !p.Date.Equal(first.Start.AddDate(0, 0, p.Days)) {
+			t.Errorf("P%d date = %v, want start + %d days", p.Percentile, p.Date, p.Days)
+		}
+	}
+	if got := first.Burndown[0].Remaining50; got != 12 {
+		t.Errorf("burndown starts at %d, want 12", got)
+	}
+	if last := first.Burndown[len(first.Burndown)-1]; last.Remaining85 != 0 {
+		t.Errorf("burndown ends at %d, want 0", last.Remaining85)
+	}
+}
+
+func TestSimulateCompletionFlagsIncompleteRuns(t *testing.T) {
+	// One issue every ten days on average: 30 issues can't finish in 60 days.
+	throughput := []int{1, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	forecast, err := SimulateCompletion(30, throughput, ForecastOptions{Iterations: 500, Seed: 1, MaxDays: 60})
+	if err != nil {
+		t.Fatalf("SimulateCompletion failed: %v", err)
+	}
+	if forecast.IncompleteRuns != 500 {
+		t.Errorf("IncompleteRuns = %d, want 500", forecast.IncompleteRuns)
+	}
+	for _, p := range forecast.Percentiles {
+		if !p.Incomplete || p.Days != 0 || !p.Date.IsZero() {
+			t.Errorf("P%d = %+v, want incomplete with no date", p.Percentile, p)
+		}
+	}
+}
+
+func TestSimulateCompletionNothingRemaining(t *testing.T) {
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	forecast, err := SimulateCompletion(0, []int{1}, ForecastOptions{Start: start})
+	if err != nil {
+		t.Fatalf("SimulateCompletion failed: %v", err)
+	}
+	for _, p := range forecast.Percentiles {
+		if !p.Date.Equal(start) {
+			t.Errorf("P%d date = %v, want %v", p.Percentile, p.Date, start)
+		}
+	}
+}
+
+func TestForecastEpic(t *testing.T) {
+	db := openTestDB(t)
+	mustExec(t, db, `INSERT INTO issues (id, title, issue_type) VALUES ('agents-10', 'Epic', 'epic')`)
+	for _, id := range []string{"agents-11", "agents-12", "agents-13", "agents-14"} {
+		mustExec(t, db, `INSERT INTO issues (id, title) VALUES (?, ?)`, id, id)
+		mustExec(t, db, `INSERT INTO dependencies (issue_id, depends_on_id, type) VALUES (?, 'agents-10', 'parent-child')`, id)
+	}
+	mustExec(t, db, `UPDATE issues SET status = 'closed' WHERE id = 'agents-11'`)
+
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	sessionID, err := StartSession(db, "agent", "/ws", "sonnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 6; i++ {
+		workID, err := RecordWork(db, sessionID, "done-"+string(rune('a'+i)), "agent", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		mustExec(t, db, `UPDATE agent_issue_work SET completed = 1, ended_at = ? WHERE work_id = ?`,
+			formatTime(start.AddDate(0, 0, -2*i-1).Add(10*time.Hour)), workID)
+	}
+
+	forecast, err := ForecastEpic(db, "agents-10", ForecastOptions{Seed: 3, Iterations: 1000, HistoryDays: 14, Start: start})
+	if err != nil {
+		t.Fatalf("ForecastEpic failed: %v", err)
+	}
+	if forecast.TotalIssues != 4 || forecast.ClosedIssues != 1 || forecast.RemainingIssues != 3 {
+		t.Errorf("issues = %d total, %d closed, %d remaining; want 4, 1, 3",
+			forecast.TotalIssues, forecast.ClosedIssues, forecast.RemainingIssues)
+	}
+	total := 0
+	for _, n := range forecast.Throughput {
+		total += n
+	}
+	if len(forecast.Throughput) != 14 || total != 6 {
+		t.Errorf("throughput = %v, want 6 completions over 14 days", forecast.Throughput)
+	}
+
+	again, err := ForecastEpic(db, "agents-10", ForecastOptions{Seed: 3, Iterations: 1000, HistoryDays: 14, Start: start})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(forecast, again) {
+		t.Error("same seed produced different epic forecasts")
+	}
+}
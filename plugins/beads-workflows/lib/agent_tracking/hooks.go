@@ -0,0 +1,839 @@
+package agent_tracking
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Claude Code hook events handled by HandleHook.
+const (
+	HookSessionStart       = "SessionStart"
+	HookUserPromptSubmit   = "UserPromptSubmit"
+	HookPreToolUse         = "PreToolUse"
+	HookPostToolUse        = "PostToolUse"
+	HookPostToolUseFailure = "PostToolUseFailure"
+	HookStop               = "Stop"
+	HookSubagentStop       = "SubagentStop"
+	HookSessionEnd         = "SessionEnd"
+)
+
+// HookPayload is the JSON a Claude Code hook receives on stdin. Only the fields
+// used for tracking are decoded.
+type HookPayload struct {
+	SessionID           string          `json:"session_id"`
+	TranscriptPath      string          `json:"transcript_path"`
+	Cwd                 string          `json:"cwd"`
+	HookEventName       string          `json:"hook_event_name"`
+	Source              string          `json:"source,omitempty"`
+	Model               string          `json:"model,omitempty"`
+	Prompt              string          `json:"prompt,omitempty"`
+	ToolName            string          `json:"tool_name,omitempty"`
+	ToolInput           json.RawMessage `json:"tool_input,omitempty"`
+	ToolResponse        json.RawMessage `json:"tool_response,omitempty"`
+	ToolUseID           string          `json:"tool_use_id,omitempty"`
+	Error               string          `json:"error,omitempty"`
+	StopHookActive      bool            `json:"stop_hook_active,omitempty"`
+	AgentTranscriptPath string          `json:"agent_transcript_path,omitempty"`
+	Reason              string          `json:"reason,omitempty"`
+}
+
+// HookOptions controls how hook payloads are recorded.
+type HookOptions struct {
+	AgentName string // Agent name for main sessions (default "claude-code")
+	ModelTier string // Model tier for sessions (default derived from the payload's model, if any)
+}
+
+// IngestHook reads one hook payload from r, typically os.Stdin, and records it
+// with HandleHook. The decoded payload is returned even when recording fails.
+//
+// Example:
+//
+//	// In a program registered as a hook command for every event:
+//	if _, err := agent_tracking.IngestHook(db, os.Stdin, agent_tracking.HookOptions{}); err != nil {
+//	    fmt.Fprintln(os.Stderr, err)
+//	}
+func IngestHook(db *sql.DB, r io.Reader, opts HookOptions) (*HookPayload, error) {
+	var payload HookPayload
+	if err := json.NewDecoder(r).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("failed to decode hook payload: %w", err)
+	}
+	return &payload, HandleHook(db, payload, opts)
+}
+
+// HandleHook records a hook payload, keyed by the hook's session ID. Each hook
+// session has one tracking session, from its first event until SessionEnd:
+//
+//   - SessionStart and UserPromptSubmit start the tracking session unless one is active.
+//   - PreToolUse starts a tool call, linked to the session's open work. Task (or
+//     Agent) calls also start a subagent session; Skill calls record skill usage.
+//   - PostToolUse and PostToolUseFailure end the call with the same tool_use_id,
+//     or the oldest open call of that tool for payloads without one. Failures are
+//     also recorded with RecordError. A Task call ends the subagent session it
+//     started, unless SubagentStop already did: with ExitError when it failed.
+//     A successful `bd update <id> --claim` or `--status in_progress` records
+//     work on the issue, and `bd close <id>` completes it.
+//   - SubagentStop ends the subagent session whose Task prompt starts the agent
+//     transcript, or the only one open, as completed.
+//   - Stop marks the end of a turn and updates context tokens from the
+//     transcript. The session and its subagents, which may run in the
+//     background, stay open.
+//   - SessionEnd ends open subagent sessions as interrupted and then ends the
+//     session: as completed when the user cleared, logged out or exited, and as
+//     interrupted otherwise.
+//
+// Token updates can return a *BudgetExceededError for a hard budget; sessions
+// are ended before tokens are updated, so the error never leaves one open.
+// Other events are ignored.
+//
+// Example:
+//
+//	err := agent_tracking.HandleHook(db, agent_tracking.HookPayload{
+//	    SessionID:     "9a8f...",
+//	    Cwd:           "/myStuff/project",
+//	    HookEventName: agent_tracking.HookSessionStart,
+//	}, agent_tracking.HookOptions{AgentName: "beads-workflow-orchestrator"})
+func HandleHook(db *sql.DB, payload HookPayload, opts HookOptions) error {
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+	if payload.SessionID == "" {
+		return fmt.Errorf("hook session ID is required")
+	}
+	if opts.AgentName == "" {
+		opts.AgentName = "claude-code"
+	}
+	if opts.ModelTier == "" {
+		opts.ModelTier = modelTierFromModel(payload.Model)
+	}
+
+	switch payload.HookEventName {
+	case HookSessionStart, HookUserPromptSubmit:
+		_, err := ensureHookSession(db, payload, opts)
+		return err
+
+	case HookPreToolUse:
+		sessionID, err := ensureHookSession(db, payload, opts)
+		if err != nil {
+			return err
+		}
+		return startHookToolCall(db, sessionID, payload, opts)
+
+	case HookPostToolUse, HookPostToolUseFailure:
+		sessionID, err := getActiveHookSession(db, payload.SessionID)
+		if err != nil || sessionID == "" {
+			return err
+		}
+		return endHookToolCall(db, sessionID, payload)
+
+	case HookSubagentStop:
+		sessionID, err := getActiveHookSession(db, payload.SessionID)
+		if err != nil || sessionID == "" {
+			return err
+		}
+		subagentID, err := getStoppedSubagent(db, sessionID, payload.AgentTranscriptPath)
+		if err != nil || subagentID == "" {
+			return err
+		}
+		return endHookSession(db, subagentID, ExitCompleted, payload.AgentTranscriptPath)
+
+	case HookStop:
+		if payload.StopHookActive {
+			// Claude is continuing because a Stop hook told it to.
+			return nil
+		}
+		sessionID, err := getActiveHookSession(db, payload.SessionID)
+		if err != nil || sessionID == "" {
+			return err
+		}
+		return updateTranscriptTokens(db, sessionID, payload.TranscriptPath)
+
+	case HookSessionEnd:
+		sessionID, err := getActiveHookSession(db, payload.SessionID)
+		if err != nil || sessionID == "" {
+			return err
+		}
+		if err := endOpenSubagentSessions(db, sessionID); err != nil {
+			return err
+		}
+		return endHookSession(db, sessionID, sessionEndExitReason(payload.Reason), payload.TranscriptPath)
+	}
+
+	return nil
+}
+
+// sessionEndExitReason maps a SessionEnd reason to an exit reason. Clearing,
+// logging out and exiting at the prompt are normal ends; anything else isn't.
+func sessionEndExitReason(reason string) ExitReason {
+	switch reason {
+	case "clear", "logout", "prompt_input_exit":
+		return ExitCompleted
+	}
+	return ExitInterrupted
+}
+
+// ensureHookSession returns the active tracking session for a hook session,
+// starting one if there is none.
+func ensureHookSession(db *sql.DB, payload HookPayload, opts HookOptions) (string, error) {
+	sessionID, err := getActiveHookSession(db, payload.SessionID)
+	if err != nil || sessionID != "" {
+		return sessionID, err
+	}
+
+	// Only SessionStart carries the model; later sessions keep the last tier.
+	tier := opts.ModelTier
+	if tier == "" {
+		err := db.QueryRow(`
+			SELECT COALESCE(s.model_tier, '')
+			FROM agent_hook_sessions h
+			JOIN agent_sessions s ON s.session_id = h.session_id
+			WHERE h.hook_session_id = ?
+		`, payload.SessionID).Scan(&tier)
+		if err != nil && err != sql.ErrNoRows {
+			return "", fmt.Errorf("failed to get hook session: %w", err)
+		}
+	}
+
+	sessionID, err = StartSession(db, opts.AgentName, payload.Cwd, tier)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO agent_hook_sessions (hook_session_id, session_id, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(hook_session_id) DO UPDATE SET
+			session_id = excluded.session_id,
+			updated_at = excluded.updated_at
+	`, payload.SessionID, sessionID, formatTime(time.Now()))
+	if err != nil {
+		return "", fmt.Errorf("failed to record hook session: %w", err)
+	}
+
+	return sessionID, nil
+}
+
+// endHookSession ends a session and then sets its context tokens from its
+// transcript, so a budget exceeded by the final count can't keep it open.
+func endHookSession(db *sql.DB, sessionID string, exitReason ExitReason, transcriptPath string) error {
+	if err := EndSession(db, sessionID, exitReason); err != nil {
+		return err
+	}
+	return updateTranscriptTokens(db, sessionID, transcriptPath)
+}
+
+// endOpenSubagentSessions ends every subagent session of a session that is still
+// running as interrupted. It is called when the session ends; subagents still
+// open then missed their SubagentStop and PostToolUse.
+func endOpenSubagentSessions(db *sql.DB, sessionID string) error {
+	for {
+		subagentID, err := getOpenSubagentSession(db, sessionID)
+		if err != nil || subagentID == "" {
+			return err
+		}
+		if err := EndSession(db, subagentID, ExitInterrupted); err != nil {
+			return err
+		}
+	}
+}
+
+// getActiveHookSession returns the active tracking session for a hook session,
+// or "" if there is none.
+func getActiveHookSession(db *sql.DB, hookSessionID string) (string, error) {
+	var sessionID string
+	err := db.QueryRow(`
+		SELECT h.session_id
+		FROM agent_hook_sessions h
+		JOIN agent_sessions s ON s.session_id = h.session_id
+		WHERE h.hook_session_id = ? AND s.ended_at IS NULL
+	`, hookSessionID).Scan(&sessionID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get hook session: %w", err)
+	}
+	return sessionID, nil
+}
+
+// startHookToolCall records the start of a tool call and, for Task and Skill
+// calls, the subagent session or skill usage.
+func startHookToolCall(db *sql.DB, sessionID string, payload HookPayload, opts HookOptions) error {
+	if payload.ToolName == "" {
+		return fmt.Errorf("tool name is required")
+	}
+
+	workID, issueID, err := getOpenHookWork(db, sessionID)
+	if err != nil {
+		return err
+	}
+	callID, err := StartToolCall(db, sessionID, workID, payload.ToolName)
+	if err != nil {
+		return err
+	}
+
+	var input struct {
+		SubagentType string `json:"subagent_type"`
+		Model        string `json:"model"`
+		Prompt       string `json:"prompt"`
+		Skill        string `json:"skill"`
+		Command      string `json:"command"`
+	}
+	if len(payload.ToolInput) > 0 {
+		// Tool input that isn't an object has nothing more to record.
+		_ = json.Unmarshal(payload.ToolInput, &input)
+	}
+
+	switch payload.ToolName {
+	case "Task", "Agent":
+		agentName := input.SubagentType
+		if agentName == "" {
+			agentName = "general-purpose"
+		}
+		tier := modelTierFromModel(input.Model)
+		if tier == "" {
+			session, err := GetSession(db, sessionID)
+			if err != nil {
+				return err
+			}
+			tier = session.ModelTier
+		}
+		subagentID, err := StartSession(db, agentName, payload.Cwd, tier)
+		if err != nil {
+			return err
+		}
+		if err := SetParentSession(db, subagentID, sessionID); err != nil {
+			return err
+		}
+		return recordHookToolCall(db, callID, payload.ToolUseID, subagentID, input.Prompt)
+
+	case "Skill":
+		skillName := input.Skill
+		if skillName == "" {
+			skillName = input.Command
+		}
+		if skillName != "" {
+			if err := addSessionSkill(db, sessionID, skillName); err != nil {
+				return err
+			}
+			if err := RecordSkillUsage(db, sessionID, skillName, issueID, 0); err != nil {
+				return err
+			}
+		}
+	}
+
+	return recordHookToolCall(db, callID, payload.ToolUseID, "", "")
+}
+
+// recordHookToolCall maps a tool call to its tool_use_id and the subagent session
+// a Task call started, so later events find them when several run in parallel.
+func recordHookToolCall(db *sql.DB, callID, toolUseID, subagentID, prompt string) error {
+	if toolUseID == "" && subagentID == "" {
+		return nil
+	}
+	_, err := db.Exec(`
+		INSERT INTO agent_hook_tool_calls (call_id, tool_use_id, subagent_session_id, prompt)
+		VALUES (?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''))
+	`, callID, toolUseID, subagentID, strings.TrimSpace(prompt))
+	if err != nil {
+		return fmt.Errorf("failed to record hook tool call: %w", err)
+	}
+	return nil
+}
+
+// endHookToolCall ends the payload's tool call and records what a finished call
+// means for errors, subagents and work.
+func endHookToolCall(db *sql.DB, sessionID string, payload HookPayload) error {
+	callID, err := getHookToolCall(db, sessionID, payload)
+	if err != nil {
+		return err
+	}
+
+	var input struct {
+		Command string `json:"command"`
+	}
+	if len(payload.ToolInput) > 0 {
+		_ = json.Unmarshal(payload.ToolInput, &input)
+	}
+
+	errMessage := hookToolError(payload)
+	success := payload.HookEventName == HookPostToolUse && errMessage == ""
+	if callID != "" {
+		err := EndToolCall(db, callID, ToolCallResult{
+			Success:  success,
+			BytesIn:  len(payload.ToolInput),
+			BytesOut: len(payload.ToolResponse),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if !success {
+		command := input.Command
+		if command == "" {
+			command = payload.ToolName
+		}
+		if errMessage == "" {
+			errMessage = payload.ToolName + " failed"
+		}
+		workID, _, err := getOpenHookWork(db, sessionID)
+		if err != nil {
+			return err
+		}
+		_, err = RecordError(db, SessionError{
+			SessionID: sessionID,
+			WorkID:    workID,
+			Category:  ErrorCategoryTool,
+			Message:   errMessage,
+			Command:   command,
+		})
+		if err != nil {
+			return err
+		}
+
+		if payload.ToolName != "Task" && payload.ToolName != "Agent" {
+			return nil
+		}
+		subagentID, err := getOpenHookSubagent(db, callID)
+		if err != nil || subagentID == "" {
+			return err
+		}
+		return EndSessionWithFailure(db, subagentID, ExitError, FailureDetails{
+			Class:   "tool_error",
+			Message: errMessage,
+		})
+	}
+
+	switch payload.ToolName {
+	case "Task", "Agent":
+		// Ends the subagent when SubagentStop isn't hooked or couldn't tell
+		// parallel subagents apart.
+		subagentID, err := getOpenHookSubagent(db, callID)
+		if err != nil || subagentID == "" {
+			return err
+		}
+		return EndSession(db, subagentID, ExitCompleted)
+
+	case "Bash":
+		return recordBeadsCommand(db, sessionID, input.Command)
+	}
+
+	return nil
+}
+
+// getHookToolCall returns the open tool call a PostToolUse or PostToolUseFailure
+// payload ends: the call recorded with its tool_use_id, or else the oldest open
+// call of its tool that has no tool_use_id. It returns "" if there is none.
+func getHookToolCall(db *sql.DB, sessionID string, payload HookPayload) (string, error) {
+	var callID string
+	if payload.ToolUseID != "" {
+		var open bool
+		err := db.QueryRow(`
+			SELECT c.call_id, c.ended_at IS NULL
+			FROM agent_hook_tool_calls h
+			JOIN agent_tool_calls c ON c.call_id = h.call_id
+			WHERE h.tool_use_id = ? AND c.session_id = ?
+		`, payload.ToolUseID, sessionID).Scan(&callID, &open)
+		if err == nil {
+			if !open {
+				return "", nil
+			}
+			return callID, nil
+		}
+		if err != sql.ErrNoRows {
+			return "", fmt.Errorf("failed to get tool call: %w", err)
+		}
+	}
+
+	err := db.QueryRow(`
+		SELECT c.call_id
+		FROM agent_tool_calls c
+		LEFT JOIN agent_hook_tool_calls h ON h.call_id = c.call_id
+		WHERE c.session_id = ? AND c.tool_name = ? AND c.ended_at IS NULL AND h.tool_use_id IS NULL
+		ORDER BY c.started_at, c.rowid
+		LIMIT 1
+	`, sessionID, payload.ToolName).Scan(&callID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get open tool call: %w", err)
+	}
+	return callID, nil
+}
+
+// getOpenHookSubagent returns the subagent session a Task call started if it is
+// still running, or "".
+func getOpenHookSubagent(db *sql.DB, callID string) (string, error) {
+	if callID == "" {
+		return "", nil
+	}
+	var subagentID string
+	err := db.QueryRow(`
+		SELECT h.subagent_session_id
+		FROM agent_hook_tool_calls h
+		JOIN agent_sessions s ON s.session_id = h.subagent_session_id
+		WHERE h.call_id = ? AND s.ended_at IS NULL
+	`, callID).Scan(&subagentID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get subagent session: %w", err)
+	}
+	return subagentID, nil
+}
+
+// getStoppedSubagent returns the open subagent session a SubagentStop is for: the
+// one whose Task prompt is the first user message of the agent transcript, or
+// else the only one open. With several open and no match it returns "", and the
+// subagent is ended by its PostToolUse instead.
+func getStoppedSubagent(db *sql.DB, sessionID, agentTranscriptPath string) (string, error) {
+	rows, err := db.Query(`
+		SELECT p.session_id, COALESCE(h.prompt, '')
+		FROM agent_session_parents p
+		JOIN agent_sessions s ON s.session_id = p.session_id
+		LEFT JOIN agent_hook_tool_calls h ON h.subagent_session_id = p.session_id
+		WHERE p.parent_session_id = ? AND s.ended_at IS NULL
+		ORDER BY s.started_at, s.rowid
+	`, sessionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get subagent sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var ids, prompts []string
+	for rows.Next() {
+		var id, prompt string
+		if err := rows.Scan(&id, &prompt); err != nil {
+			return "", fmt.Errorf("failed to scan subagent session: %w", err)
+		}
+		ids = append(ids, id)
+		prompts = append(prompts, prompt)
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("error iterating subagent sessions: %w", err)
+	}
+	if len(ids) == 0 {
+		return "", nil
+	}
+
+	prompt, err := readTranscriptPrompt(agentTranscriptPath)
+	if err != nil {
+		return "", err
+	}
+	if prompt != "" {
+		for i := range ids {
+			if prompts[i] == prompt {
+				return ids[i], nil
+			}
+		}
+	}
+	if len(ids) == 1 {
+		return ids[0], nil
+	}
+	return "", nil
+}
+
+// recordBeadsCommand records work for the bd commands in a shell command: claims
+// start work on an issue and closes complete it.
+func recordBeadsCommand(db *sql.DB, sessionID, command string) error {
+	session, err := GetSession(db, sessionID)
+	if err != nil {
+		return err
+	}
+
+	segments := strings.FieldsFunc(command, func(r rune) bool {
+		return r == ';' || r == '&' || r == '|' || r == '\n'
+	})
+	for _, segment := range segments {
+		fields := strings.Fields(segment)
+		for len(fields) > 0 && fields[0] != "bd" {
+			fields = fields[1:]
+		}
+		if len(fields) < 3 {
+			continue
+		}
+
+		var ids []string
+		for _, f := range fields[2:] {
+			if strings.HasPrefix(f, "-") {
+				break
+			}
+			ids = append(ids, strings.Trim(f, `"'`))
+		}
+
+		switch fields[1] {
+		case "update":
+			if !isBeadsClaim(fields[2:]) {
+				continue
+			}
+			for _, issueID := range ids {
+				if _, err := getOpenIssueWork(db, sessionID, issueID); err == nil {
+					continue
+				} else if !errors.Is(err, sql.ErrNoRows) {
+					return err
+				}
+				if _, err := RecordWork(db, sessionID, issueID, session.AgentName, "Claimed with bd update"); err != nil {
+					return err
+				}
+				if !containsString(session.IssuesClaimed, issueID) {
+					session.IssuesClaimed = append(session.IssuesClaimed, issueID)
+					if err := UpdateSessionIssues(db, sessionID, session.IssuesClaimed); err != nil {
+						return err
+					}
+				}
+			}
+
+		case "close":
+			for _, issueID := range ids {
+				workID, err := getOpenIssueWork(db, sessionID, issueID)
+				if errors.Is(err, sql.ErrNoRows) {
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				if err := CompleteWork(db, workID, "Closed with bd close"); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// isBeadsClaim reports whether bd update arguments claim the issue.
+func isBeadsClaim(args []string) bool {
+	for i, arg := range args {
+		switch {
+		case arg == "--claim":
+			return true
+		case arg == "--status=in_progress" || arg == "-s=in_progress":
+			return true
+		case (arg == "--status" || arg == "-s") && i+1 < len(args) && args[i+1] == "in_progress":
+			return true
+		}
+	}
+	return false
+}
+
+// getOpenHookWork returns the session's most recently started open work, if any.
+func getOpenHookWork(db *sql.DB, sessionID string) (string, string, error) {
+	var workID, issueID string
+	err := db.QueryRow(`
+		SELECT work_id, issue_id FROM agent_issue_work
+		WHERE session_id = ? AND ended_at IS NULL
+		ORDER BY started_at DESC, rowid DESC
+		LIMIT 1
+	`, sessionID).Scan(&workID, &issueID)
+	if err == sql.ErrNoRows {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get open work: %w", err)
+	}
+	return workID, issueID, nil
+}
+
+// getOpenIssueWork returns the session's open work on an issue, or sql.ErrNoRows.
+func getOpenIssueWork(db *sql.DB, sessionID, issueID string) (string, error) {
+	var workID string
+	err := db.QueryRow(`
+		SELECT work_id FROM agent_issue_work
+		WHERE session_id = ? AND issue_id = ? AND ended_at IS NULL
+		ORDER BY started_at DESC, rowid DESC
+		LIMIT 1
+	`, sessionID, issueID).Scan(&workID)
+	if err == sql.ErrNoRows {
+		return "", err
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get open work: %w", err)
+	}
+	return workID, nil
+}
+
+// getOpenSubagentSession returns the session's oldest subagent session still
+// running, or "" if there is none.
+func getOpenSubagentSession(db *sql.DB, sessionID string) (string, error) {
+	var subagentID string
+	err := db.QueryRow(`
+		SELECT p.session_id
+		FROM agent_session_parents p
+		JOIN agent_sessions s ON s.session_id = p.session_id
+		WHERE p.parent_session_id = ? AND s.ended_at IS NULL
+		ORDER BY s.started_at, s.rowid
+		LIMIT 1
+	`, sessionID).Scan(&subagentID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get subagent session: %w", err)
+	}
+	return subagentID, nil
+}
+
+// addSessionSkill adds a skill to the session's skills_used list.
+func addSessionSkill(db *sql.DB, sessionID, skillName string) error {
+	session, err := GetSession(db, sessionID)
+	if err != nil {
+		return err
+	}
+	if containsString(session.SkillsUsed, skillName) {
+		return nil
+	}
+	return UpdateSessionSkills(db, sessionID, append(session.SkillsUsed, skillName))
+}
+
+// updateTranscriptTokens sets a session's context tokens from the last model
+// usage in a Claude Code transcript. Missing transcripts are skipped.
+func updateTranscriptTokens(db *sql.DB, sessionID, transcriptPath string) error {
+	tokens := -1
+	err := readTranscript(transcriptPath, func(line []byte) bool {
+		if !bytes.Contains(line, []byte(`"usage"`)) {
+			return true
+		}
+		var entry struct {
+			Message struct {
+				Usage *struct {
+					InputTokens              int `json:"input_tokens"`
+					CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+					CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+					OutputTokens             int `json:"output_tokens"`
+				} `json:"usage"`
+			} `json:"message"`
+		}
+		if json.Unmarshal(line, &entry) == nil && entry.Message.Usage != nil {
+			u := entry.Message.Usage
+			tokens = u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens + u.OutputTokens
+		}
+		return true
+	})
+	if err != nil || tokens < 0 {
+		return err
+	}
+
+	return UpdateSessionTokens(db, sessionID, tokens)
+}
+
+// readTranscriptPrompt returns the text of the first user message in a Claude
+// Code transcript, or "" if there is none.
+func readTranscriptPrompt(transcriptPath string) (string, error) {
+	var prompt string
+	err := readTranscript(transcriptPath, func(line []byte) bool {
+		var entry struct {
+			Message struct {
+				Role    string          `json:"role"`
+				Content json.RawMessage `json:"content"`
+			} `json:"message"`
+		}
+		if json.Unmarshal(line, &entry) != nil || entry.Message.Role != "user" {
+			return true
+		}
+		var text string
+		if json.Unmarshal(entry.Message.Content, &text) != nil {
+			var blocks []struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			}
+			_ = json.Unmarshal(entry.Message.Content, &blocks)
+			var parts []string
+			for _, b := range blocks {
+				if b.Type == "text" {
+					parts = append(parts, b.Text)
+				}
+			}
+			text = strings.Join(parts, "\n")
+		}
+		prompt = strings.TrimSpace(text)
+		return false
+	})
+	return prompt, err
+}
+
+// readTranscript calls fn with each line of a Claude Code transcript until fn
+// returns false. Lines that don't decode, such as one still being written, are
+// for fn to skip. A missing transcript has no lines.
+func readTranscript(transcriptPath string, fn func(line []byte) bool) error {
+	if transcriptPath == "" {
+		return nil
+	}
+	f, err := os.Open(transcriptPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open transcript: %w", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 && !fn(line) {
+			return nil
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read transcript: %w", err)
+		}
+	}
+}
+
+// hookToolError returns the error reported for a tool call, if any.
+func hookToolError(payload HookPayload) string {
+	if payload.Error != "" {
+		return payload.Error
+	}
+
+	var response struct {
+		IsError bool            `json:"is_error"`
+		Success *bool           `json:"success"`
+		Error   json.RawMessage `json:"error"`
+	}
+	if len(payload.ToolResponse) == 0 || json.Unmarshal(payload.ToolResponse, &response) != nil {
+		return ""
+	}
+	var message string
+	if len(response.Error) > 0 && json.Unmarshal(response.Error, &message) != nil {
+		message = string(response.Error)
+	}
+	if message == "" && (response.IsError || (response.Success != nil && !*response.Success)) {
+		message = payload.ToolName + " failed"
+	}
+	return message
+}
+
+// modelTierFromModel maps a model name such as "claude-sonnet-4-5" to its tier.
+func modelTierFromModel(model string) string {
+	lower := strings.ToLower(model)
+	for _, tier := range []string{"opus", "sonnet", "haiku"} {
+		if strings.Contains(lower, tier) {
+			return tier
+		}
+	}
+	return model
+}
+
+// containsString reports whether list contains s.
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
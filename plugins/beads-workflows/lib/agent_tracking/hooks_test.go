@@ -0,0 +1,263 @@
+package agent_tracking
+
+import (
+	"bufio"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readHookFixture returns the hook payloads recorded in testdata/<name>, one
+// per line, in the order Claude Code sent them.
+func readHookFixture(t *testing.T, name string) []string {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var payloads []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			payloads = append(payloads, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return payloads
+}
+
+// replayHooks ingests recorded hook payloads in order.
+func replayHooks(t *testing.T, db *sql.DB, payloads []string) {
+	t.Helper()
+	for _, p := range payloads {
+		payload, err := IngestHook(db, strings.NewReader(p), HookOptions{})
+		if err != nil {
+			t.Fatalf("IngestHook(%s) failed: %v", payload.HookEventName, err)
+		}
+	}
+}
+
+// hookSession returns the only session an agent has.
+func hookSession(t *testing.T, db *sql.DB, agentName string) *Session {
+	t.Helper()
+	sessions, err := ListSessionsByAgent(db, agentName, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 {
+		t.Fatalf("%s sessions = %d, want 1", agentName, len(sessions))
+	}
+	return sessions[0]
+}
+
+func TestHookSessionReplay(t *testing.T) {
+	db := openTestDB(t)
+	payloads := readHookFixture(t, "hook_session.jsonl")
+
+	// Through the first turn's Stop: the session stays open with the tokens
+	// of the transcript's last usage.
+	firstTurn := 14
+	replayHooks(t, db, payloads[:firstTurn])
+	session := hookSession(t, db, "claude-code")
+	if session.EndedAt != nil || session.ContextTokens != 33817 {
+		t.Fatalf("session after Stop = %+v, want it open with 33817 tokens", session)
+	}
+
+	replayHooks(t, db, payloads[firstTurn:])
+	session = hookSession(t, db, "claude-code")
+	if session.EndedAt == nil || session.ExitReason != ExitCompleted {
+		t.Errorf("session = %+v, want one session ended as completed", session)
+	}
+	if session.ModelTier != "sonnet" || session.WorkspacePath != "/myStuff/project" {
+		t.Errorf("session = %+v", session)
+	}
+	if len(session.IssuesClaimed) != 1 || session.IssuesClaimed[0] != "agents-42" ||
+		len(session.SkillsUsed) != 1 || session.SkillsUsed[0] != "dependency-thinking" {
+		t.Errorf("issues = %v, skills = %v", session.IssuesClaimed, session.SkillsUsed)
+	}
+
+	work, err := ListWorkBySession(db, session.SessionID)
+	if err != nil {
+		t.Fatalf("ListWorkBySession failed: %v", err)
+	}
+	if len(work) != 1 || work[0].IssueID != "agents-42" || !work[0].Completed || work[0].WorkNotes != "Closed with bd close" {
+		t.Fatalf("work = %+v, want agents-42 claimed and closed", work)
+	}
+	workID := work[0].WorkID
+
+	calls, err := ListToolCallsBySession(db, session.SessionID)
+	if err != nil {
+		t.Fatalf("ListToolCallsBySession failed: %v", err)
+	}
+	tools := map[string]int{}
+	var failed []*ToolCall
+	for _, c := range calls {
+		if c.EndedAt == nil {
+			t.Errorf("call %s left open", c.ToolName)
+		}
+		if !c.Success {
+			failed = append(failed, c)
+		}
+		tools[c.ToolName]++
+	}
+	if len(calls) != 6 || tools["Bash"] != 4 || tools["Skill"] != 1 || tools["Task"] != 1 {
+		t.Errorf("tool calls = %v, want 4 Bash, 1 Skill and 1 Task", tools)
+	}
+	if len(failed) != 1 || failed[0].ToolName != "Bash" || failed[0].WorkID != workID {
+		t.Errorf("failed calls = %+v, want the go test run", failed)
+	}
+
+	errs, err := ListErrorsBySession(db, session.SessionID)
+	if err != nil {
+		t.Fatalf("ListErrorsBySession failed: %v", err)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("errors = %+v", errs)
+	}
+	if e := errs[0]; e.Category != ErrorCategoryTool || e.Command != "go test ./..." ||
+		e.Message != "Command failed with exit code 1" || e.WorkID != workID || e.IssueID != "agents-42" {
+		t.Errorf("error = %+v", e)
+	}
+
+	usage, err := ListSkillUsageBySession(db, session.SessionID)
+	if err != nil {
+		t.Fatalf("ListSkillUsageBySession failed: %v", err)
+	}
+	if len(usage) != 1 || usage[0].SkillName != "dependency-thinking" || usage[0].UsedForIssueID != "agents-42" {
+		t.Errorf("skill usage = %+v", usage)
+	}
+
+	subagents, err := ListSubagentSessions(db, session.SessionID)
+	if err != nil {
+		t.Fatalf("ListSubagentSessions failed: %v", err)
+	}
+	if len(subagents) != 1 {
+		t.Fatalf("subagents = %v", subagents)
+	}
+	reviewer, err := GetSession(db, subagents[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reviewer.AgentName != "code-reviewer" || reviewer.ModelTier != "haiku" ||
+		reviewer.ExitReason != ExitCompleted || reviewer.ContextTokens != 3145 {
+		t.Errorf("subagent = %+v, want code-reviewer ended by SubagentStop", reviewer)
+	}
+}
+
+func TestHookInterruptedReplay(t *testing.T) {
+	db := openTestDB(t)
+	payloads := readHookFixture(t, "hook_interrupted.jsonl")
+
+	// Stop ends neither the session nor the subagent still running.
+	replayHooks(t, db, payloads[:len(payloads)-1])
+	if planner := hookSession(t, db, "general-purpose"); planner.EndedAt != nil {
+		t.Errorf("general-purpose = %+v after Stop, want it running", planner)
+	}
+	replayHooks(t, db, payloads[len(payloads)-1:])
+
+	session := hookSession(t, db, "claude-code")
+	if session.ExitReason != ExitInterrupted || session.ModelTier != "opus" {
+		t.Errorf("session = %+v, want it interrupted by SessionEnd", session)
+	}
+
+	explore := hookSession(t, db, "Explore")
+	if explore.ExitReason != ExitError {
+		t.Errorf("Explore = %+v, want it ended by the failed Task call", explore)
+	}
+	failure, err := GetSessionFailure(db, explore.SessionID)
+	if err != nil {
+		t.Fatalf("GetSessionFailure failed: %v", err)
+	}
+	want := FailureDetails{Class: "tool_error", Message: "Agent type 'Explore' exceeded the maximum number of turns"}
+	if failure == nil || failure.Details != want {
+		t.Errorf("failure = %+v, want %+v", failure, want)
+	}
+
+	planner := hookSession(t, db, "general-purpose")
+	if planner.EndedAt == nil || planner.ExitReason != ExitInterrupted {
+		t.Errorf("general-purpose = %+v, want it interrupted at SessionEnd", planner)
+	}
+	if failure, err := GetSessionFailure(db, planner.SessionID); err != nil || failure != nil {
+		t.Errorf("general-purpose failure = %+v, %v", failure, err)
+	}
+
+	errs, err := ListErrorsBySession(db, session.SessionID)
+	if err != nil {
+		t.Fatalf("ListErrorsBySession failed: %v", err)
+	}
+	if len(errs) != 1 || errs[0].Command != "Task" || errs[0].WorkID != "" {
+		t.Errorf("errors = %+v, want the failed Task call", errs)
+	}
+}
+
+func TestHookParallelReplay(t *testing.T) {
+	db := openTestDB(t)
+	payloads := readHookFixture(t, "hook_parallel.jsonl")
+
+	// Through the first Stop: SubagentStop ended the Explore agent named by its
+	// transcript, not the oldest open one, and the background reviewer runs on.
+	firstTurn := 7
+	replayHooks(t, db, payloads[:firstTurn])
+	explore := hookSession(t, db, "Explore")
+	if explore.ExitReason != ExitCompleted || explore.ContextTokens != 1966 {
+		t.Errorf("Explore = %+v, want it ended by SubagentStop with 1966 tokens", explore)
+	}
+	if reviewer := hookSession(t, db, "code-reviewer"); reviewer.EndedAt != nil {
+		t.Errorf("code-reviewer = %+v after Stop, want it running in the background", reviewer)
+	}
+	session := hookSession(t, db, "claude-code")
+	if session.EndedAt != nil || session.ContextTokens != 17840 {
+		t.Errorf("session after Stop = %+v, want it open with 17840 tokens despite a partly written transcript", session)
+	}
+
+	replayHooks(t, db, payloads[firstTurn:])
+	reviewer := hookSession(t, db, "code-reviewer")
+	if reviewer.ExitReason != ExitCompleted {
+		t.Errorf("code-reviewer = %+v, want it ended by its SubagentStop", reviewer)
+	}
+	if explore := hookSession(t, db, "Explore"); explore.ExitReason != ExitCompleted {
+		t.Errorf("Explore = %+v, want it left completed", explore)
+	}
+	session = hookSession(t, db, "claude-code")
+	if session.ExitReason != ExitCompleted {
+		t.Errorf("session = %+v, want it completed at logout", session)
+	}
+
+	// The two Bash calls ended in the opposite order they started; each is
+	// matched by its tool_use_id.
+	calls, err := ListToolCallsBySession(db, session.SessionID)
+	if err != nil {
+		t.Fatalf("ListToolCallsBySession failed: %v", err)
+	}
+	var vet, test *ToolCall
+	for _, c := range calls {
+		if c.EndedAt == nil {
+			t.Errorf("call %s left open", c.ToolName)
+		}
+		if c.ToolName != "Bash" {
+			continue
+		}
+		switch c.BytesIn {
+		case len(`{"command":"go vet ./...","description":"Vet"}`):
+			vet = c
+		case len(`{"command":"go test ./...","description":"Run the whole test suite"}`):
+			test = c
+		}
+	}
+	if vet == nil || test == nil || !vet.Success || test.Success {
+		t.Errorf("go vet = %+v, go test = %+v; want only go test failed", vet, test)
+	}
+	errs, err := ListErrorsBySession(db, session.SessionID)
+	if err != nil {
+		t.Fatalf("ListErrorsBySession failed: %v", err)
+	}
+	if len(errs) != 1 || errs[0].Command != "go test ./..." {
+		t.Errorf("errors = %+v", errs)
+	}
+}
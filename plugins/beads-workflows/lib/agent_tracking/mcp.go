@@ -0,0 +1,478 @@
+package agent_tracking
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// mcpProtocolVersions are the Model Context Protocol versions the server speaks,
+// newest first.
+var mcpProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes.
+const (
+	jsonRPCParseError     = -32700
+	jsonRPCInvalidRequest = -32600
+	jsonRPCMethodNotFound = -32601
+	jsonRPCInvalidParams  = -32602
+)
+
+// MCPServerOptions identifies the server to clients.
+type MCPServerOptions struct {
+	Name    string // Server name (default "agent-tracking")
+	Version string // Server version (default Version())
+}
+
+// MCPServer is a Model Context Protocol server that lets agents record their
+// own sessions, work and skill usage and look up prior attempts on an issue.
+// It speaks newline-delimited JSON-RPC 2.0, as used by the stdio transport.
+type MCPServer struct {
+	db    *sql.DB
+	opts  MCPServerOptions
+	tools []mcpTool
+}
+
+// mcpTool is a tool exposed by the server. call decodes the arguments and
+// returns the result to encode as the tool's output.
+type mcpTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+	call        func(args json.RawMessage) (interface{}, error)
+}
+
+// jsonRPCMessage is a JSON-RPC 2.0 request, notification or response.
+type jsonRPCMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *jsonRPCError   `json:"error,omitempty"`
+}
+
+// jsonRPCError is the error member of a JSON-RPC response.
+type jsonRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewMCPServer creates an MCP server backed by a tracking database. Its tools are
+// start_session, end_session, record_work, complete_work, record_skill_usage,
+// get_issue_stats and list_work_by_issue.
+//
+// Example:
+//
+//	server, err := agent_tracking.NewMCPServer(db, agent_tracking.MCPServerOptions{})
+//	if err != nil {
+//	    return err
+//	}
+//	err = server.Serve(ctx, os.Stdin, os.Stdout)
+func NewMCPServer(db *sql.DB, opts MCPServerOptions) (*MCPServer, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	if opts.Name == "" {
+		opts.Name = "agent-tracking"
+	}
+	if opts.Version == "" {
+		opts.Version = Version()
+	}
+
+	s := &MCPServer{db: db, opts: opts}
+	s.tools = s.newTools()
+	return s, nil
+}
+
+// Serve reads requests from r and writes responses to w, one JSON message per
+// line, until r reaches EOF or ctx is cancelled. Requests are handled in order.
+//
+// Example:
+//
+//	// A client can send:
+//	// {"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"list_work_by_issue","arguments":{"issue_id":"agents-42"}}}
+//	err := server.Serve(ctx, os.Stdin, os.Stdout)
+func (s *MCPServer) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(bytes.TrimSpace(line)) > 0 {
+				select {
+				case lines <- line:
+				case <-done:
+					return
+				}
+			}
+			if err != nil {
+				if err == io.EOF {
+					err = nil
+				}
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-readErr:
+			if err != nil {
+				return fmt.Errorf("failed to read request: %w", err)
+			}
+			return nil
+		case line := <-lines:
+			response := s.handleMessage(line)
+			if response == nil {
+				continue
+			}
+			data, err := json.Marshal(response)
+			if err != nil {
+				return fmt.Errorf("failed to encode response: %w", err)
+			}
+			if _, err := w.Write(append(data, '\n')); err != nil {
+				return fmt.Errorf("failed to write response: %w", err)
+			}
+		}
+	}
+}
+
+// handleMessage handles one incoming message and returns the response, or nil
+// for notifications.
+func (s *MCPServer) handleMessage(line []byte) *jsonRPCMessage {
+	var req jsonRPCMessage
+	if err := json.Unmarshal(line, &req); err != nil {
+		return rpcError(json.RawMessage("null"), jsonRPCParseError, "parse error: "+err.Error())
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		if len(req.ID) == 0 {
+			req.ID = json.RawMessage("null")
+		}
+		return rpcError(req.ID, jsonRPCInvalidRequest, "invalid request")
+	}
+	if len(req.ID) == 0 {
+		// Notifications, such as notifications/initialized, need no response.
+		return nil
+	}
+
+	var result interface{}
+	var rpcErr *jsonRPCError
+	switch req.Method {
+	case "initialize":
+		result, rpcErr = s.initialize(req.Params)
+	case "ping":
+		result = struct{}{}
+	case "tools/list":
+		result = map[string]interface{}{"tools": s.tools}
+	case "tools/call":
+		result, rpcErr = s.callTool(req.Params)
+	default:
+		rpcErr = &jsonRPCError{Code: jsonRPCMethodNotFound, Message: "method not found: " + req.Method}
+	}
+
+	if rpcErr != nil {
+		return &jsonRPCMessage{JSONRPC: "2.0", ID: req.ID, Error: rpcErr}
+	}
+	return &jsonRPCMessage{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
+
+// initialize negotiates the protocol version: the client's version if the
+// server speaks it, otherwise the newest the server speaks.
+func (s *MCPServer) initialize(params json.RawMessage) (interface{}, *jsonRPCError) {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &jsonRPCError{Code: jsonRPCInvalidParams, Message: "invalid params: " + err.Error()}
+		}
+	}
+
+	version := mcpProtocolVersions[0]
+	for _, v := range mcpProtocolVersions {
+		if v == p.ProtocolVersion {
+			version = v
+		}
+	}
+
+	return map[string]interface{}{
+		"protocolVersion": version,
+		"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+		"serverInfo":      map[string]string{"name": s.opts.Name, "version": s.opts.Version},
+		"instructions":    "Record your session, work and skill usage. Before starting on an issue, call list_work_by_issue and get_issue_stats to see prior attempts.",
+	}, nil
+}
+
+// callTool runs a tool. Tool failures are reported in the result with isError
+// set, so the calling model sees them; unknown tools are protocol errors.
+func (s *MCPServer) callTool(params json.RawMessage) (interface{}, *jsonRPCError) {
+	var p struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &jsonRPCError{Code: jsonRPCInvalidParams, Message: "invalid params: " + err.Error()}
+	}
+
+	var tool *mcpTool
+	for i := range s.tools {
+		if s.tools[i].Name == p.Name {
+			tool = &s.tools[i]
+		}
+	}
+	if tool == nil {
+		return nil, &jsonRPCError{Code: jsonRPCInvalidParams, Message: "unknown tool: " + p.Name}
+	}
+	if len(p.Arguments) == 0 || string(p.Arguments) == "null" {
+		p.Arguments = json.RawMessage("{}")
+	}
+
+	result, err := tool.call(p.Arguments)
+	if err != nil {
+		return mcpToolResult(err.Error(), true), nil
+	}
+	text, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcpToolResult("failed to encode result: "+err.Error(), true), nil
+	}
+	return mcpToolResult(string(text), false), nil
+}
+
+// newTools defines the tools the server exposes.
+func (s *MCPServer) newTools() []mcpTool {
+	return []mcpTool{
+		{
+			Name:        "start_session",
+			Description: "Start an agent work session. Returns the session_id to pass to the other tools.",
+			InputSchema: mcpSchema([]string{"agent_name", "workspace_path"}, map[string]interface{}{
+				"agent_name":     mcpProperty("string", "Name of the agent, e.g. beads-workflow-orchestrator"),
+				"workspace_path": mcpProperty("string", "Path of the workspace the agent works in"),
+				"model_tier":     mcpProperty("string", "Model tier, e.g. sonnet or opus"),
+			}),
+			call: func(raw json.RawMessage) (interface{}, error) {
+				var args struct {
+					AgentName     string `json:"agent_name"`
+					WorkspacePath string `json:"workspace_path"`
+					ModelTier     string `json:"model_tier"`
+				}
+				if err := decodeToolArgs(raw, &args); err != nil {
+					return nil, err
+				}
+				sessionID, err := StartSession(s.db, args.AgentName, args.WorkspacePath, args.ModelTier)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]string{"session_id": sessionID}, nil
+			},
+		},
+		{
+			Name:        "end_session",
+			Description: "End a session. For sessions that did not complete, error_class, error_message and failed_phase describe what went wrong.",
+			InputSchema: mcpSchema([]string{"session_id", "exit_reason"}, map[string]interface{}{
+				"session_id":    mcpProperty("string", "Session to end"),
+				"exit_reason":   mcpExitReasonProperty(ExitReasons(), "Why the session ended"),
+				"error_class":   mcpProperty("string", "Error class, e.g. test_failure"),
+				"error_message": mcpProperty("string", "Error message"),
+				"failed_phase":  mcpProperty("string", "Phase that failed, e.g. verification"),
+			}),
+			call: func(raw json.RawMessage) (interface{}, error) {
+				var args struct {
+					SessionID    string     `json:"session_id"`
+					ExitReason   ExitReason `json:"exit_reason"`
+					ErrorClass   string     `json:"error_class"`
+					ErrorMessage string     `json:"error_message"`
+					FailedPhase  string     `json:"failed_phase"`
+				}
+				if err := decodeToolArgs(raw, &args); err != nil {
+					return nil, err
+				}
+				var err error
+				if args.ErrorClass != "" {
+					err = EndSessionWithFailure(s.db, args.SessionID, args.ExitReason, FailureDetails{
+						Class:   args.ErrorClass,
+						Message: args.ErrorMessage,
+						Phase:   args.FailedPhase,
+					})
+				} else {
+					err = EndSession(s.db, args.SessionID, args.ExitReason)
+				}
+				if err != nil {
+					return nil, err
+				}
+				return map[string]string{"session_id": args.SessionID, "exit_reason": string(args.ExitReason)}, nil
+			},
+		},
+		{
+			Name:        "record_work",
+			Description: "Record that the session started work on an issue. Returns the work_id to pass to complete_work.",
+			InputSchema: mcpSchema([]string{"session_id", "issue_id"}, map[string]interface{}{
+				"session_id": mcpProperty("string", "Session doing the work"),
+				"issue_id":   mcpProperty("string", "Beads issue ID, e.g. agents-42"),
+				"agent_name": mcpProperty("string", "Agent doing the work (default: the session's agent)"),
+				"rationale":  mcpProperty("string", "Why this issue was chosen"),
+			}),
+			call: func(raw json.RawMessage) (interface{}, error) {
+				var args struct {
+					SessionID string `json:"session_id"`
+					IssueID   string `json:"issue_id"`
+					AgentName string `json:"agent_name"`
+					Rationale string `json:"rationale"`
+				}
+				if err := decodeToolArgs(raw, &args); err != nil {
+					return nil, err
+				}
+				if args.AgentName == "" && args.SessionID != "" {
+					session, err := GetSession(s.db, args.SessionID)
+					if err != nil {
+						return nil, err
+					}
+					args.AgentName = session.AgentName
+				}
+				workID, err := RecordWork(s.db, args.SessionID, args.IssueID, args.AgentName, args.Rationale)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]string{"work_id": workID}, nil
+			},
+		},
+		{
+			Name:        "complete_work",
+			Description: "Mark work on an issue as completed.",
+			InputSchema: mcpSchema([]string{"work_id"}, map[string]interface{}{
+				"work_id": mcpProperty("string", "Work ID returned by record_work"),
+				"notes":   mcpProperty("string", "What was done"),
+			}),
+			call: func(raw json.RawMessage) (interface{}, error) {
+				var args struct {
+					WorkID string `json:"work_id"`
+					Notes  string `json:"notes"`
+				}
+				if err := decodeToolArgs(raw, &args); err != nil {
+					return nil, err
+				}
+				if err := CompleteWork(s.db, args.WorkID, args.Notes); err != nil {
+					return nil, err
+				}
+				return GetWork(s.db, args.WorkID)
+			},
+		},
+		{
+			Name:        "record_skill_usage",
+			Description: "Record that a skill was loaded during the session.",
+			InputSchema: mcpSchema([]string{"session_id", "skill_name"}, map[string]interface{}{
+				"session_id":    mcpProperty("string", "Session that loaded the skill"),
+				"skill_name":    mcpProperty("string", "Skill name, e.g. dependency-thinking"),
+				"issue_id":      mcpProperty("string", "Issue the skill was loaded for"),
+				"context_added": mcpProperty("integer", "Tokens of context the skill added"),
+			}),
+			call: func(raw json.RawMessage) (interface{}, error) {
+				var args struct {
+					SessionID    string `json:"session_id"`
+					SkillName    string `json:"skill_name"`
+					IssueID      string `json:"issue_id"`
+					ContextAdded int    `json:"context_added"`
+				}
+				if err := decodeToolArgs(raw, &args); err != nil {
+					return nil, err
+				}
+				if err := RecordSkillUsage(s.db, args.SessionID, args.SkillName, args.IssueID, args.ContextAdded); err != nil {
+					return nil, err
+				}
+				return map[string]string{"session_id": args.SessionID, "skill_name": args.SkillName}, nil
+			},
+		},
+		{
+			Name:        "get_issue_stats",
+			Description: "Get statistics for an issue: time spent, agents and sessions that worked on it and whether it was completed.",
+			InputSchema: mcpSchema([]string{"issue_id"}, map[string]interface{}{
+				"issue_id": mcpProperty("string", "Beads issue ID"),
+			}),
+			call: func(raw json.RawMessage) (interface{}, error) {
+				var args struct {
+					IssueID string `json:"issue_id"`
+				}
+				if err := decodeToolArgs(raw, &args); err != nil {
+					return nil, err
+				}
+				return GetIssueStats(s.db, args.IssueID)
+			},
+		},
+		{
+			Name:        "list_work_by_issue",
+			Description: "List prior work on an issue, with each attempt's rationale, notes and completion. Check this before starting on an issue.",
+			InputSchema: mcpSchema([]string{"issue_id"}, map[string]interface{}{
+				"issue_id": mcpProperty("string", "Beads issue ID"),
+			}),
+			call: func(raw json.RawMessage) (interface{}, error) {
+				var args struct {
+					IssueID string `json:"issue_id"`
+				}
+				if err := decodeToolArgs(raw, &args); err != nil {
+					return nil, err
+				}
+				work, err := ListWorkByIssue(s.db, args.IssueID)
+				if err != nil {
+					return nil, err
+				}
+				if work == nil {
+					work = []*Work{}
+				}
+				return work, nil
+			},
+		},
+	}
+}
+
+// decodeToolArgs decodes tool arguments, rejecting unknown fields.
+func decodeToolArgs(raw json.RawMessage, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+// mcpToolResult builds a tools/call result with one text content item.
+func mcpToolResult(text string, isError bool) map[string]interface{} {
+	return map[string]interface{}{
+		"content": []map[string]string{{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
+
+// mcpSchema builds an object JSON schema.
+func mcpSchema(required []string, properties map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// mcpProperty builds a JSON schema property.
+func mcpProperty(typ, description string) map[string]interface{} {
+	return map[string]interface{}{"type": typ, "description": description}
+}
+
+// mcpExitReasonProperty builds a JSON schema property limited to exit reasons.
+func mcpExitReasonProperty(values []ExitReason, description string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "enum": values, "description": description}
+}
+
+// rpcError builds a JSON-RPC error response.
+func rpcError(id json.RawMessage, code int, message string) *jsonRPCMessage {
+	return &jsonRPCMessage{JSONRPC: "2.0", ID: id, Error: &jsonRPCError{Code: code, Message: message}}
+}
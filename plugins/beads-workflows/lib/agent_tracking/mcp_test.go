@@ -0,0 +1,220 @@
+package agent_tracking
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// mcpResponse is a JSON-RPC response as a client decodes it.
+type mcpResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *jsonRPCError   `json:"error"`
+}
+
+// mcpClient talks to a server running Serve over pipes.
+type mcpClient struct {
+	t         *testing.T
+	requests  *io.PipeWriter
+	responses *bufio.Scanner
+}
+
+// send writes one raw line to the server.
+func (c *mcpClient) send(line string) {
+	c.t.Helper()
+	if _, err := io.WriteString(c.requests, line+"\n"); err != nil {
+		c.t.Fatalf("failed to send %s: %v", line, err)
+	}
+}
+
+// receive reads the next response.
+func (c *mcpClient) receive() mcpResponse {
+	c.t.Helper()
+	if !c.responses.Scan() {
+		c.t.Fatalf("no response: %v", c.responses.Err())
+	}
+	var resp mcpResponse
+	if err := json.Unmarshal(c.responses.Bytes(), &resp); err != nil {
+		c.t.Fatalf("response %s doesn't parse: %v", c.responses.Text(), err)
+	}
+	if resp.JSONRPC != "2.0" {
+		c.t.Errorf("response %s isn't JSON-RPC 2.0", c.responses.Text())
+	}
+	return resp
+}
+
+// call sends a request and returns its response, checking the ID.
+func (c *mcpClient) call(id int, method string, params interface{}) mcpResponse {
+	c.t.Helper()
+	data, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	c.send(string(data))
+	resp := c.receive()
+	if string(resp.ID) != fmt.Sprint(id) {
+		c.t.Fatalf("response ID = %s, want %d", resp.ID, id)
+	}
+	return resp
+}
+
+// callTool calls a tool and decodes its text content into out, if not nil.
+func (c *mcpClient) callTool(id int, name string, args map[string]interface{}, out interface{}) (bool, string) {
+	c.t.Helper()
+	resp := c.call(id, "tools/call", map[string]interface{}{"name": name, "arguments": args})
+	if resp.Error != nil {
+		c.t.Fatalf("%s: %+v", name, resp.Error)
+	}
+	var result struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		IsError bool `json:"isError"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil || len(result.Content) != 1 || result.Content[0].Type != "text" {
+		c.t.Fatalf("%s result = %s", name, resp.Result)
+	}
+	text := result.Content[0].Text
+	if out != nil && !result.IsError {
+		if err := json.Unmarshal([]byte(text), out); err != nil {
+			c.t.Fatalf("%s output %q doesn't parse: %v", name, text, err)
+		}
+	}
+	return result.IsError, text
+}
+
+func TestMCPServeOverPipes(t *testing.T) {
+	db := openTestDB(t)
+	server, err := NewMCPServer(db, MCPServerOptions{Version: "1.2.3"})
+	if err != nil {
+		t.Fatalf("NewMCPServer failed: %v", err)
+	}
+
+	requests, serverIn := io.Pipe()
+	serverOut, responses := io.Pipe()
+	served := make(chan error, 1)
+	go func() {
+		err := server.Serve(context.Background(), requests, responses)
+		responses.Close()
+		served <- err
+	}()
+	c := &mcpClient{t: t, requests: serverIn, responses: bufio.NewScanner(serverOut)}
+
+	resp := c.call(1, "initialize", map[string]interface{}{
+		"protocolVersion": "2025-03-26",
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      map[string]string{"name": "test", "version": "0"},
+	})
+	var init struct {
+		ProtocolVersion string            `json:"protocolVersion"`
+		ServerInfo      map[string]string `json:"serverInfo"`
+	}
+	if err := json.Unmarshal(resp.Result, &init); err != nil || resp.Error != nil {
+		t.Fatalf("initialize = %+v", resp)
+	}
+	if init.ProtocolVersion != "2025-03-26" || init.ServerInfo["name"] != "agent-tracking" || init.ServerInfo["version"] != "1.2.3" {
+		t.Errorf("initialize result = %s", resp.Result)
+	}
+
+	// The notification gets no response, so the next response is tools/list's.
+	c.send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	resp = c.call(2, "tools/list", nil)
+	var list struct {
+		Tools []struct {
+			Name        string                 `json:"name"`
+			InputSchema map[string]interface{} `json:"inputSchema"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(resp.Result, &list); err != nil {
+		t.Fatalf("tools/list = %+v", resp)
+	}
+	var names []string
+	for _, tool := range list.Tools {
+		names = append(names, tool.Name)
+		if tool.InputSchema["type"] != "object" {
+			t.Errorf("%s schema = %v", tool.Name, tool.InputSchema)
+		}
+	}
+	want := "start_session end_session record_work complete_work record_skill_usage get_issue_stats list_work_by_issue"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("tools = %s, want %s", got, want)
+	}
+
+	var started struct {
+		SessionID string `json:"session_id"`
+	}
+	c.callTool(3, "start_session", map[string]interface{}{
+		"agent_name": "beads-workflow-orchestrator", "workspace_path": "/ws", "model_tier": "opus",
+	}, &started)
+	if started.SessionID == "" {
+		t.Fatal("start_session returned no session ID")
+	}
+	var recorded struct {
+		WorkID string `json:"work_id"`
+	}
+	c.callTool(4, "record_work", map[string]interface{}{
+		"session_id": started.SessionID, "issue_id": "agents-42", "rationale": "highest priority",
+	}, &recorded)
+	var work []Work
+	if isError, text := c.callTool(5, "list_work_by_issue", map[string]interface{}{"issue_id": "agents-42"}, &work); isError {
+		t.Fatalf("list_work_by_issue failed: %s", text)
+	}
+	if len(work) != 1 || work[0].WorkID != recorded.WorkID || work[0].SessionID != started.SessionID ||
+		work[0].AgentName != "beads-workflow-orchestrator" || work[0].DecisionRationale != "highest priority" {
+		t.Errorf("work = %+v", work)
+	}
+
+	isError, text := c.callTool(6, "end_session", map[string]interface{}{
+		"session_id": started.SessionID, "exit_reason": "crashed",
+	}, nil)
+	if !isError || !strings.Contains(text, "crashed") {
+		t.Errorf("end_session with a bad exit reason = %v, %q; want an isError result", isError, text)
+	}
+
+	c.send(`{"jsonrpc":"2.0","id":7,"method":`)
+	if resp := c.receive(); resp.Error == nil || resp.Error.Code != jsonRPCParseError || string(resp.ID) != "null" {
+		t.Errorf("truncated request = %+v, want a parse error", resp)
+	}
+	if resp := c.call(8, "resources/list", nil); resp.Error == nil || resp.Error.Code != jsonRPCMethodNotFound {
+		t.Errorf("resources/list = %+v, want method not found", resp)
+	}
+	resp = c.call(9, "tools/call", map[string]interface{}{"name": "delete_session", "arguments": map[string]interface{}{}})
+	if resp.Error == nil || resp.Error.Code != jsonRPCInvalidParams || !strings.Contains(resp.Error.Message, "delete_session") {
+		t.Errorf("unknown tool = %+v, want an invalid params error", resp)
+	}
+
+	serverIn.Close()
+	if err := <-served; err != nil {
+		t.Errorf("Serve returned %v at EOF", err)
+	}
+	session, err := GetSession(db, started.SessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.EndedAt != nil {
+		t.Errorf("session = %+v, want it left open by the rejected end_session", session)
+	}
+}
+
+func TestMCPServeStopsOnCancel(t *testing.T) {
+	db := openTestDB(t)
+	server, err := NewMCPServer(db, MCPServerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests, serverIn := io.Pipe()
+	defer serverIn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := server.Serve(ctx, requests, io.Discard); err != context.Canceled {
+		t.Errorf("Serve = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,376 @@
+package agent_tracking
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Webhook request headers.
+const (
+	WebhookHeaderEvent     = "X-Agent-Tracking-Event"
+	WebhookHeaderDelivery  = "X-Agent-Tracking-Delivery"
+	WebhookHeaderTimestamp = "X-Agent-Tracking-Timestamp"
+	WebhookHeaderSignature = "X-Agent-Tracking-Signature"
+)
+
+// NotificationRule decides whether an event should trigger a notification.
+// Match is only called for events whose type is listed in EventTypes; it returns
+// the notification message, or an empty string when the rule doesn't fire.
+type NotificationRule struct {
+	Name       string
+	EventTypes []string
+	Match      func(db *sql.DB, e Event) (string, error)
+}
+
+// Webhook is an HTTP endpoint that receives notifications. When Rules is empty
+// the webhook receives notifications from every rule. Payloads are signed with
+// Secret when it is set.
+type Webhook struct {
+	Name   string   `json:"name"`
+	URL    string   `json:"url"`
+	Secret string   `json:"secret,omitempty"`
+	Rules  []string `json:"rules,omitempty"`
+}
+
+// NotifierConfig configures a Notifier.
+type NotifierConfig struct {
+	Webhooks    []Webhook
+	Rules       []NotificationRule // Rules to evaluate (default DefaultNotificationRules)
+	MaxRetries  int                // Retries after a failed delivery; zero uses the default of 3, negative disables retries
+	BackoffBase time.Duration      // Delay before the first retry, doubled for each retry (default 1s)
+	BackoffMax  time.Duration      // Longest delay between retries (default 30s)
+	QueueSize   int                // Events buffered for rule evaluation before new ones are dropped (default 256)
+	Client      *http.Client       // HTTP client (default 10s timeout)
+	OnError     func(error)        // Called when a rule fails or a delivery gives up
+}
+
+// Notification is the JSON payload posted to webhooks.
+type Notification struct {
+	ID        string    `json:"id"`
+	Rule      string    `json:"rule"`
+	Message   string    `json:"message"`
+	Event     Event     `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Notifier evaluates notification rules against the tracking events this process
+// writes to its database and delivers matching notifications to webhooks.
+type Notifier struct {
+	db         *sql.DB
+	cfg        NotifierConfig
+	sub        *Subscription
+	dispatch   sync.WaitGroup
+	deliveries sync.WaitGroup
+	closeOnce  sync.Once
+}
+
+// DefaultNotificationRules returns the built-in rules: sessions ending with an
+// error, exceeded budgets, and issues worked on by three agents without completion.
+func DefaultNotificationRules() []NotificationRule {
+	return []NotificationRule{
+		SessionErrorRule(),
+		BudgetExceededRule(),
+		StalledIssueRule(3),
+	}
+}
+
+// SessionErrorRule fires when a session ends with the "error" exit reason.
+func SessionErrorRule() NotificationRule {
+	return NotificationRule{
+		Name:       "session_error",
+		EventTypes: []string{EventSessionEnded},
+		Match: func(db *sql.DB, e Event) (string, error) {
+			if e.ExitReason != ExitError {
+				return "", nil
+			}
+			var agentName string
+			err := db.QueryRow(`SELECT agent_name FROM agent_sessions WHERE session_id = ?`, e.SessionID).Scan(&agentName)
+			if err != nil && err != sql.ErrNoRows {
+				return "", fmt.Errorf("failed to get session agent: %w", err)
+			}
+			return fmt.Sprintf("Session %s (%s) ended with an error", e.SessionID, agentName), nil
+		},
+	}
+}
+
+// BudgetExceededRule fires when a write takes a budget over its limit. It fires
+// once per budget and period, not on every later write.
+func BudgetExceededRule() NotificationRule {
+	return NotificationRule{
+		Name:       "budget_exceeded",
+		EventTypes: []string{EventBudgetExceeded},
+		Match: func(db *sql.DB, e Event) (string, error) {
+			err := &BudgetExceededError{Exceeded: e.Budgets}
+			return fmt.Sprintf("Session %s: %s", e.SessionID, err.Error()), nil
+		},
+	}
+}
+
+// StalledIssueRule fires when work starts on an issue that the given number of
+// distinct agents have now worked on without completing it. It fires once per
+// issue, for the work entry that brings in the Nth agent; later agents on the
+// same issue don't fire it again.
+func StalledIssueRule(agents int) NotificationRule {
+	return NotificationRule{
+		Name:       "stalled_issue",
+		EventTypes: []string{EventWorkStarted},
+		Match: func(db *sql.DB, e Event) (string, error) {
+			// Rules run after the event was queued, so count only the agents that
+			// had started work up to this entry.
+			var before, upTo, completed int
+			err := db.QueryRow(`
+				SELECT
+					COUNT(DISTINCT CASE WHEN w.rowid < cur.rowid THEN w.agent_name END),
+					COUNT(DISTINCT CASE WHEN w.rowid <= cur.rowid THEN w.agent_name END),
+					COALESCE(MAX(w.completed), 0)
+				FROM agent_issue_work w
+				JOIN agent_issue_work cur ON cur.work_id = ?
+				WHERE w.issue_id = cur.issue_id
+			`, e.WorkID).Scan(&before, &upTo, &completed)
+			if err != nil {
+				return "", fmt.Errorf("failed to get issue agents: %w", err)
+			}
+			if completed == 1 || upTo != agents || before == upTo {
+				return "", nil
+			}
+			return fmt.Sprintf("Issue %s has been worked on by %d agents without completion (latest: %s)",
+				e.IssueID, upTo, e.AgentName), nil
+		},
+	}
+}
+
+// NewNotifier starts a notifier for the events this process writes to db. Events
+// written to other databases, such as other federation members, are ignored.
+// Call Close to stop it and wait for pending deliveries.
+//
+// Example:
+//
+//	notifier, err := agent_tracking.NewNotifier(db, agent_tracking.NotifierConfig{
+//	    Webhooks: []agent_tracking.Webhook{
+//	        {Name: "ops", URL: "https://hooks.example.com/agents", Secret: os.Getenv("AGENT_WEBHOOK_SECRET")},
+//	    },
+//	})
+//	if err != nil {
+//	    return err
+//	}
+//	defer notifier.Close()
+func NewNotifier(db *sql.DB, cfg NotifierConfig) (*Notifier, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	for _, w := range cfg.Webhooks {
+		if w.URL == "" {
+			return nil, fmt.Errorf("webhook URL is required")
+		}
+	}
+	if cfg.Rules == nil {
+		cfg.Rules = DefaultNotificationRules()
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	} else if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.BackoffBase <= 0 {
+		cfg.BackoffBase = time.Second
+	}
+	if cfg.BackoffMax <= 0 {
+		cfg.BackoffMax = 30 * time.Second
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 256
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	var types []string
+	for _, rule := range cfg.Rules {
+		types = append(types, rule.EventTypes...)
+	}
+
+	n := &Notifier{db: db, cfg: cfg}
+	n.sub = Subscribe(SubscribeOptions{
+		DB:         db,
+		Filter:     EventFilter{Types: types},
+		BufferSize: cfg.QueueSize,
+		OnDrop: func(e Event) {
+			n.reportError(fmt.Errorf("notification queue full, dropped %s event", e.Type))
+		},
+	})
+
+	n.dispatch.Add(1)
+	go n.run()
+
+	return n, nil
+}
+
+// Close stops receiving events and waits until queued events are evaluated and
+// their deliveries have succeeded or exhausted their retries.
+func (n *Notifier) Close() {
+	n.closeOnce.Do(func() {
+		n.sub.Close()
+		n.dispatch.Wait()
+		n.deliveries.Wait()
+	})
+}
+
+// run evaluates the rules for each queued event.
+func (n *Notifier) run() {
+	defer n.dispatch.Done()
+
+	for e := range n.sub.Events() {
+		for _, rule := range n.cfg.Rules {
+			if !ruleHandles(rule, e.Type) {
+				continue
+			}
+			message, err := rule.Match(n.db, e)
+			if err != nil {
+				n.reportError(fmt.Errorf("rule %s failed: %w", rule.Name, err))
+				continue
+			}
+			if message == "" {
+				continue
+			}
+
+			notification := Notification{
+				ID:        generateID(),
+				Rule:      rule.Name,
+				Message:   message,
+				Event:     e,
+				CreatedAt: time.Now().UTC(),
+			}
+			for _, w := range n.cfg.Webhooks {
+				if !webhookWantsRule(w, rule.Name) {
+					continue
+				}
+				n.deliveries.Add(1)
+				go func(w Webhook) {
+					defer n.deliveries.Done()
+					if err := n.deliver(w, notification); err != nil {
+						n.reportError(err)
+					}
+				}(w)
+			}
+		}
+	}
+}
+
+// deliver posts a notification to a webhook, retrying network errors, 429 and
+// 5xx responses with exponential backoff.
+func (n *Notifier) deliver(w Webhook, notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	backoff := n.cfg.BackoffBase
+	var lastErr error
+	for attempt := 0; attempt <= n.cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+			if backoff > n.cfg.BackoffMax {
+				backoff = n.cfg.BackoffMax
+			}
+		}
+
+		retry, err := n.post(w, notification, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+
+	return fmt.Errorf("failed to deliver %s notification to webhook %s: %w", notification.Rule, w.Name, lastErr)
+}
+
+// post makes one delivery attempt and reports whether a failure is worth retrying.
+func (n *Notifier) post(w Webhook, notification Notification, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookHeaderEvent, notification.Rule)
+	req.Header.Set(WebhookHeaderDelivery, notification.ID)
+	req.Header.Set(WebhookHeaderTimestamp, timestamp)
+	if w.Secret != "" {
+		req.Header.Set(WebhookHeaderSignature, SignWebhookPayload(w.Secret, timestamp, body))
+	}
+
+	resp, err := n.cfg.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+}
+
+// reportError passes an error to the configured error handler.
+func (n *Notifier) reportError(err error) {
+	if n.cfg.OnError != nil {
+		n.cfg.OnError(err)
+	}
+}
+
+// ruleHandles reports whether a rule evaluates events of the given type.
+func ruleHandles(rule NotificationRule, eventType string) bool {
+	for _, t := range rule.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookWantsRule reports whether a webhook subscribes to a rule.
+func webhookWantsRule(w Webhook, rule string) bool {
+	if len(w.Rules) == 0 {
+		return true
+	}
+	for _, r := range w.Rules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// SignWebhookPayload returns the signature header value for a payload:
+// "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the secret.
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks a signature produced by SignWebhookPayload.
+func VerifyWebhookSignature(secret, timestamp string, body []byte, signature string) bool {
+	expected := SignWebhookPayload(secret, timestamp, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
@@ -0,0 +1,285 @@
+package agent_tracking
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver is a test webhook endpoint. It answers with the queued
+// statuses in order, then 204, and records every request it receives.
+type webhookReceiver struct {
+	t        *testing.T
+	secret   string
+	statuses []int
+
+	mu       sync.Mutex
+	requests []receivedWebhook
+}
+
+// receivedWebhook is one request made to a webhookReceiver.
+type receivedWebhook struct {
+	at           time.Time
+	header       http.Header
+	notification Notification
+}
+
+func newWebhookReceiver(t *testing.T, secret string, statuses ...int) (*webhookReceiver, *httptest.Server) {
+	r := &webhookReceiver{t: t, secret: secret, statuses: statuses}
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return r, server
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		r.t.Errorf("failed to read webhook body: %v", err)
+	}
+	if r.secret != "" && !VerifyWebhookSignature(r.secret, req.Header.Get(WebhookHeaderTimestamp), body, req.Header.Get(WebhookHeaderSignature)) {
+		r.t.Errorf("invalid webhook signature %q", req.Header.Get(WebhookHeaderSignature))
+	}
+	var notification Notification
+	if err := json.Unmarshal(body, &notification); err != nil {
+		r.t.Errorf("invalid webhook payload: %v", err)
+	}
+
+	r.mu.Lock()
+	r.requests = append(r.requests, receivedWebhook{at: time.Now(), header: req.Header.Clone(), notification: notification})
+	status := http.StatusNoContent
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	r.mu.Unlock()
+
+	w.WriteHeader(status)
+}
+
+func (r *webhookReceiver) received() []receivedWebhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedWebhook(nil), r.requests...)
+}
+
+// rules returns the rule names of the received notifications, in order.
+func (r *webhookReceiver) rules() []string {
+	var rules []string
+	for _, req := range r.received() {
+		rules = append(rules, req.notification.Rule)
+	}
+	return rules
+}
+
+func TestNotifierRetriesWithBackoff(t *testing.T) {
+	db := openTestDB(t)
+	receiver, server := newWebhookReceiver(t, "", http.StatusServiceUnavailable, http.StatusTooManyRequests)
+
+	notifier, err := NewNotifier(db, NotifierConfig{
+		Webhooks:    []Webhook{{Name: "test", URL: server.URL}},
+		Rules:       []NotificationRule{SessionErrorRule()},
+		BackoffBase: 20 * time.Millisecond,
+		BackoffMax:  time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewNotifier failed: %v", err)
+	}
+
+	sessionID, err := StartSession(db, "agent", "/ws", "sonnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := EndSession(db, sessionID, ExitError); err != nil {
+		t.Fatal(err)
+	}
+	notifier.Close()
+
+	requests := receiver.received()
+	if len(requests) != 3 {
+		t.Fatalf("got %d attempts, want 3", len(requests))
+	}
+	for i, min := range []time.Duration{20 * time.Millisecond, 40 * time.Millisecond} {
+		if gap := requests[i+1].at.Sub(requests[i].at); gap < min {
+			t.Errorf("retry %d after %v, want at least %v", i+1, gap, min)
+		}
+	}
+	if id := requests[0].header.Get(WebhookHeaderDelivery); id == "" || id != requests[2].header.Get(WebhookHeaderDelivery) {
+		t.Errorf("retries have delivery IDs %q and %q, want the same", id, requests[2].header.Get(WebhookHeaderDelivery))
+	}
+}
+
+func TestNotifierGivesUp(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		maxRetries int
+		statuses   []int
+		attempts   int
+	}{
+		{"exhausts retries", 2, []int{500, 502, 503, 504}, 3},
+		{"retries disabled", -1, []int{500}, 1},
+		{"client error", 0, []int{400}, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db := openTestDB(t)
+			receiver, server := newWebhookReceiver(t, "", tc.statuses...)
+
+			var mu sync.Mutex
+			var errs []error
+			notifier, err := NewNotifier(db, NotifierConfig{
+				Webhooks:    []Webhook{{Name: "test", URL: server.URL}},
+				Rules:       []NotificationRule{SessionErrorRule()},
+				MaxRetries:  tc.maxRetries,
+				BackoffBase: time.Millisecond,
+				OnError: func(err error) {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				},
+			})
+			if err != nil {
+				t.Fatalf("NewNotifier failed: %v", err)
+			}
+
+			sessionID, err := StartSession(db, "agent", "/ws", "sonnet")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := EndSession(db, sessionID, ExitError); err != nil {
+				t.Fatal(err)
+			}
+			notifier.Close()
+
+			if got := len(receiver.received()); got != tc.attempts {
+				t.Errorf("got %d attempts, want %d", got, tc.attempts)
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), "failed to deliver session_error notification to webhook test") {
+				t.Errorf("errors = %v, want one delivery failure", errs)
+			}
+		})
+	}
+}
+
+func TestNotifierSignsPayloads(t *testing.T) {
+	db := openTestDB(t)
+	signed, signedServer := newWebhookReceiver(t, "s3cret")
+	unsigned, unsignedServer := newWebhookReceiver(t, "")
+
+	notifier, err := NewNotifier(db, NotifierConfig{
+		Webhooks: []Webhook{
+			{Name: "signed", URL: signedServer.URL, Secret: "s3cret"},
+			{Name: "unsigned", URL: unsignedServer.URL},
+		},
+		Rules: []NotificationRule{SessionErrorRule()},
+	})
+	if err != nil {
+		t.Fatalf("NewNotifier failed: %v", err)
+	}
+
+	sessionID, err := StartSession(db, "agent", "/ws", "sonnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := EndSession(db, sessionID, ExitError); err != nil {
+		t.Fatal(err)
+	}
+	notifier.Close()
+
+	requests := signed.received()
+	if len(requests) != 1 {
+		t.Fatalf("signed webhook got %d requests, want 1", len(requests))
+	}
+	header := requests[0].header
+	if !strings.HasPrefix(header.Get(WebhookHeaderSignature), "sha256=") || header.Get(WebhookHeaderTimestamp) == "" {
+		t.Errorf("signature headers = %q, %q", header.Get(WebhookHeaderSignature), header.Get(WebhookHeaderTimestamp))
+	}
+	if header.Get(WebhookHeaderEvent) != "session_error" || header.Get(WebhookHeaderDelivery) != requests[0].notification.ID {
+		t.Errorf("event headers = %q, %q", header.Get(WebhookHeaderEvent), header.Get(WebhookHeaderDelivery))
+	}
+	if n := requests[0].notification; n.Event.SessionID != sessionID || n.Event.AgentName != "agent" {
+		t.Errorf("notification event = %+v", n.Event)
+	}
+
+	requests = unsigned.received()
+	if len(requests) != 1 || requests[0].header.Get(WebhookHeaderSignature) != "" {
+		t.Errorf("unsigned webhook got %d requests with signature %q", len(requests), requests[0].header.Get(WebhookHeaderSignature))
+	}
+
+	if VerifyWebhookSignature("s3cret", "1700000000", []byte(`{}`), SignWebhookPayload("other", "1700000000", []byte(`{}`))) {
+		t.Error("signature with the wrong secret verified")
+	}
+}
+
+func TestDefaultNotificationRules(t *testing.T) {
+	db := openTestDB(t)
+	receiver, server := newWebhookReceiver(t, "")
+	budgets, budgetServer := newWebhookReceiver(t, "")
+
+	notifier, err := NewNotifier(db, NotifierConfig{
+		Webhooks: []Webhook{
+			{Name: "all", URL: server.URL},
+			{Name: "budgets", URL: budgetServer.URL, Rules: []string{"budget_exceeded"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewNotifier failed: %v", err)
+	}
+
+	// session_error fires for errors only.
+	for _, reason := range []ExitReason{ExitCompleted, ExitError} {
+		sessionID, err := StartSession(db, "agent", "/ws", "sonnet")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := EndSession(db, sessionID, reason); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// budget_exceeded fires for a soft budget.
+	if _, err := CreateBudget(db, Budget{
+		Scope: BudgetScopeAgent, ScopeValue: "spender", Metric: BudgetMetricTokens, Period: BudgetPeriodDay, Limit: 100,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	spender, err := StartSession(db, "spender", "/ws", "sonnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateSessionTokens(db, spender, 500); err != nil {
+		t.Fatal(err)
+	}
+
+	// stalled_issue fires for the third agent only.
+	for i := 1; i <= 4; i++ {
+		agent := fmt.Sprintf("agent-%d", i)
+		sessionID, err := StartSession(db, agent, "/ws", "sonnet")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := RecordWork(db, sessionID, "agents-42", agent, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	notifier.Close()
+
+	// Deliveries run concurrently, so compare the rules in sorted order.
+	rules := receiver.rules()
+	sort.Strings(rules)
+	if got := strings.Join(rules, ","); got != "budget_exceeded,session_error,stalled_issue" {
+		t.Errorf("notifications = %s, want one from each default rule", got)
+	}
+	for _, req := range receiver.received() {
+		if req.notification.Rule == "stalled_issue" && !strings.Contains(req.notification.Message, "(latest: agent-3)") {
+			t.Errorf("stalled issue message = %q", req.notification.Message)
+		}
+	}
+	if got := strings.Join(budgets.rules(), ","); got != "budget_exceeded" {
+		t.Errorf("budget webhook notifications = %s, want budget_exceeded only", got)
+	}
+}
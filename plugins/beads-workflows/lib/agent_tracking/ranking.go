@@ -0,0 +1,310 @@
+package agent_tracking
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RankingWeights sets how much each factor contributes to a ready issue's score.
+// Every factor is normalized to the range 0..1 (or -1..1 for agent fit) before weighting.
+type RankingWeights struct {
+	Priority       float64 `json:"priority"`
+	Age            float64 `json:"age"`
+	Unblocks       float64 `json:"unblocks"`
+	FailedAttempts float64 `json:"failed_attempts"`
+	AgentFit       float64 `json:"agent_fit"`
+}
+
+// DefaultRankingWeights returns weights that keep priority dominant, in line with the
+// beads-workflow-orchestrator selection rules, and use the other factors as tie-breakers.
+func DefaultRankingWeights() RankingWeights {
+	return RankingWeights{
+		Priority:       4,
+		Age:            1,
+		Unblocks:       2,
+		FailedAttempts: 2,
+		AgentFit:       1,
+	}
+}
+
+// RankingOptions controls how ready issues are ranked.
+type RankingOptions struct {
+	AgentName string          // Agent the work is being selected for; enables the agent fit factor
+	Weights   *RankingWeights // Factor weights (default DefaultRankingWeights)
+	Now       time.Time       // Reference time for issue age (default now)
+}
+
+// RankingFactor is one factor's contribution to a ranked issue's score.
+type RankingFactor struct {
+	Name   string  `json:"name"`
+	Value  float64 `json:"value"`
+	Score  float64 `json:"score"`
+	Detail string  `json:"detail"`
+}
+
+// RankedIssue is a ready issue with its score and the reasoning behind it.
+type RankedIssue struct {
+	Issue     *BeadsIssue     `json:"issue"`
+	Rank      int             `json:"rank"`
+	Score     float64         `json:"score"`
+	Factors   []RankingFactor `json:"factors"`
+	Rationale string          `json:"rationale"`
+}
+
+// agentTypeRecord is an agent's history on one issue type.
+type agentTypeRecord struct {
+	attempted int
+	completed int
+}
+
+// RankReadyIssues scores ready issues and returns them best first. When ready is nil,
+// the candidates are loaded with ListReadyIssues.
+//
+// Issues score higher for higher priority, greater age and unblocking more open issues,
+// and lower for prior attempts that ended without completing. With an agent name, the
+// agent's completion rate on the same issue type adjusts the score up or down.
+// Rationale summarizes the factors and can be passed to RecordWork unchanged.
+//
+// Example:
+//
+//	ranked, err := agent_tracking.RankReadyIssues(db, nil, agent_tracking.RankingOptions{
+//	    AgentName: "beads-workflow-orchestrator",
+//	})
+//	best := ranked[0]
+//	workID, err := agent_tracking.RecordWork(db, sessionID, best.Issue.ID, "beads-workflow-orchestrator", best.Rationale)
+func RankReadyIssues(db *sql.DB, ready []*BeadsIssue, opts RankingOptions) ([]*RankedIssue, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	weights := DefaultRankingWeights()
+	if opts.Weights != nil {
+		weights = *opts.Weights
+	}
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	if ready == nil {
+		var err error
+		ready, err = ListReadyIssues(db)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(ready) == 0 {
+		return nil, nil
+	}
+
+	unblocks, err := getUnblockCounts(db)
+	if err != nil {
+		return nil, err
+	}
+
+	failed, err := getFailedAttemptCounts(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var fit map[string]agentTypeRecord
+	if opts.AgentName != "" {
+		fit, err = getAgentTypeRecords(db, opts.AgentName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ranked := make([]*RankedIssue, 0, len(ready))
+	for _, issue := range ready {
+		r := &RankedIssue{Issue: issue}
+
+		priority := float64(4-clampInt(issue.Priority, 0, 4)) / 4
+		r.addFactor("priority", priority, weights.Priority, fmt.Sprintf("P%d priority", issue.Priority))
+
+		ageDays := now.Sub(issue.CreatedAt).Hours() / 24
+		if ageDays < 0 {
+			ageDays = 0
+		}
+		r.addFactor("age", minFloat(ageDays/30, 1), weights.Age, fmt.Sprintf("open %s", pluralize(int(ageDays), "day")))
+
+		n := unblocks[issue.ID]
+		r.addFactor("unblocks", minFloat(float64(n)/5, 1), weights.Unblocks, fmt.Sprintf("unblocks %s", pluralize(n, "issue")))
+
+		n = failed[issue.ID]
+		if n > 0 {
+			r.addFactor("failed_attempts", -minFloat(float64(n)/3, 1), weights.FailedAttempts,
+				fmt.Sprintf("%s without completion", pluralize(n, "prior attempt")))
+		}
+
+		if opts.AgentName != "" {
+			rec := fit[issue.IssueType]
+			if rec.attempted > 0 {
+				rate := float64(rec.completed) / float64(rec.attempted)
+				r.addFactor("agent_fit", rate*2-1, weights.AgentFit,
+					fmt.Sprintf("%s completed %d of %d %s issues", opts.AgentName, rec.completed, rec.attempted, issue.IssueType))
+			} else {
+				r.addFactor("agent_fit", 0, weights.AgentFit,
+					fmt.Sprintf("%s has no history on %s issues", opts.AgentName, issue.IssueType))
+			}
+		}
+
+		ranked = append(ranked, r)
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Issue.Priority != b.Issue.Priority {
+			return a.Issue.Priority < b.Issue.Priority
+		}
+		if !a.Issue.CreatedAt.Equal(b.Issue.CreatedAt) {
+			return a.Issue.CreatedAt.Before(b.Issue.CreatedAt)
+		}
+		return a.Issue.ID < b.Issue.ID
+	})
+
+	for i, r := range ranked {
+		r.Rank = i + 1
+		details := make([]string, len(r.Factors))
+		for j, f := range r.Factors {
+			details[j] = f.Detail
+		}
+		r.Rationale = fmt.Sprintf("Ranked %d of %d (score %.2f): %s",
+			r.Rank, len(ranked), r.Score, strings.Join(details, "; "))
+	}
+
+	return ranked, nil
+}
+
+// addFactor records a factor and adds its weighted value to the score.
+func (r *RankedIssue) addFactor(name string, value, weight float64, detail string) {
+	score := value * weight
+	r.Factors = append(r.Factors, RankingFactor{
+		Name:   name,
+		Value:  value,
+		Score:  score,
+		Detail: detail,
+	})
+	r.Score += score
+}
+
+// getUnblockCounts returns, per issue, the number of open issues it blocks.
+func getUnblockCounts(db *sql.DB) (map[string]int, error) {
+	rows, err := db.Query(`
+		SELECT d.depends_on_id, COUNT(*)
+		FROM dependencies d
+		JOIN issues i ON i.id = d.issue_id
+		WHERE d.type = 'blocks' AND i.status != 'closed'
+		GROUP BY d.depends_on_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unblock counts: %w", err)
+	}
+	defer rows.Close()
+
+	return scanIssueCounts(rows)
+}
+
+// getFailedAttemptCounts returns, per issue, the number of work entries that were
+// never completed and whose session has ended.
+func getFailedAttemptCounts(db *sql.DB) (map[string]int, error) {
+	rows, err := db.Query(`
+		SELECT w.issue_id, COUNT(*)
+		FROM agent_issue_work w
+		JOIN agent_sessions s ON w.session_id = s.session_id
+		WHERE w.completed = 0 AND s.ended_at IS NOT NULL
+		GROUP BY w.issue_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get failed attempts: %w", err)
+	}
+	defer rows.Close()
+
+	return scanIssueCounts(rows)
+}
+
+// getAgentTypeRecords returns an agent's attempted and completed issue counts per issue type.
+func getAgentTypeRecords(db *sql.DB, agentName string) (map[string]agentTypeRecord, error) {
+	rows, err := db.Query(`
+		SELECT
+			i.issue_type,
+			COUNT(DISTINCT w.issue_id) as attempted,
+			COUNT(DISTINCT CASE WHEN w.completed = 1 THEN w.issue_id END) as completed
+		FROM agent_issue_work w
+		JOIN issues i ON i.id = w.issue_id
+		WHERE w.agent_name = ?
+		GROUP BY i.issue_type
+	`, agentName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get agent history: %w", err)
+	}
+	defer rows.Close()
+
+	records := make(map[string]agentTypeRecord)
+	for rows.Next() {
+		var issueType string
+		var rec agentTypeRecord
+		if err := rows.Scan(&issueType, &rec.attempted, &rec.completed); err != nil {
+			return nil, fmt.Errorf("failed to scan agent history: %w", err)
+		}
+		records[issueType] = rec
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating agent history: %w", err)
+	}
+
+	return records, nil
+}
+
+// scanIssueCounts scans (issue_id, count) rows into a map.
+func scanIssueCounts(rows *sql.Rows) (map[string]int, error) {
+	counts := make(map[string]int)
+	for rows.Next() {
+		var issueID string
+		var count int
+		if err := rows.Scan(&issueID, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan issue count: %w", err)
+		}
+		counts[issueID] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating issue counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// pluralize formats a count with a singular or plural noun.
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// clampInt limits v to the range [lo, hi].
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// minFloat returns the smaller of a and b.
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,88 @@
+package agent_tracking
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRankReadyIssues(t *testing.T) {
+	db := openTestDB(t)
+	mustExec(t, db, `
+		INSERT INTO issues (id, title, priority, issue_type, created_at) VALUES
+			('agents-1', 'Old and failing', 1, 'task', '2026-03-01 09:00:00'),
+			('agents-2', 'Blocker', 1, 'task', '2026-03-30 09:00:00'),
+			('agents-3', 'Lower priority', 2, 'task', '2026-03-30 09:00:00'),
+			('agents-4', 'Blocked', 0, 'task', '2026-03-30 09:00:00')
+	`)
+	mustExec(t, db, `INSERT INTO dependencies (issue_id, depends_on_id, type) VALUES ('agents-4', 'agents-2', 'blocks')`)
+
+	for i := 0; i < 3; i++ {
+		sessionID, err := StartSession(db, "agent", "/ws", "sonnet")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := RecordWork(db, sessionID, "agents-1", "agent", ""); err != nil {
+			t.Fatal(err)
+		}
+		if err := EndSession(db, sessionID, ExitError); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ranked, err := RankReadyIssues(db, nil, RankingOptions{Now: time.Date(2026, 3, 31, 9, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatalf("RankReadyIssues failed: %v", err)
+	}
+
+	var order []string
+	for _, r := range ranked {
+		order = append(order, r.Issue.ID)
+	}
+	// The blocker unblocks agents-4, and three failed attempts sink agents-1
+	// below a lower priority issue. agents-4 itself is not ready.
+	if got := strings.Join(order, ","); got != "agents-2,agents-3,agents-1" {
+		t.Fatalf("order = %s, want agents-2,agents-3,agents-1", got)
+	}
+	if !strings.HasPrefix(ranked[0].Rationale, "Ranked 1 of 3") || !strings.Contains(ranked[0].Rationale, "unblocks 1 issue") {
+		t.Errorf("rationale = %q", ranked[0].Rationale)
+	}
+	if !strings.Contains(ranked[2].Rationale, "3 prior attempts without completion") {
+		t.Errorf("rationale = %q", ranked[2].Rationale)
+	}
+}
+
+func TestRankReadyIssuesAgentFit(t *testing.T) {
+	db := openTestDB(t)
+	now := time.Date(2026, 3, 31, 9, 0, 0, 0, time.UTC)
+	ready := []*BeadsIssue{
+		{ID: "agents-1", Priority: 2, IssueType: "bug", CreatedAt: now},
+		{ID: "agents-2", Priority: 2, IssueType: "feature", CreatedAt: now},
+	}
+	mustExec(t, db, `INSERT INTO issues (id, title, issue_type) VALUES ('done-bug', 'x', 'bug'), ('done-feature', 'y', 'feature')`)
+	sessionID, err := StartSession(db, "fixer", "/ws", "sonnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	workID, err := RecordWork(db, sessionID, "done-bug", "fixer", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CompleteWork(db, workID, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RecordWork(db, sessionID, "done-feature", "fixer", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	ranked, err := RankReadyIssues(db, ready, RankingOptions{AgentName: "fixer", Now: now})
+	if err != nil {
+		t.Fatalf("RankReadyIssues failed: %v", err)
+	}
+	if ranked[0].Issue.ID != "agents-1" {
+		t.Errorf("best = %s, want the bug the agent has completed before", ranked[0].Issue.ID)
+	}
+	if !strings.Contains(ranked[0].Rationale, "fixer completed 1 of 1 bug issues") {
+		t.Errorf("rationale = %q", ranked[0].Rationale)
+	}
+}
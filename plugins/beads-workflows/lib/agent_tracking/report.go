@@ -0,0 +1,351 @@
+package agent_tracking
+
+import (
+	"database/sql"
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+)
+
+// ReportOptions controls what an activity report covers.
+type ReportOptions struct {
+	Title       string    // Report heading (default "Agent Activity Report")
+	Since       time.Time // Start of the reported window
+	Until       time.Time // End of the reported window, exclusive (zero means now)
+	MaxSessions int       // Sessions shown in the duration chart (default 50)
+	MaxIssues   int       // Issues shown in the issue breakdown (default 50)
+}
+
+// Report is the data behind an activity report.
+type Report struct {
+	Title       string            `json:"title"`
+	Since       time.Time         `json:"since"`
+	Until       time.Time         `json:"until,omitempty"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	Overall     *OverallStats     `json:"overall"`
+	Agents      []*AgentStats     `json:"agents"`
+	Skills      []*SkillStats     `json:"skills"`
+	Issues      []*IssueStats     `json:"issues"`
+	Sessions    []SessionDuration `json:"sessions"`
+}
+
+// BuildReport gathers overall totals, per-agent, per-skill and per-issue stats,
+// and session durations for sessions started in [opts.Since, opts.Until).
+// Skills are counted by when they were loaded. Issue time only counts work from
+// sessions in the window, with open work measured up to opts.Until.
+//
+// Example:
+//
+//	report, err := agent_tracking.BuildReport(db, agent_tracking.ReportOptions{
+//	    Since: time.Now().AddDate(0, 0, -14),
+//	    Until: time.Now().AddDate(0, 0, -7),
+//	})
+func BuildReport(db *sql.DB, opts ReportOptions) (*Report, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	if opts.Title == "" {
+		opts.Title = "Agent Activity Report"
+	}
+	if opts.MaxSessions <= 0 {
+		opts.MaxSessions = 50
+	}
+	if opts.MaxIssues <= 0 {
+		opts.MaxIssues = 50
+	}
+
+	report := &Report{
+		Title:       opts.Title,
+		Since:       opts.Since,
+		Until:       opts.Until,
+		GeneratedAt: time.Now().UTC(),
+	}
+	sinceStr, untilStr := formatTime(opts.Since), formatUntil(opts.Until)
+
+	var err error
+	report.Overall, err = getOverallStatsBetween(db, opts.Since, opts.Until)
+	if err != nil {
+		return nil, err
+	}
+
+	agents, err := queryNames(db, `
+		SELECT agent_name FROM agent_sessions
+		WHERE started_at >= ? AND started_at < ?
+		GROUP BY agent_name
+		ORDER BY COUNT(*) DESC, agent_name
+	`, sinceStr, untilStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agents: %w", err)
+	}
+	for _, name := range agents {
+		stats, err := getAgentStatsBetween(db, name, opts.Since, opts.Until)
+		if err != nil {
+			return nil, err
+		}
+		report.Agents = append(report.Agents, stats)
+	}
+
+	skills, err := queryNames(db, `
+		SELECT skill_name FROM agent_skill_usage
+		WHERE loaded_at >= ? AND loaded_at < ?
+		GROUP BY skill_name
+		ORDER BY COUNT(*) DESC, skill_name
+	`, sinceStr, untilStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list skills: %w", err)
+	}
+	for _, name := range skills {
+		stats, err := getSkillStatsBetween(db, name, opts.Since, opts.Until)
+		if err != nil {
+			return nil, err
+		}
+		report.Skills = append(report.Skills, stats)
+	}
+
+	issues, err := queryNames(db, `
+		SELECT w.issue_id FROM agent_issue_work w
+		JOIN agent_sessions s ON s.session_id = w.session_id
+		WHERE s.started_at >= ? AND s.started_at < ?
+		GROUP BY w.issue_id
+		ORDER BY MAX(w.started_at) DESC, w.issue_id
+		LIMIT ?
+	`, sinceStr, untilStr, opts.MaxIssues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list issues: %w", err)
+	}
+	for _, id := range issues {
+		stats, err := getIssueStatsBetween(db, id, opts.Since, opts.Until)
+		if err != nil {
+			return nil, err
+		}
+		report.Issues = append(report.Issues, stats)
+	}
+
+	report.Sessions, err = getSessionDurationsBetween(db, "", opts.Since, opts.Until, opts.MaxSessions)
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// WriteHTMLReport writes a report as a single HTML page with inline CSS and SVG
+// charts, so it can be opened or mailed without any other files.
+//
+// Example:
+//
+//	f, err := os.Create("agent-report.html")
+//	if err != nil {
+//	    return err
+//	}
+//	defer f.Close()
+//	err = agent_tracking.WriteHTMLReport(f, report)
+func WriteHTMLReport(w io.Writer, report *Report) error {
+	if report == nil {
+		return fmt.Errorf("report is required")
+	}
+
+	data := struct {
+		*Report
+		SessionChart svgBarChart
+		SkillChart   svgBarChart
+	}{Report: report}
+
+	var sessionBars []svgBar
+	for _, s := range report.Sessions {
+		class := "active"
+		if s.IsCompleted {
+			class = "done"
+		}
+		sessionBars = append(sessionBars, svgBar{
+			Label: s.StartedAt.Format("Jan 02 15:04") + " " + truncateText(s.AgentName, 28),
+			Value: s.Duration.Seconds(),
+			Text:  formatElapsed(s.Duration),
+			Class: class,
+		})
+	}
+	data.SessionChart = newSVGBarChart(sessionBars)
+
+	var skillBars []svgBar
+	for _, s := range report.Skills {
+		skillBars = append(skillBars, svgBar{
+			Label: truncateText(s.SkillName, 36),
+			Value: float64(s.TotalUses),
+			Text:  fmt.Sprintf("%d", s.TotalUses),
+			Class: "skill",
+		})
+	}
+	data.SkillChart = newSVGBarChart(skillBars)
+
+	if err := htmlReportTemplate.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to write HTML report: %w", err)
+	}
+	return nil
+}
+
+// svgBar is one bar of a horizontal bar chart.
+type svgBar struct {
+	Label string
+	Value float64
+	Text  string
+	Class string
+	Y     int
+	Width int
+}
+
+// svgBarChart is a horizontal bar chart laid out for the report template.
+type svgBarChart struct {
+	Bars   []svgBar
+	Height int
+}
+
+// Layout of the report's bar charts, in SVG user units.
+const (
+	svgChartWidth = 720
+	svgLabelWidth = 260
+	svgBarHeight  = 18
+	svgBarGap     = 6
+)
+
+// newSVGBarChart scales bars to the chart width and stacks them vertically.
+func newSVGBarChart(bars []svgBar) svgBarChart {
+	var max float64
+	for _, b := range bars {
+		if b.Value > max {
+			max = b.Value
+		}
+	}
+
+	plotWidth := svgChartWidth - svgLabelWidth - 70
+	for i := range bars {
+		bars[i].Y = i * (svgBarHeight + svgBarGap)
+		if max > 0 {
+			bars[i].Width = int(bars[i].Value / max * float64(plotWidth))
+		}
+		if bars[i].Width < 1 {
+			bars[i].Width = 1
+		}
+	}
+
+	return svgBarChart{Bars: bars, Height: len(bars) * (svgBarHeight + svgBarGap)}
+}
+
+// queryNames runs a query returning a single text column.
+func queryNames(db *sql.DB, query string, args ...interface{}) ([]string, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"elapsed": formatElapsed,
+	"date":    func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04 UTC") },
+	"percent": func(part, total int) string {
+		if total == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.0f%%", float64(part)/float64(total)*100)
+	},
+	"chartWidth": func() int { return svgChartWidth },
+	"labelWidth": func() int { return svgLabelWidth },
+	"barHeight":  func() int { return svgBarHeight },
+	"textY":      func(y int) int { return y + svgBarHeight - 5 },
+	"valueX":     func(width int) int { return svgLabelWidth + width + 6 },
+	"labelX":     func() int { return svgLabelWidth - 8 },
+	"round":      func(f float64) string { return fmt.Sprintf("%.0f", f) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #1f2328; margin: 2rem auto; max-width: 960px; padding: 0 1rem; }
+h1 { margin-bottom: 0.2rem; }
+h2 { border-bottom: 1px solid #d0d7de; padding-bottom: 0.3rem; margin-top: 2.5rem; }
+.meta { color: #656d76; margin-top: 0; }
+.totals { display: flex; flex-wrap: wrap; gap: 1rem; }
+.total { border: 1px solid #d0d7de; border-radius: 6px; padding: 0.8rem 1rem; min-width: 120px; }
+.total .value { font-size: 1.6rem; font-weight: 600; }
+.total .label { color: #656d76; font-size: 0.85rem; }
+table { border-collapse: collapse; width: 100%; font-size: 0.9rem; }
+th, td { text-align: left; padding: 0.4rem 0.6rem; border-bottom: 1px solid #eaeef2; }
+th { background: #f6f8fa; }
+td.num, th.num { text-align: right; font-variant-numeric: tabular-nums; }
+.none { color: #656d76; font-style: italic; }
+svg text { font-size: 12px; fill: #1f2328; }
+svg .done { fill: #2da44e; }
+svg .active { fill: #d4a72c; }
+svg .skill { fill: #0969da; }
+.legend span { display: inline-block; width: 10px; height: 10px; margin: 0 0.3rem 0 1rem; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">Sessions started since {{date .Since}}{{if not .Until.IsZero}}, before {{date .Until}}{{end}} &middot; generated {{date .GeneratedAt}}</p>
+
+<h2>Totals</h2>
+<div class="totals">
+  <div class="total"><div class="value">{{.Overall.TotalSessions}}</div><div class="label">sessions</div></div>
+  <div class="total"><div class="value">{{.Overall.ActiveSessions}}</div><div class="label">active</div></div>
+  <div class="total"><div class="value">{{.Overall.UniqueAgents}}</div><div class="label">agents</div></div>
+  <div class="total"><div class="value">{{.Overall.CompletedIssues}} / {{.Overall.TotalIssues}}</div><div class="label">issues completed</div></div>
+  <div class="total"><div class="value">{{.Overall.UniqueSkills}}</div><div class="label">skills used</div></div>
+  <div class="total"><div class="value">{{.Overall.TotalTokens}}</div><div class="label">tokens</div></div>
+</div>
+
+<h2>Agents</h2>
+{{if .Agents}}
+<table>
+<tr><th>Agent</th><th class="num">Sessions</th><th class="num">Active</th><th class="num">Issues</th><th class="num">Completed</th><th class="num">Completion</th><th class="num">Avg session</th><th class="num">Tokens</th><th>Most used skills</th></tr>
+{{range .Agents}}
+<tr><td>{{.AgentName}}</td><td class="num">{{.TotalSessions}}</td><td class="num">{{.ActiveSessions}}</td><td class="num">{{.TotalIssues}}</td><td class="num">{{.CompletedIssues}}</td><td class="num">{{percent .CompletedIssues .TotalIssues}}</td><td class="num">{{elapsed .AvgSessionTime}}</td><td class="num">{{.TotalTokens}}</td><td>{{range $i, $s := .MostUsedSkills}}{{if $i}}, {{end}}{{$s.SkillName}} ({{$s.Count}}){{end}}</td></tr>
+{{end}}
+</table>
+{{else}}<p class="none">No sessions in this window.</p>{{end}}
+
+<h2>Session durations</h2>
+{{if .SessionChart.Bars}}
+<p class="legend"><span style="background:#2da44e"></span>ended<span style="background:#d4a72c"></span>still active</p>
+<svg xmlns="http://www.w3.org/2000/svg" width="{{chartWidth}}" height="{{.SessionChart.Height}}" viewBox="0 0 {{chartWidth}} {{.SessionChart.Height}}" role="img" aria-label="Session durations">
+{{range .SessionChart.Bars}}<text x="{{labelX}}" y="{{textY .Y}}" text-anchor="end">{{.Label}}</text><rect class="{{.Class}}" x="{{labelWidth}}" y="{{.Y}}" width="{{.Width}}" height="{{barHeight}}" rx="2"></rect><text x="{{valueX .Width}}" y="{{textY .Y}}">{{.Text}}</text>
+{{end}}</svg>
+{{else}}<p class="none">No sessions in this window.</p>{{end}}
+
+<h2>Skills</h2>
+{{if .Skills}}
+<svg xmlns="http://www.w3.org/2000/svg" width="{{chartWidth}}" height="{{.SkillChart.Height}}" viewBox="0 0 {{chartWidth}} {{.SkillChart.Height}}" role="img" aria-label="Skill loads">
+{{range .SkillChart.Bars}}<text x="{{labelX}}" y="{{textY .Y}}" text-anchor="end">{{.Label}}</text><rect class="{{.Class}}" x="{{labelWidth}}" y="{{.Y}}" width="{{.Width}}" height="{{barHeight}}" rx="2"></rect><text x="{{valueX .Width}}" y="{{textY .Y}}">{{.Text}}</text>
+{{end}}</svg>
+<table>
+<tr><th>Skill</th><th class="num">Loads</th><th class="num">Sessions</th><th class="num">Agents</th><th class="num">Total context</th><th class="num">Avg context</th></tr>
+{{range .Skills}}
+<tr><td>{{.SkillName}}</td><td class="num">{{.TotalUses}}</td><td class="num">{{.UniqueSessions}}</td><td class="num">{{.UniqueAgents}}</td><td class="num">{{.TotalContext}}</td><td class="num">{{round .AvgContext}}</td></tr>
+{{end}}
+</table>
+{{else}}<p class="none">No skills loaded in this window.</p>{{end}}
+
+<h2>Issues</h2>
+{{if .Issues}}
+<table>
+<tr><th>Issue</th><th>Status</th><th class="num">Work sessions</th><th class="num">Agents</th><th class="num">Time</th><th>Breakdown</th></tr>
+{{range .Issues}}
+<tr><td>{{.IssueID}}</td><td>{{if .IsCompleted}}completed{{else}}open{{end}}</td><td class="num">{{.TotalWorkSessions}}</td><td class="num">{{.TotalAgents}}</td><td class="num">{{elapsed .TotalTime}}</td><td>{{range $i, $a := .AgentBreakdown}}{{if $i}}; {{end}}{{$a.AgentName}}: {{$a.WorkSessions}} &times; {{elapsed $a.TotalTime}}{{if $a.Completed}}, completed{{end}}{{end}}</td></tr>
+{{end}}
+</table>
+{{else}}<p class="none">No issues worked on in this window.</p>{{end}}
+</body>
+</html>
+`))
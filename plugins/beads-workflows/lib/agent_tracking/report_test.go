@@ -0,0 +1,147 @@
+package agent_tracking
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildReportBoundsEverySection(t *testing.T) {
+	db := openTestDB(t)
+	weekStart := time.Date(2026, 9, 14, 0, 0, 0, 0, time.UTC)
+	weekEnd := weekStart.AddDate(0, 0, 7)
+
+	// seed starts a session at start with one piece of work on agents-1 and a
+	// skill load, backdating every row so the report window decides what counts.
+	seed := func(agent string, start time.Time, workFor time.Duration, open bool) {
+		t.Helper()
+		sessionID, err := StartSession(db, agent, "/ws", "opus")
+		if err != nil {
+			t.Fatal(err)
+		}
+		workID, err := RecordWork(db, sessionID, "agents-1", agent, "next")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := RecordSkillUsage(db, sessionID, "dependency-thinking", "agents-1", 300); err != nil {
+			t.Fatal(err)
+		}
+		mustExec(t, db, `UPDATE agent_sessions SET started_at = ? WHERE session_id = ?`, formatTime(start), sessionID)
+		mustExec(t, db, `UPDATE agent_skill_usage SET loaded_at = ? WHERE session_id = ?`, formatTime(start), sessionID)
+		mustExec(t, db, `UPDATE agent_issue_work SET started_at = ? WHERE work_id = ?`, formatTime(start), workID)
+		if open {
+			return
+		}
+		if err := CompleteWork(db, workID, "done"); err != nil {
+			t.Fatal(err)
+		}
+		if err := EndSession(db, sessionID, ExitCompleted); err != nil {
+			t.Fatal(err)
+		}
+		end := formatTime(start.Add(workFor))
+		mustExec(t, db, `UPDATE agent_issue_work SET ended_at = ? WHERE work_id = ?`, end, workID)
+		mustExec(t, db, `UPDATE agent_sessions SET ended_at = ? WHERE session_id = ?`, end, sessionID)
+	}
+	seed("before", weekStart.AddDate(0, 0, -3), 5*time.Hour, false)
+	seed("inside", weekStart.AddDate(0, 0, 2), 2*time.Hour, false)
+	seed("straddling", weekEnd.Add(-time.Hour), 0, true)
+	seed("after", weekEnd.Add(time.Hour), time.Hour, false)
+
+	r, err := BuildReport(db, ReportOptions{Since: weekStart, Until: weekEnd})
+	if err != nil {
+		t.Fatalf("BuildReport failed: %v", err)
+	}
+
+	if r.Overall.TotalSessions != 2 || r.Overall.UniqueAgents != 2 || r.Overall.TotalIssues != 1 {
+		t.Errorf("overall = %+v, want the two sessions started in the window", r.Overall)
+	}
+	var agents []string
+	for _, a := range r.Agents {
+		agents = append(agents, a.AgentName)
+	}
+	if got := strings.Join(agents, ","); got != "inside,straddling" {
+		t.Errorf("agents = %s", got)
+	}
+	if len(r.Skills) != 1 || r.Skills[0].TotalUses != 2 {
+		t.Errorf("skills = %+v, want 2 loads inside the window", r.Skills)
+	}
+	if len(r.Sessions) != 2 {
+		t.Fatalf("sessions = %+v", r.Sessions)
+	}
+	for _, s := range r.Sessions {
+		if s.AgentName == "straddling" && s.Duration.Round(time.Second) != time.Hour {
+			t.Errorf("open session duration = %v, want it clipped to Until", s.Duration)
+		}
+	}
+
+	if len(r.Issues) != 1 {
+		t.Fatalf("issues = %+v", r.Issues)
+	}
+	issue := r.Issues[0]
+	if issue.TotalWorkSessions != 2 || issue.TotalAgents != 2 || !issue.IsCompleted {
+		t.Errorf("issue = %+v, want only the window's two work entries", issue)
+	}
+	if issue.TotalTime.Round(time.Second) != 3*time.Hour {
+		t.Errorf("issue time = %v, want 2h of finished work plus 1h of open work up to Until", issue.TotalTime)
+	}
+
+	var b bytes.Buffer
+	if err := WriteHTMLReport(&b, r); err != nil {
+		t.Fatalf("WriteHTMLReport failed: %v", err)
+	}
+	if !strings.Contains(b.String(), "since 2026-09-14 00:00 UTC, before 2026-09-21 00:00 UTC") {
+		t.Error("report heading doesn't show the window")
+	}
+}
+
+func TestBuildReportWithoutUntil(t *testing.T) {
+	db := openTestDB(t)
+	sessionID, err := StartSession(db, "orchestrator", "/ws", "opus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RecordWork(db, sessionID, "agents-2", "orchestrator", "next"); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := BuildReport(db, ReportOptions{Since: time.Now().Add(-time.Hour)})
+	if err != nil {
+		t.Fatalf("BuildReport failed: %v", err)
+	}
+	if r.Overall.TotalSessions != 1 || len(r.Issues) != 1 || r.Issues[0].TotalWorkSessions != 1 {
+		t.Errorf("report = %+v, want the open session", r.Overall)
+	}
+
+	var b bytes.Buffer
+	if err := WriteHTMLReport(&b, r); err != nil {
+		t.Fatalf("WriteHTMLReport failed: %v", err)
+	}
+	if strings.Contains(b.String(), "before") {
+		t.Error("report heading shows an end of window that wasn't set")
+	}
+}
+
+func TestGetIssueStatsKeepsWorkWithoutSession(t *testing.T) {
+	db := openTestDB(t)
+	// RecordWork doesn't require the session row, so work can outlive it.
+	if _, err := RecordWork(db, "deleted-session", "agents-3", "orchestrator", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := GetIssueStats(db, "agents-3")
+	if err != nil {
+		t.Fatalf("GetIssueStats failed: %v", err)
+	}
+	if stats.TotalWorkSessions != 1 || stats.TotalAgents != 1 || len(stats.AgentBreakdown) != 1 {
+		t.Errorf("stats = %+v, want the work counted", stats)
+	}
+
+	bounded, err := getIssueStatsBetween(db, "agents-3", time.Now().Add(-time.Hour), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bounded.TotalWorkSessions != 0 {
+		t.Errorf("bounded stats = %+v, want work without a session start left out", bounded)
+	}
+}
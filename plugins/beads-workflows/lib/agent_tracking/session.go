@@ -16,7 +16,7 @@ import (
 //	if err != nil {
 //	    return fmt.Errorf("failed to start session: %w", err)
 //	}
-//	defer agent_tracking.EndSession(db, sessionID, "completed")
+//	defer agent_tracking.EndSession(db, sessionID, agent_tracking.ExitCompleted)
 func StartSession(db *sql.DB, agentName, workspacePath, modelTier string) (string, error) {
 	if db == nil {
 		return "", fmt.Errorf("database connection is nil")
@@ -39,26 +39,52 @@ func StartSession(db *sql.DB, agentName, workspacePath, modelTier string) (strin
 		return "", fmt.Errorf("failed to create session: %w", err)
 	}
 
+	publishEvent(db, Event{
+		Type:          EventSessionStarted,
+		SessionID:     sessionID,
+		AgentName:     agentName,
+		WorkspacePath: workspacePath,
+		ModelTier:     modelTier,
+	})
+
 	return sessionID, nil
 }
 
-// EndSession marks a session as ended with the given exit reason.
-// Common exit reasons: "completed", "interrupted", "error", "timeout"
+// EndSession marks a session as ended with the given exit reason, which must be
+// one of the ExitReason constants. Any failure recorded by an earlier
+// EndSessionWithFailure is removed. Use EndSessionWithFailure to record what
+// went wrong.
 //
 // Example:
 //
-//	err := agent_tracking.EndSession(db, sessionID, "completed")
-func EndSession(db *sql.DB, sessionID, exitReason string) error {
+//	err := agent_tracking.EndSession(db, sessionID, agent_tracking.ExitCompleted)
+func EndSession(db *sql.DB, sessionID string, exitReason ExitReason) error {
 	if db == nil {
 		return fmt.Errorf("database connection is nil")
 	}
 	if sessionID == "" {
 		return fmt.Errorf("session ID is required")
 	}
+	if !exitReason.Valid() {
+		return fmt.Errorf("invalid exit reason: %q", exitReason)
+	}
 
+	return endSession(db, sessionID, exitReason, nil)
+}
+
+// endSession ends a session and replaces any failure recorded for it: with
+// details when given, otherwise with none. Both writes happen in one
+// transaction, so a session is never left ended with a stale or missing failure.
+func endSession(db *sql.DB, sessionID string, exitReason ExitReason, details *FailureDetails) error {
 	endedAt := formatTime(time.Now())
 
-	result, err := db.Exec(`
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
 		UPDATE agent_sessions
 		SET ended_at = ?, exit_reason = ?
 		WHERE session_id = ?
@@ -75,6 +101,32 @@ func EndSession(db *sql.DB, sessionID, exitReason string) error {
 		return fmt.Errorf("session not found: %s", sessionID)
 	}
 
+	if details != nil {
+		_, err = tx.Exec(`
+			INSERT INTO agent_session_failures (session_id, error_class, error_message, failed_phase, created_at)
+			VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(session_id) DO UPDATE SET
+				error_class = excluded.error_class,
+				error_message = excluded.error_message,
+				failed_phase = excluded.failed_phase,
+				created_at = excluded.created_at
+		`, sessionID, details.Class, details.Message, details.Phase, endedAt)
+		if err != nil {
+			return fmt.Errorf("failed to record session failure: %w", err)
+		}
+	} else {
+		_, err = tx.Exec(`DELETE FROM agent_session_failures WHERE session_id = ?`, sessionID)
+		if err != nil {
+			return fmt.Errorf("failed to clear session failure: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit session end: %w", err)
+	}
+
+	publishSessionEvent(db, Event{Type: EventSessionEnded, SessionID: sessionID, ExitReason: exitReason})
+
 	return nil
 }
 
@@ -135,7 +187,7 @@ func GetSession(db *sql.DB, sessionID string) (*Session, error) {
 
 	// Parse optional strings
 	if exitReason.Valid {
-		session.ExitReason = exitReason.String
+		session.ExitReason = ExitReason(exitReason.String)
 	}
 	if modelTier.Valid {
 		session.ModelTier = modelTier.String
@@ -187,6 +239,8 @@ func UpdateSessionIssues(db *sql.DB, sessionID string, issues []string) error {
 		return fmt.Errorf("session not found: %s", sessionID)
 	}
 
+	publishSessionEvent(db, Event{Type: EventSessionUpdated, SessionID: sessionID, Issues: issues})
+
 	return nil
 }
 
@@ -225,10 +279,15 @@ func UpdateSessionSkills(db *sql.DB, sessionID string, skills []string) error {
 		return fmt.Errorf("session not found: %s", sessionID)
 	}
 
+	publishSessionEvent(db, Event{Type: EventSessionUpdated, SessionID: sessionID, Skills: skills})
+
 	return nil
 }
 
 // UpdateSessionTokens updates the context token count for a session.
+// If the new count takes a budget that applies to the session over its limit, the
+// count is still saved and a budget_exceeded event is published; for a hard budget a
+// *BudgetExceededError is also returned.
 //
 // Example:
 //
@@ -241,6 +300,11 @@ func UpdateSessionTokens(db *sql.DB, sessionID string, tokens int) error {
 		return fmt.Errorf("session ID is required")
 	}
 
+	exceededBefore, err := getExceededBudgetIDs(db, sessionID)
+	if err != nil {
+		return err
+	}
+
 	result, err := db.Exec(`
 		UPDATE agent_sessions
 		SET context_tokens = ?
@@ -258,7 +322,9 @@ func UpdateSessionTokens(db *sql.DB, sessionID string, tokens int) error {
 		return fmt.Errorf("session not found: %s", sessionID)
 	}
 
-	return nil
+	publishSessionEvent(db, Event{Type: EventTokensUpdated, SessionID: sessionID, Tokens: tokens})
+
+	return checkBudgetsAfterWrite(db, sessionID, exceededBefore)
 }
 
 // ListActiveSessions returns all sessions that haven't been ended.
@@ -358,7 +424,7 @@ func scanSessions(rows *sql.Rows) ([]*Session, error) {
 
 		// Parse optional strings
 		if exitReason.Valid {
-			session.ExitReason = exitReason.String
+			session.ExitReason = ExitReason(exitReason.String)
 		}
 		if modelTier.Valid {
 			session.ModelTier = modelTier.String
@@ -383,7 +449,8 @@ func scanSessions(rows *sql.Rows) ([]*Session, error) {
 }
 
 // RecordWork creates a new work entry for an issue within a session.
-// Returns the work ID.
+// Returns the work ID. If a hard budget for the session's agent, workspace or
+// issue is already exceeded, no work is recorded and a *BudgetExceededError is returned.
 //
 // Example:
 //
@@ -402,6 +469,10 @@ func RecordWork(db *sql.DB, sessionID, issueID, agentName, rationale string) (st
 		return "", fmt.Errorf("agent name is required")
 	}
 
+	if err := checkWorkBudgets(db, sessionID, issueID); err != nil {
+		return "", err
+	}
+
 	workID := generateID()
 	startedAt := formatTime(time.Now())
 
@@ -413,6 +484,14 @@ func RecordWork(db *sql.DB, sessionID, issueID, agentName, rationale string) (st
 		return "", fmt.Errorf("failed to record work: %w", err)
 	}
 
+	publishSessionEvent(db, Event{
+		Type:      EventWorkStarted,
+		SessionID: sessionID,
+		AgentName: agentName,
+		WorkID:    workID,
+		IssueID:   issueID,
+	})
+
 	return workID, nil
 }
 
@@ -448,10 +527,15 @@ func CompleteWork(db *sql.DB, workID string, notes string) error {
 		return fmt.Errorf("work not found: %s", workID)
 	}
 
+	publishSessionEvent(db, Event{Type: EventWorkCompleted, WorkID: workID, Notes: notes})
+
 	return nil
 }
 
 // RecordSkillUsage logs the usage of a skill during a session.
+// If the added context takes the session over a budget, the usage is still
+// recorded and a budget_exceeded event is published; for a hard budget a
+// *BudgetExceededError is also returned.
 //
 // Example:
 //
@@ -467,10 +551,15 @@ func RecordSkillUsage(db *sql.DB, sessionID, skillName, issueID string, contextA
 		return fmt.Errorf("skill name is required")
 	}
 
+	exceededBefore, err := getExceededBudgetIDs(db, sessionID)
+	if err != nil {
+		return err
+	}
+
 	usageID := generateID()
 	loadedAt := formatTime(time.Now())
 
-	_, err := db.Exec(`
+	_, err = db.Exec(`
 		INSERT INTO agent_skill_usage (usage_id, session_id, skill_name, loaded_at, used_for_issue_id, context_added)
 		VALUES (?, ?, ?, ?, ?, ?)
 	`, usageID, sessionID, skillName, loadedAt, issueID, contextAdded)
@@ -478,7 +567,15 @@ func RecordSkillUsage(db *sql.DB, sessionID, skillName, issueID string, contextA
 		return fmt.Errorf("failed to record skill usage: %w", err)
 	}
 
-	return nil
+	publishSessionEvent(db, Event{
+		Type:         EventSkillUsed,
+		SessionID:    sessionID,
+		SkillName:    skillName,
+		IssueID:      issueID,
+		ContextAdded: contextAdded,
+	})
+
+	return checkBudgetsAfterWrite(db, sessionID, exceededBefore)
 }
 
 // GetWork retrieves a work entry by its ID.
@@ -637,3 +734,170 @@ func scanWorkEntries(rows *sql.Rows) ([]*Work, error) {
 
 	return workEntries, nil
 }
+
+// ListCompletedWork returns the most recently completed work entries, newest first.
+//
+// Example:
+//
+//	recent, err := agent_tracking.ListCompletedWork(db, 10)
+func ListCompletedWork(db *sql.DB, limit int) ([]*Work, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	rows, err := db.Query(`
+		SELECT work_id, issue_id, session_id, agent_name, started_at, ended_at,
+		       status_changes, decision_rationale, work_notes, completed
+		FROM agent_issue_work
+		WHERE completed = 1
+		ORDER BY ended_at DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list completed work: %w", err)
+	}
+	defer rows.Close()
+
+	return scanWorkEntries(rows)
+}
+
+// ListSkillUsageBySession returns the skills loaded during a session, in load order.
+func ListSkillUsageBySession(db *sql.DB, sessionID string) ([]*SkillUsage, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	if sessionID == "" {
+		return nil, fmt.Errorf("session ID is required")
+	}
+
+	rows, err := db.Query(`
+		SELECT usage_id, session_id, skill_name, loaded_at, used_for_issue_id, context_added
+		FROM agent_skill_usage
+		WHERE session_id = ?
+		ORDER BY loaded_at ASC
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list skill usage by session: %w", err)
+	}
+	defer rows.Close()
+
+	var usages []*SkillUsage
+	for rows.Next() {
+		var usage SkillUsage
+		var loadedAtStr string
+		var issueID sql.NullString
+
+		err := rows.Scan(&usage.UsageID, &usage.SessionID, &usage.SkillName, &loadedAtStr, &issueID, &usage.ContextAdded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan skill usage: %w", err)
+		}
+
+		usage.LoadedAt, err = parseTime(loadedAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse loaded_at: %w", err)
+		}
+		if issueID.Valid {
+			usage.UsedForIssueID = issueID.String
+		}
+
+		usages = append(usages, &usage)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating skill usage: %w", err)
+	}
+
+	return usages, nil
+}
+
+// getWorkIssueForSession returns the issue of a work entry, checking that the
+// work belongs to the session.
+func getWorkIssueForSession(db *sql.DB, sessionID, workID string) (string, error) {
+	var workSessionID, issueID string
+	err := db.QueryRow(`
+		SELECT session_id, issue_id FROM agent_issue_work WHERE work_id = ?
+	`, workID).Scan(&workSessionID, &issueID)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("work not found: %s", workID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get work: %w", err)
+	}
+	if workSessionID != sessionID {
+		return "", fmt.Errorf("work %s belongs to session %s, not %s", workID, workSessionID, sessionID)
+	}
+	return issueID, nil
+}
+
+// SetParentSession records that a session is a subagent session started by
+// another session. Setting a new parent replaces the previous one.
+//
+// Example:
+//
+//	subID, err := agent_tracking.StartSession(db, "code-reviewer", "/myStuff/project", "haiku")
+//	err = agent_tracking.SetParentSession(db, subID, sessionID)
+func SetParentSession(db *sql.DB, sessionID, parentSessionID string) error {
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+	if sessionID == "" {
+		return fmt.Errorf("session ID is required")
+	}
+	if parentSessionID == "" {
+		return fmt.Errorf("parent session ID is required")
+	}
+	if sessionID == parentSessionID {
+		return fmt.Errorf("session cannot be its own parent: %s", sessionID)
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO agent_session_parents (session_id, parent_session_id)
+		VALUES (?, ?)
+		ON CONFLICT(session_id) DO UPDATE SET parent_session_id = excluded.parent_session_id
+	`, sessionID, parentSessionID)
+	if err != nil {
+		return fmt.Errorf("failed to set parent session: %w", err)
+	}
+
+	return nil
+}
+
+// ListSubagentSessions returns the IDs of the sessions started by a session.
+func ListSubagentSessions(db *sql.DB, parentSessionID string) ([]string, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	if parentSessionID == "" {
+		return nil, fmt.Errorf("parent session ID is required")
+	}
+
+	rows, err := db.Query(`
+		SELECT p.session_id
+		FROM agent_session_parents p
+		JOIN agent_sessions s ON s.session_id = p.session_id
+		WHERE p.parent_session_id = ?
+		ORDER BY s.started_at, p.session_id
+	`, parentSessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subagent sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessionIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan subagent session: %w", err)
+		}
+		sessionIDs = append(sessionIDs, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating subagent sessions: %w", err)
+	}
+
+	return sessionIDs, nil
+}
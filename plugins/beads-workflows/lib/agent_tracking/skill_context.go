@@ -0,0 +1,377 @@
+package agent_tracking
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DuplicateSkillLoad is a skill that was loaded more than once in the same session.
+// WastedContext is the context added by every load after the first.
+type DuplicateSkillLoad struct {
+	SessionID     string    `json:"session_id"`
+	AgentName     string    `json:"agent_name"`
+	SkillName     string    `json:"skill_name"`
+	Loads         int       `json:"loads"`
+	WastedContext int       `json:"wasted_context"`
+	FirstLoadedAt time.Time `json:"first_loaded_at"`
+	LastLoadedAt  time.Time `json:"last_loaded_at"`
+}
+
+// UntiedSkillLoad is a skill loaded in a session without ever being tied to an issue.
+type UntiedSkillLoad struct {
+	SessionID    string `json:"session_id"`
+	AgentName    string `json:"agent_name"`
+	SkillName    string `json:"skill_name"`
+	Loads        int    `json:"loads"`
+	ContextAdded int    `json:"context_added"`
+}
+
+// SessionSkillContext is the share of a session's context tokens consumed by skills.
+type SessionSkillContext struct {
+	SessionID     string    `json:"session_id"`
+	AgentName     string    `json:"agent_name"`
+	StartedAt     time.Time `json:"started_at"`
+	ContextTokens int       `json:"context_tokens"`
+	SkillContext  int       `json:"skill_context"`
+	SkillLoads    int       `json:"skill_loads"`
+	SkillShare    float64   `json:"skill_share"`
+}
+
+// AgentContextBudget summarizes how an agent's context tokens are spent on skills.
+type AgentContextBudget struct {
+	AgentName                 string         `json:"agent_name"`
+	Sessions                  int            `json:"sessions"`
+	ContextTokens             int            `json:"context_tokens"`
+	SkillContext              int            `json:"skill_context"`
+	SkillShare                float64        `json:"skill_share"`
+	DuplicateContext          int            `json:"duplicate_context"`
+	UntiedContext             int            `json:"untied_context"`
+	AvgSkillContextPerSession float64        `json:"avg_skill_context_per_session"`
+	TopSkills                 []SkillContext `json:"top_skills"`
+	Since                     time.Time      `json:"since"`
+}
+
+// SkillContext is a skill and the context it added.
+type SkillContext struct {
+	SkillName    string `json:"skill_name"`
+	Loads        int    `json:"loads"`
+	ContextAdded int    `json:"context_added"`
+}
+
+// FindDuplicateSkillLoads returns skills loaded more than once within a session,
+// for sessions started since the given time, most wasted context first.
+//
+// Example:
+//
+//	dups, err := agent_tracking.FindDuplicateSkillLoads(db, time.Now().AddDate(0, 0, -7))
+//	for _, d := range dups {
+//	    fmt.Printf("%s loaded %s %d times (%d tokens wasted)\n", d.AgentName, d.SkillName, d.Loads, d.WastedContext)
+//	}
+func FindDuplicateSkillLoads(db *sql.DB, since time.Time) ([]DuplicateSkillLoad, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	rows, err := db.Query(`
+		SELECT
+			u.session_id,
+			s.agent_name,
+			u.skill_name,
+			COUNT(*) as loads,
+			COALESCE(SUM(u.context_added), 0) - (
+				SELECT COALESCE(earliest.context_added, 0)
+				FROM agent_skill_usage earliest
+				WHERE earliest.session_id = u.session_id AND earliest.skill_name = u.skill_name
+				ORDER BY earliest.loaded_at ASC, earliest.rowid ASC
+				LIMIT 1
+			) as wasted,
+			MIN(u.loaded_at),
+			MAX(u.loaded_at)
+		FROM agent_skill_usage u
+		JOIN agent_sessions s ON u.session_id = s.session_id
+		WHERE s.started_at >= ?
+		GROUP BY u.session_id, u.skill_name
+		HAVING COUNT(*) > 1
+		ORDER BY wasted DESC, loads DESC
+	`, formatTime(since))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find duplicate skill loads: %w", err)
+	}
+	defer rows.Close()
+
+	var dups []DuplicateSkillLoad
+	for rows.Next() {
+		var d DuplicateSkillLoad
+		var firstStr, lastStr string
+		if err := rows.Scan(&d.SessionID, &d.AgentName, &d.SkillName, &d.Loads, &d.WastedContext, &firstStr, &lastStr); err != nil {
+			return nil, fmt.Errorf("failed to scan duplicate skill load: %w", err)
+		}
+		d.FirstLoadedAt, err = parseTime(firstStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse loaded_at: %w", err)
+		}
+		d.LastLoadedAt, err = parseTime(lastStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse loaded_at: %w", err)
+		}
+		dups = append(dups, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating duplicate skill loads: %w", err)
+	}
+
+	return dups, nil
+}
+
+// FindUntiedSkillLoads returns skills that were loaded in a session but never tied
+// to an issue with used_for_issue_id, for sessions started since the given time.
+//
+// Example:
+//
+//	untied, err := agent_tracking.FindUntiedSkillLoads(db, time.Now().AddDate(0, 0, -7))
+func FindUntiedSkillLoads(db *sql.DB, since time.Time) ([]UntiedSkillLoad, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	rows, err := db.Query(`
+		SELECT u.session_id, s.agent_name, u.skill_name, COUNT(*), COALESCE(SUM(u.context_added), 0)
+		FROM agent_skill_usage u
+		JOIN agent_sessions s ON u.session_id = s.session_id
+		WHERE s.started_at >= ?
+		GROUP BY u.session_id, u.skill_name
+		HAVING COUNT(CASE WHEN u.used_for_issue_id IS NOT NULL AND u.used_for_issue_id != '' THEN 1 END) = 0
+		ORDER BY SUM(u.context_added) DESC
+	`, formatTime(since))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find untied skill loads: %w", err)
+	}
+	defer rows.Close()
+
+	var untied []UntiedSkillLoad
+	for rows.Next() {
+		var u UntiedSkillLoad
+		if err := rows.Scan(&u.SessionID, &u.AgentName, &u.SkillName, &u.Loads, &u.ContextAdded); err != nil {
+			return nil, fmt.Errorf("failed to scan untied skill load: %w", err)
+		}
+		untied = append(untied, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating untied skill loads: %w", err)
+	}
+
+	return untied, nil
+}
+
+// GetSessionSkillContext returns, for each session started since the given time,
+// how many of its context tokens were added by skill loads. Sessions are ordered
+// by skill share, highest first.
+//
+// Example:
+//
+//	sessions, err := agent_tracking.GetSessionSkillContext(db, time.Now().AddDate(0, 0, -7))
+//	for _, s := range sessions {
+//	    fmt.Printf("%s: %.0f%% of %d tokens from skills\n", s.SessionID, s.SkillShare*100, s.ContextTokens)
+//	}
+func GetSessionSkillContext(db *sql.DB, since time.Time) ([]SessionSkillContext, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	rows, err := db.Query(`
+		SELECT
+			s.session_id,
+			s.agent_name,
+			s.started_at,
+			s.context_tokens,
+			COALESCE(SUM(u.context_added), 0) as skill_context,
+			COUNT(u.usage_id) as skill_loads
+		FROM agent_sessions s
+		LEFT JOIN agent_skill_usage u ON u.session_id = s.session_id
+		WHERE s.started_at >= ?
+		GROUP BY s.session_id
+		ORDER BY s.started_at DESC
+	`, formatTime(since))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session skill context: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []SessionSkillContext
+	for rows.Next() {
+		var sc SessionSkillContext
+		var startedAtStr string
+		if err := rows.Scan(&sc.SessionID, &sc.AgentName, &startedAtStr, &sc.ContextTokens, &sc.SkillContext, &sc.SkillLoads); err != nil {
+			return nil, fmt.Errorf("failed to scan session skill context: %w", err)
+		}
+		sc.StartedAt, err = parseTime(startedAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse started_at: %w", err)
+		}
+		sc.SkillShare = skillShare(sc.SkillContext, sc.ContextTokens)
+		sessions = append(sessions, sc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating session skill context: %w", err)
+	}
+
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].SkillShare > sessions[j].SkillShare
+	})
+
+	return sessions, nil
+}
+
+// GetAgentContextBudget reports how an agent's context tokens were spent on skills
+// in sessions started since the given time, including context lost to duplicate
+// loads and to skills never tied to an issue.
+//
+// Example:
+//
+//	budget, err := agent_tracking.GetAgentContextBudget(db, "beads-workflow-orchestrator", time.Now().AddDate(0, -1, 0))
+//	fmt.Printf("Skills: %.0f%% of context, %d tokens in duplicate loads\n",
+//	    budget.SkillShare*100, budget.DuplicateContext)
+func GetAgentContextBudget(db *sql.DB, agentName string, since time.Time) (*AgentContextBudget, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	if agentName == "" {
+		return nil, fmt.Errorf("agent name is required")
+	}
+
+	budget := &AgentContextBudget{
+		AgentName: agentName,
+		Since:     since,
+	}
+
+	sinceStr := formatTime(since)
+
+	// Get session and token totals
+	err := db.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(context_tokens), 0)
+		FROM agent_sessions
+		WHERE agent_name = ? AND started_at >= ?
+	`, agentName, sinceStr).Scan(&budget.Sessions, &budget.ContextTokens)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session totals: %w", err)
+	}
+
+	// Get context added by skills, per skill
+	rows, err := db.Query(`
+		SELECT u.skill_name, COUNT(*), COALESCE(SUM(u.context_added), 0) as total
+		FROM agent_skill_usage u
+		JOIN agent_sessions s ON u.session_id = s.session_id
+		WHERE s.agent_name = ? AND s.started_at >= ?
+		GROUP BY u.skill_name
+		ORDER BY total DESC
+	`, agentName, sinceStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get skill context: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sc SkillContext
+		if err := rows.Scan(&sc.SkillName, &sc.Loads, &sc.ContextAdded); err != nil {
+			return nil, fmt.Errorf("failed to scan skill context: %w", err)
+		}
+		budget.SkillContext += sc.ContextAdded
+		if len(budget.TopSkills) < 5 {
+			budget.TopSkills = append(budget.TopSkills, sc)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating skill context: %w", err)
+	}
+
+	budget.SkillShare = skillShare(budget.SkillContext, budget.ContextTokens)
+	if budget.Sessions > 0 {
+		budget.AvgSkillContextPerSession = float64(budget.SkillContext) / float64(budget.Sessions)
+	}
+
+	dups, err := FindDuplicateSkillLoads(db, since)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range dups {
+		if d.AgentName == agentName {
+			budget.DuplicateContext += d.WastedContext
+		}
+	}
+
+	untied, err := FindUntiedSkillLoads(db, since)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range untied {
+		if u.AgentName == agentName {
+			budget.UntiedContext += u.ContextAdded
+		}
+	}
+
+	return budget, nil
+}
+
+// ListAgentContextBudgets returns the context budget report for every agent with
+// sessions started since the given time, highest skill share first.
+//
+// Example:
+//
+//	budgets, err := agent_tracking.ListAgentContextBudgets(db, time.Now().AddDate(0, -1, 0))
+func ListAgentContextBudgets(db *sql.DB, since time.Time) ([]*AgentContextBudget, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	rows, err := db.Query(`
+		SELECT DISTINCT agent_name
+		FROM agent_sessions
+		WHERE started_at >= ?
+		ORDER BY agent_name
+	`, formatTime(since))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agents: %w", err)
+	}
+
+	var agents []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan agent name: %w", err)
+		}
+		agents = append(agents, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating agents: %w", err)
+	}
+
+	var budgets []*AgentContextBudget
+	for _, name := range agents {
+		budget, err := GetAgentContextBudget(db, name, since)
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, budget)
+	}
+
+	sort.SliceStable(budgets, func(i, j int) bool {
+		return budgets[i].SkillShare > budgets[j].SkillShare
+	})
+
+	return budgets, nil
+}
+
+// skillShare returns skill context as a fraction of the session context tokens.
+func skillShare(skillContext, contextTokens int) float64 {
+	if contextTokens <= 0 {
+		return 0
+	}
+	return float64(skillContext) / float64(contextTokens)
+}
@@ -0,0 +1,91 @@
+package agent_tracking
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSkillContextReports(t *testing.T) {
+	db := openTestDB(t)
+	sessionID, err := StartSession(db, "agent", "/ws", "sonnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateSessionTokens(db, sessionID, 10000); err != nil {
+		t.Fatal(err)
+	}
+	for _, load := range []struct {
+		skill, issue string
+		context      int
+	}{
+		{"golang-patterns", "agents-1", 500},
+		{"golang-patterns", "", 400},
+		{"docker", "", 300},
+	} {
+		if err := RecordSkillUsage(db, sessionID, load.skill, load.issue, load.context); err != nil {
+			t.Fatal(err)
+		}
+	}
+	idle, err := StartSession(db, "agent", "/ws", "sonnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dups, err := FindDuplicateSkillLoads(db, time.Time{})
+	if err != nil {
+		t.Fatalf("FindDuplicateSkillLoads failed: %v", err)
+	}
+	if len(dups) != 1 || dups[0].SkillName != "golang-patterns" || dups[0].Loads != 2 || dups[0].WastedContext != 400 {
+		t.Errorf("duplicates = %+v, want golang-patterns loaded twice wasting 400", dups)
+	}
+
+	untied, err := FindUntiedSkillLoads(db, time.Time{})
+	if err != nil {
+		t.Fatalf("FindUntiedSkillLoads failed: %v", err)
+	}
+	if len(untied) != 1 || untied[0].SkillName != "docker" || untied[0].ContextAdded != 300 {
+		t.Errorf("untied = %+v, want docker with 300", untied)
+	}
+
+	sessions, err := GetSessionSkillContext(db, time.Time{})
+	if err != nil {
+		t.Fatalf("GetSessionSkillContext failed: %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].SessionID != sessionID || sessions[0].SkillContext != 1200 || sessions[0].SkillShare != 0.12 {
+		t.Errorf("sessions = %+v, want the busy session first with 12%% from skills", sessions)
+	}
+	if sessions[1].SessionID != idle || sessions[1].SkillLoads != 0 {
+		t.Errorf("idle session = %+v", sessions[1])
+	}
+
+	budget, err := GetAgentContextBudget(db, "agent", time.Time{})
+	if err != nil {
+		t.Fatalf("GetAgentContextBudget failed: %v", err)
+	}
+	if budget.Sessions != 2 || budget.SkillContext != 1200 || budget.DuplicateContext != 400 || budget.UntiedContext != 300 {
+		t.Errorf("budget = %+v", budget)
+	}
+	if len(budget.TopSkills) != 2 || budget.TopSkills[0].SkillName != "golang-patterns" || budget.AvgSkillContextPerSession != 600 {
+		t.Errorf("top skills = %+v, avg %.0f", budget.TopSkills, budget.AvgSkillContextPerSession)
+	}
+}
+
+func TestFindDuplicateSkillLoadsWithoutContextSize(t *testing.T) {
+	db := openTestDB(t)
+	sessionID, err := StartSession(db, "agent", "/ws", "sonnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, usageID := range []string{"u1", "u2"} {
+		mustExec(t, db, `INSERT INTO agent_skill_usage (usage_id, session_id, skill_name, loaded_at, context_added)
+			VALUES (?, ?, 'docker', ?, NULL)`, usageID, sessionID, formatTime(time.Now()))
+	}
+
+	dups, err := FindDuplicateSkillLoads(db, time.Time{})
+	if err != nil {
+		t.Fatalf("FindDuplicateSkillLoads failed: %v", err)
+	}
+	if len(dups) != 1 || dups[0].WastedContext != 0 {
+		t.Errorf("duplicates = %+v, want one with no wasted context", dups)
+	}
+}
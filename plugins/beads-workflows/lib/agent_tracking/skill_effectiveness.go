@@ -0,0 +1,281 @@
+package agent_tracking
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Skill effectiveness verdicts.
+const (
+	SkillVerdictHelps            = "helps"
+	SkillVerdictNotPayingOff     = "not_paying_off"
+	SkillVerdictInsufficientData = "insufficient_data"
+)
+
+// OutcomeStats summarizes the outcomes of a group of work attempts.
+type OutcomeStats struct {
+	Attempts       int           `json:"attempts"`
+	Completed      int           `json:"completed"`
+	Reworked       int           `json:"reworked"`
+	CompletionRate float64       `json:"completion_rate"`
+	ReworkRate     float64       `json:"rework_rate"`
+	AvgDuration    time.Duration `json:"avg_duration"`
+	AvgTokens      float64       `json:"avg_tokens"`
+}
+
+// SkillEffectiveness compares work that loaded a skill for its issue with work that didn't.
+//
+// The differences are computed within each issue type and averaged, weighted by how
+// many attempts loaded the skill, so a skill used mostly on hard issue types isn't
+// penalized for them. Positive CompletionLift and negative ReworkChange are good.
+type SkillEffectiveness struct {
+	SkillName       string        `json:"skill_name"`
+	WithSkill       OutcomeStats  `json:"with_skill"`
+	WithoutSkill    OutcomeStats  `json:"without_skill"`
+	IssueTypes      int           `json:"issue_types"`
+	CompletionLift  float64       `json:"completion_lift"`
+	ReworkChange    float64       `json:"rework_change"`
+	DurationChange  time.Duration `json:"duration_change"`
+	TokenChange     float64       `json:"token_change"`
+	AvgContextAdded float64       `json:"avg_context_added"`
+	Verdict         string        `json:"verdict"`
+}
+
+// SkillEffectivenessOptions controls the skill effectiveness analysis.
+type SkillEffectivenessOptions struct {
+	Since       time.Time // Only consider sessions started at or after this time
+	MinAttempts int       // Minimum comparable attempts with the skill loaded (default 5)
+	MinLift     float64   // Completion lift or rework reduction that counts as helping (default 0.05)
+}
+
+// outcomeTotals accumulates the sums behind an OutcomeStats' averages.
+type outcomeTotals struct {
+	stats   OutcomeStats
+	seconds float64
+	tokens  float64
+}
+
+// add counts one attempt.
+func (t *outcomeTotals) add(a workAttempt) {
+	t.stats.Attempts++
+	t.tokens += a.tokens
+	if a.completed {
+		t.stats.Completed++
+		t.seconds += a.seconds
+		if a.reworked {
+			t.stats.Reworked++
+		}
+	}
+}
+
+// result computes the rates and averages.
+func (t *outcomeTotals) result() OutcomeStats {
+	s := t.stats
+	if s.Attempts > 0 {
+		s.CompletionRate = float64(s.Completed) / float64(s.Attempts)
+		s.AvgTokens = t.tokens / float64(s.Attempts)
+	}
+	if s.Completed > 0 {
+		s.ReworkRate = float64(s.Reworked) / float64(s.Completed)
+		s.AvgDuration = secondsToDuration(t.seconds / float64(s.Completed))
+	}
+	return s
+}
+
+// skillLoad is a skill loaded in a session for a specific issue.
+type skillLoad struct {
+	sessionID string
+	issueID   string
+}
+
+// GetSkillEffectiveness compares, for every skill loaded for an issue, the outcomes of
+// work that loaded it against work on the same issue types that didn't.
+// Results are ordered with skills that aren't paying off first, most context first.
+//
+// Example:
+//
+//	results, err := agent_tracking.GetSkillEffectiveness(db, agent_tracking.SkillEffectivenessOptions{
+//	    Since: time.Now().AddDate(0, -3, 0),
+//	})
+//	for _, r := range results {
+//	    fmt.Printf("%s: %s (%+.0f%% completion, %.0f tokens per load)\n",
+//	        r.SkillName, r.Verdict, r.CompletionLift*100, r.AvgContextAdded)
+//	}
+func GetSkillEffectiveness(db *sql.DB, opts SkillEffectivenessOptions) ([]SkillEffectiveness, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	if opts.MinAttempts <= 0 {
+		opts.MinAttempts = 5
+	}
+	if opts.MinLift <= 0 {
+		opts.MinLift = 0.05
+	}
+
+	attempts, err := getWorkAttempts(db, opts.Since)
+	if err != nil {
+		return nil, err
+	}
+
+	loads, contextAdded, err := getSkillLoadsByIssue(db, opts.Since)
+	if err != nil {
+		return nil, err
+	}
+
+	skillNames := make([]string, 0, len(loads))
+	for name := range loads {
+		skillNames = append(skillNames, name)
+	}
+	sort.Strings(skillNames)
+
+	var results []SkillEffectiveness
+	for _, name := range skillNames {
+		loadedFor := loads[name]
+
+		var with, without outcomeTotals
+		withByType := make(map[string]*outcomeTotals)
+		withoutByType := make(map[string]*outcomeTotals)
+		for _, a := range attempts {
+			byType, totals := withoutByType, &without
+			if loadedFor[skillLoad{sessionID: a.sessionID, issueID: a.issueID}] {
+				byType, totals = withByType, &with
+			}
+			totals.add(a)
+			if byType[a.issueType] == nil {
+				byType[a.issueType] = &outcomeTotals{}
+			}
+			byType[a.issueType].add(a)
+		}
+
+		r := SkillEffectiveness{
+			SkillName:       name,
+			WithSkill:       with.result(),
+			WithoutSkill:    without.result(),
+			AvgContextAdded: contextAdded[name],
+		}
+
+		var weight, completion, rework, tokens, durWeight, duration float64
+		comparable := 0
+		issueTypes := make([]string, 0, len(withByType))
+		for issueType := range withByType {
+			issueTypes = append(issueTypes, issueType)
+		}
+		sort.Strings(issueTypes)
+		for _, issueType := range issueTypes {
+			w := withByType[issueType]
+			wo, ok := withoutByType[issueType]
+			if !ok {
+				continue
+			}
+			ws, wos := w.result(), wo.result()
+			n := float64(ws.Attempts)
+			r.IssueTypes++
+			comparable += ws.Attempts
+			weight += n
+			completion += n * (ws.CompletionRate - wos.CompletionRate)
+			tokens += n * (ws.AvgTokens - wos.AvgTokens)
+			if ws.Completed > 0 && wos.Completed > 0 {
+				durWeight += n
+				rework += n * (ws.ReworkRate - wos.ReworkRate)
+				duration += n * float64(ws.AvgDuration-wos.AvgDuration)
+			}
+		}
+		if weight > 0 {
+			r.CompletionLift = completion / weight
+			r.TokenChange = tokens / weight
+		}
+		if durWeight > 0 {
+			r.ReworkChange = rework / durWeight
+			r.DurationChange = time.Duration(duration / durWeight)
+		}
+
+		switch {
+		case comparable < opts.MinAttempts:
+			r.Verdict = SkillVerdictInsufficientData
+		case r.CompletionLift >= opts.MinLift || -r.ReworkChange >= opts.MinLift:
+			r.Verdict = SkillVerdictHelps
+		default:
+			r.Verdict = SkillVerdictNotPayingOff
+		}
+
+		results = append(results, r)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i].Verdict == SkillVerdictNotPayingOff, results[j].Verdict == SkillVerdictNotPayingOff
+		if a != b {
+			return a
+		}
+		return results[i].AvgContextAdded > results[j].AvgContextAdded
+	})
+
+	return results, nil
+}
+
+// ListUnprofitableSkills returns the skills whose context cost isn't paying off:
+// loading them doesn't raise completion or reduce rework by at least MinLift.
+//
+// Example:
+//
+//	skills, err := agent_tracking.ListUnprofitableSkills(db, agent_tracking.SkillEffectivenessOptions{})
+func ListUnprofitableSkills(db *sql.DB, opts SkillEffectivenessOptions) ([]SkillEffectiveness, error) {
+	results, err := GetSkillEffectiveness(db, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var unprofitable []SkillEffectiveness
+	for _, r := range results {
+		if r.Verdict == SkillVerdictNotPayingOff {
+			unprofitable = append(unprofitable, r)
+		}
+	}
+
+	return unprofitable, nil
+}
+
+// getSkillLoadsByIssue returns, per skill, the (session, issue) pairs it was loaded for
+// and its average context added per load.
+func getSkillLoadsByIssue(db *sql.DB, since time.Time) (map[string]map[skillLoad]bool, map[string]float64, error) {
+	rows, err := db.Query(`
+		SELECT u.skill_name, u.session_id, u.used_for_issue_id, COALESCE(u.context_added, 0)
+		FROM agent_skill_usage u
+		JOIN agent_sessions s ON u.session_id = s.session_id
+		WHERE s.started_at >= ? AND u.used_for_issue_id IS NOT NULL AND u.used_for_issue_id != ''
+	`, formatTime(since))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get skill loads: %w", err)
+	}
+	defer rows.Close()
+
+	loads := make(map[string]map[skillLoad]bool)
+	contextTotals := make(map[string]int)
+	loadCounts := make(map[string]int)
+	for rows.Next() {
+		var name string
+		var load skillLoad
+		var contextAdded int
+		if err := rows.Scan(&name, &load.sessionID, &load.issueID, &contextAdded); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan skill load: %w", err)
+		}
+		if loads[name] == nil {
+			loads[name] = make(map[skillLoad]bool)
+		}
+		loads[name][load] = true
+		contextTotals[name] += contextAdded
+		loadCounts[name]++
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating skill loads: %w", err)
+	}
+
+	avgContext := make(map[string]float64, len(contextTotals))
+	for name, total := range contextTotals {
+		avgContext[name] = float64(total) / float64(loadCounts[name])
+	}
+
+	return loads, avgContext, nil
+}
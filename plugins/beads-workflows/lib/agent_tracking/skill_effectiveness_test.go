@@ -0,0 +1,81 @@
+package agent_tracking
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGetSkillEffectiveness(t *testing.T) {
+	db := openTestDB(t)
+
+	// "tdd" is loaded for five bugs that were all completed and "lore" for five
+	// bugs of which one was completed.
+	for i := 0; i < 10; i++ {
+		issueID := fmt.Sprintf("bug-%d", i)
+		mustExec(t, db, `INSERT INTO issues (id, title, issue_type) VALUES (?, ?, 'bug')`, issueID, issueID)
+		sessionID, err := StartSession(db, "agent", "/ws", "sonnet")
+		if err != nil {
+			t.Fatal(err)
+		}
+		workID, err := RecordWork(db, sessionID, issueID, "agent", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		skill := "tdd"
+		if i >= 5 {
+			skill = "lore"
+		}
+		if i < 6 {
+			if err := CompleteWork(db, workID, ""); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if i == 9 {
+			// Rows written by older clients may have no context size.
+			mustExec(t, db, `INSERT INTO agent_skill_usage (usage_id, session_id, skill_name, loaded_at, used_for_issue_id, context_added)
+				VALUES ('legacy', ?, 'lore', ?, ?, NULL)`, sessionID, "2026-03-02T10:00:00Z", issueID)
+		} else if err := RecordSkillUsage(db, sessionID, skill, issueID, 400); err != nil {
+			t.Fatal(err)
+		}
+		if err := EndSession(db, sessionID, ExitCompleted); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := GetSkillEffectiveness(db, SkillEffectivenessOptions{})
+	if err != nil {
+		t.Fatalf("GetSkillEffectiveness failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d skills, want 2", len(results))
+	}
+
+	lore, tdd := results[0], results[1]
+	if lore.SkillName != "lore" || lore.Verdict != SkillVerdictNotPayingOff {
+		t.Errorf("first result = %s (%s), want lore not paying off", lore.SkillName, lore.Verdict)
+	}
+	if lore.WithSkill.CompletionRate != 0.2 || lore.WithoutSkill.CompletionRate != 1 || lore.AvgContextAdded != 320 {
+		t.Errorf("lore = %.1f with, %.1f without, %.0f context", lore.WithSkill.CompletionRate, lore.WithoutSkill.CompletionRate, lore.AvgContextAdded)
+	}
+	if tdd.SkillName != "tdd" || tdd.Verdict != SkillVerdictHelps || tdd.CompletionLift < 0.79 || tdd.CompletionLift > 0.81 {
+		t.Errorf("tdd = %s with %.2f lift, want helps with 0.8", tdd.Verdict, tdd.CompletionLift)
+	}
+
+	unprofitable, err := ListUnprofitableSkills(db, SkillEffectivenessOptions{})
+	if err != nil {
+		t.Fatalf("ListUnprofitableSkills failed: %v", err)
+	}
+	if len(unprofitable) != 1 || unprofitable[0].SkillName != "lore" {
+		t.Errorf("unprofitable = %+v, want only lore", unprofitable)
+	}
+
+	insufficient, err := GetSkillEffectiveness(db, SkillEffectivenessOptions{MinAttempts: 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range insufficient {
+		if r.Verdict != SkillVerdictInsufficientData {
+			t.Errorf("%s verdict = %s with too few attempts", r.SkillName, r.Verdict)
+		}
+	}
+}
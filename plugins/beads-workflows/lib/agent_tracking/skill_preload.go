@@ -0,0 +1,167 @@
+package agent_tracking
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// SkillPreloadOptions controls which skills are recommended for preloading.
+type SkillPreloadOptions struct {
+	IssueType   string    // Issue type the session will work on (required)
+	AgentName   string    // Agent the session is for; falls back to all agents when its history is too thin
+	TokenBudget int       // Maximum combined context the recommended skills may add (default 4000)
+	MinAttempts int       // Minimum attempts with a skill loaded before it is trusted (default 3)
+	Since       time.Time // Only consider sessions started at or after this time
+}
+
+// SkillPreload is a candidate skill with the benefit it showed on matching work.
+// Benefit is the completion lift plus the rework reduction compared with matching
+// work that didn't load the skill.
+type SkillPreload struct {
+	SkillName       string  `json:"skill_name"`
+	Attempts        int     `json:"attempts"`
+	Benefit         float64 `json:"benefit"`
+	AvgContext      float64 `json:"avg_context"`
+	BenefitPerToken float64 `json:"benefit_per_token"`
+	Selected        bool    `json:"selected"`
+}
+
+// SkillPreloadRecommendation is the skill set to preload at session start.
+type SkillPreloadRecommendation struct {
+	IssueType    string         `json:"issue_type"`
+	AgentName    string         `json:"agent_name,omitempty"`
+	Basis        string         `json:"basis"`
+	TokenBudget  int            `json:"token_budget"`
+	TotalContext int            `json:"total_context"`
+	Skills       []string       `json:"skills"`
+	Candidates   []SkillPreload `json:"candidates"`
+}
+
+// RecommendSkillPreload suggests skills to load up front for work on an issue type.
+// Candidates are ranked by benefit per context token, and skills with a positive
+// benefit are selected in that order while they fit in the token budget.
+//
+// When an agent name is given, only that agent's work is used unless it has no
+// skill with enough attempts, in which case all agents' work is used; Basis records which.
+//
+// Example:
+//
+//	rec, err := agent_tracking.RecommendSkillPreload(db, agent_tracking.SkillPreloadOptions{
+//	    IssueType:   "feature",
+//	    AgentName:   "beads-workflow-orchestrator",
+//	    TokenBudget: 3000,
+//	})
+//	for _, skill := range rec.Skills {
+//	    // load skill
+//	}
+func RecommendSkillPreload(db *sql.DB, opts SkillPreloadOptions) (*SkillPreloadRecommendation, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	if opts.IssueType == "" {
+		return nil, fmt.Errorf("issue type is required")
+	}
+	if opts.TokenBudget <= 0 {
+		opts.TokenBudget = 4000
+	}
+	if opts.MinAttempts <= 0 {
+		opts.MinAttempts = 3
+	}
+
+	attempts, err := getWorkAttempts(db, opts.Since)
+	if err != nil {
+		return nil, err
+	}
+
+	loads, contextAdded, err := getSkillLoadsByIssue(db, opts.Since)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &SkillPreloadRecommendation{
+		IssueType:   opts.IssueType,
+		AgentName:   opts.AgentName,
+		TokenBudget: opts.TokenBudget,
+		Skills:      []string{},
+	}
+
+	var candidates []SkillPreload
+	if opts.AgentName != "" {
+		rec.Basis = "agent"
+		candidates = rankSkillPreloads(attempts, loads, contextAdded, opts.IssueType, opts.AgentName, opts.MinAttempts)
+	}
+	if len(candidates) == 0 {
+		rec.Basis = "all_agents"
+		candidates = rankSkillPreloads(attempts, loads, contextAdded, opts.IssueType, "", opts.MinAttempts)
+	}
+
+	for i := range candidates {
+		c := &candidates[i]
+		if c.Benefit <= 0 {
+			continue
+		}
+		cost := int(math.Ceil(c.AvgContext))
+		if rec.TotalContext+cost > opts.TokenBudget {
+			continue
+		}
+		c.Selected = true
+		rec.TotalContext += cost
+		rec.Skills = append(rec.Skills, c.SkillName)
+	}
+	rec.Candidates = candidates
+
+	return rec, nil
+}
+
+// rankSkillPreloads scores every skill with at least minAttempts matching attempts
+// and returns them best benefit per token first.
+func rankSkillPreloads(attempts []workAttempt, loads map[string]map[skillLoad]bool, contextAdded map[string]float64, issueType, agentName string, minAttempts int) []SkillPreload {
+	var matching []workAttempt
+	for _, a := range attempts {
+		if a.issueType != issueType {
+			continue
+		}
+		if agentName != "" && a.agentName != agentName {
+			continue
+		}
+		matching = append(matching, a)
+	}
+
+	var candidates []SkillPreload
+	for name, loadedFor := range loads {
+		var with, without outcomeTotals
+		for _, a := range matching {
+			if loadedFor[skillLoad{sessionID: a.sessionID, issueID: a.issueID}] {
+				with.add(a)
+			} else {
+				without.add(a)
+			}
+		}
+		if with.stats.Attempts < minAttempts || without.stats.Attempts == 0 {
+			continue
+		}
+
+		ws, wos := with.result(), without.result()
+		c := SkillPreload{
+			SkillName:  name,
+			Attempts:   ws.Attempts,
+			Benefit:    (ws.CompletionRate - wos.CompletionRate) + (wos.ReworkRate - ws.ReworkRate),
+			AvgContext: contextAdded[name],
+		}
+		// Skills that add no context are free; rank them by benefit alone.
+		c.BenefitPerToken = c.Benefit / math.Max(c.AvgContext, 1)
+		candidates = append(candidates, c)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].BenefitPerToken != candidates[j].BenefitPerToken {
+			return candidates[i].BenefitPerToken > candidates[j].BenefitPerToken
+		}
+		return candidates[i].SkillName < candidates[j].SkillName
+	})
+
+	return candidates
+}
@@ -0,0 +1,89 @@
+package agent_tracking
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestRecommendSkillPreload(t *testing.T) {
+	db := openTestDB(t)
+
+	// Even attempts load "focus" and complete; "bulky" rides along on every
+	// fourth attempt and "noise" on three that fail.
+	for i := 0; i < 12; i++ {
+		issueID := fmt.Sprintf("bug-%d", i)
+		mustExec(t, db, `INSERT INTO issues (id, title, issue_type) VALUES (?, ?, 'bug')`, issueID, issueID)
+		sessionID, err := StartSession(db, "agent", "/ws", "sonnet")
+		if err != nil {
+			t.Fatal(err)
+		}
+		workID, err := RecordWork(db, sessionID, issueID, "agent", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var skills []string
+		if i%2 == 0 {
+			skills = append(skills, "focus")
+			if err := CompleteWork(db, workID, ""); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if i%4 == 0 {
+			skills = append(skills, "bulky")
+		}
+		if i == 1 || i == 3 || i == 5 {
+			skills = append(skills, "noise")
+		}
+		context := map[string]int{"focus": 500, "bulky": 5000, "noise": 100}
+		for _, skill := range skills {
+			if err := RecordSkillUsage(db, sessionID, skill, issueID, context[skill]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := EndSession(db, sessionID, ExitCompleted); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec, err := RecommendSkillPreload(db, SkillPreloadOptions{IssueType: "bug", AgentName: "agent"})
+	if err != nil {
+		t.Fatalf("RecommendSkillPreload failed: %v", err)
+	}
+	if rec.Basis != "agent" {
+		t.Errorf("basis = %s, want agent", rec.Basis)
+	}
+	if !reflect.DeepEqual(rec.Skills, []string{"focus"}) || rec.TotalContext != 500 {
+		t.Errorf("skills = %v (%d tokens), want focus only", rec.Skills, rec.TotalContext)
+	}
+	if len(rec.Candidates) != 3 || rec.Candidates[0].SkillName != "focus" || rec.Candidates[0].Benefit != 1 {
+		t.Errorf("candidates = %+v, want focus first with full benefit", rec.Candidates)
+	}
+	for _, c := range rec.Candidates {
+		if c.SkillName == "noise" && c.Benefit >= 0 {
+			t.Errorf("noise benefit = %.2f, want negative", c.Benefit)
+		}
+	}
+
+	// A bigger budget fits the helpful but expensive skill too.
+	rec, err = RecommendSkillPreload(db, SkillPreloadOptions{IssueType: "bug", AgentName: "agent", TokenBudget: 6000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rec.Skills, []string{"focus", "bulky"}) {
+		t.Errorf("skills = %v, want focus and bulky", rec.Skills)
+	}
+
+	// An agent without history falls back to everyone's.
+	rec, err = RecommendSkillPreload(db, SkillPreloadOptions{IssueType: "bug", AgentName: "newcomer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Basis != "all_agents" || !reflect.DeepEqual(rec.Skills, []string{"focus"}) {
+		t.Errorf("newcomer = %s %v, want all_agents [focus]", rec.Basis, rec.Skills)
+	}
+
+	if _, err := RecommendSkillPreload(db, SkillPreloadOptions{}); err == nil {
+		t.Error("expected an error without an issue type")
+	}
+}
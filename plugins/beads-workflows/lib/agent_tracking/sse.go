@@ -0,0 +1,203 @@
+package agent_tracking
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultStreamEventTypes are the event types an EventStream sends when the
+// request doesn't ask for specific types.
+var DefaultStreamEventTypes = []string{
+	EventSessionStarted,
+	EventSessionEnded,
+	EventWorkStarted,
+	EventWorkCompleted,
+	EventSkillUsed,
+	EventTokensUpdated,
+}
+
+// EventStreamOptions configures an EventStream.
+type EventStreamOptions struct {
+	PollInterval time.Duration // How often to read new changes (default 1s)
+	Heartbeat    time.Duration // Interval of keep-alive comments (default 15s)
+	BatchSize    int           // Changes read per query (default 500)
+	OnError      func(error)   // Called when reading changes fails (default log.Printf); clients only see a generic message
+}
+
+// EventStream is an http.Handler that streams tracking activity as Server-Sent
+// Events, read from the change log so that every process's writes are included.
+//
+// Each SSE message has the change ID as its id, the event type as its event name
+// and the Event as JSON data. Browsers reconnecting with Last-Event-ID resume
+// after that change; new clients only receive changes from the time they connect
+// unless they pass a last_event_id query parameter.
+//
+// Query parameters:
+//   - agent: only events for this agent
+//   - workspace: only events for sessions in this workspace
+//   - session: only events for this session
+//   - type: event types to send, repeated or comma separated (default DefaultStreamEventTypes)
+//   - last_event_id: resume after this change ID, for clients that can't set headers
+//
+// Example:
+//
+//	stream, err := agent_tracking.NewEventStream(db, agent_tracking.EventStreamOptions{})
+//	if err != nil {
+//	    return err
+//	}
+//	http.Handle("/events", stream)
+//
+//	// In the browser:
+//	// const source = new EventSource("/events?workspace=/myStuff/project");
+//	// source.addEventListener("work_completed", e => console.log(JSON.parse(e.data)));
+type EventStream struct {
+	db   *sql.DB
+	opts EventStreamOptions
+}
+
+// NewEventStream creates an SSE handler for the tracking activity in db.
+func NewEventStream(db *sql.DB, opts EventStreamOptions) (*EventStream, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.Heartbeat <= 0 {
+		opts.Heartbeat = 15 * time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+	if opts.OnError == nil {
+		opts.OnError = func(err error) {
+			log.Printf("agent_tracking: event stream: %v", err)
+		}
+	}
+
+	return &EventStream{db: db, opts: opts}, nil
+}
+
+// ServeHTTP streams events until the client disconnects.
+func (s *EventStream) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	query := req.URL.Query()
+	filter := EventFilter{
+		AgentName:     query.Get("agent"),
+		WorkspacePath: query.Get("workspace"),
+		SessionID:     query.Get("session"),
+		Types:         DefaultStreamEventTypes,
+	}
+	if types := streamEventTypes(query["type"]); len(types) > 0 {
+		filter.Types = types
+	}
+
+	lastEventID := req.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = query.Get("last_event_id")
+	}
+	var cursor int64
+	if lastEventID != "" {
+		id, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || id < 0 {
+			http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		cursor = id
+	} else {
+		latest, err := LatestChangeID(s.db)
+		if err != nil {
+			s.opts.OnError(err)
+			http.Error(w, "failed to read changes", http.StatusInternalServerError)
+			return
+		}
+		cursor = latest
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", s.opts.PollInterval.Milliseconds()*2)
+	flusher.Flush()
+
+	ctx := req.Context()
+	poll := time.NewTicker(s.opts.PollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(s.opts.Heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		for {
+			events, err := queryChanges(ctx, s.db, cursor, filter, s.opts.BatchSize)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				s.opts.OnError(err)
+				fmt.Fprint(w, ": failed to read changes, retrying\n\n")
+				flusher.Flush()
+				break
+			}
+			for _, e := range events {
+				if err := writeStreamEvent(w, e); err != nil {
+					return
+				}
+				cursor = e.ID
+			}
+			if len(events) > 0 {
+				flusher.Flush()
+			}
+			if len(events) < s.opts.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-poll.C:
+		}
+	}
+}
+
+// writeStreamEvent writes one event in SSE format.
+func writeStreamEvent(w http.ResponseWriter, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
+
+// streamEventTypes splits repeated and comma separated type parameters.
+func streamEventTypes(values []string) []string {
+	var types []string
+	for _, v := range values {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				types = append(types, t)
+			}
+		}
+	}
+	return types
+}
@@ -0,0 +1,196 @@
+package agent_tracking
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sseMessage is one message read from an event stream.
+type sseMessage struct {
+	id    string
+	event string
+	data  string
+}
+
+// openEventStream connects to an event stream and returns its messages as they
+// arrive. The connection is closed when the test ends.
+func openEventStream(t *testing.T, url, lastEventID string) <-chan sseMessage {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("stream response = %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	messages := make(chan sseMessage, 16)
+	go func() {
+		defer resp.Body.Close()
+		defer close(messages)
+		var msg sseMessage
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if msg.event != "" {
+					messages <- msg
+				}
+				msg = sseMessage{}
+			case strings.HasPrefix(line, "id: "):
+				msg.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				msg.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				msg.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return messages
+}
+
+func nextMessage(t *testing.T, messages <-chan sseMessage) sseMessage {
+	t.Helper()
+	select {
+	case msg, ok := <-messages:
+		if !ok {
+			t.Fatal("stream closed")
+		}
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a stream message")
+	}
+	return sseMessage{}
+}
+
+func TestEventStream(t *testing.T) {
+	db := openTestDB(t)
+	stream, err := NewEventStream(db, EventStreamOptions{PollInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Registered before the streams so the clients disconnect first.
+	server := httptest.NewServer(stream)
+	t.Cleanup(server.Close)
+
+	old, err := StartSession(db, "agent", "/ws/a", "sonnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// New clients only see changes from the time they connect.
+	live := openEventStream(t, server.URL+"?workspace=/ws/a&type=work_started,session_ended", "")
+	other, err := StartSession(db, "agent", "/ws/b", "sonnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RecordWork(db, other, "agents-2", "agent", ""); err != nil {
+		t.Fatal(err)
+	}
+	workID, err := RecordWork(db, old, "agents-1", "agent", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := EndSession(db, old, ExitCompleted); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := nextMessage(t, live)
+	var e Event
+	if err := json.Unmarshal([]byte(msg.data), &e); err != nil {
+		t.Fatalf("invalid event data %q: %v", msg.data, err)
+	}
+	if msg.event != EventWorkStarted || e.WorkID != workID || e.WorkspacePath != "/ws/a" || msg.id != strconv.FormatInt(e.ID, 10) {
+		t.Errorf("first message = %+v (%+v)", msg, e)
+	}
+	if msg = nextMessage(t, live); msg.event != EventSessionEnded {
+		t.Errorf("second message = %+v, want session_ended", msg)
+	}
+
+	// Reconnecting with Last-Event-ID replays from the change log.
+	resumed := openEventStream(t, server.URL+"?session="+old, "0")
+	var events []string
+	for i := 0; i < 3; i++ {
+		events = append(events, nextMessage(t, resumed).event)
+	}
+	if got := strings.Join(events, ","); got != "session_started,work_started,session_ended" {
+		t.Errorf("replayed events = %s", got)
+	}
+}
+
+func TestEventStreamRejectsBadLastEventID(t *testing.T) {
+	db := openTestDB(t)
+	stream, err := NewEventStream(db, EventStreamOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	stream.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events?last_event_id=abc", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestEventStreamHidesQueryErrors(t *testing.T) {
+	db := openTestDB(t)
+	mustExec(t, db, `DROP TABLE agent_changes`)
+	errs := make(chan error, 16)
+	stream, err := NewEventStream(db, EventStreamOptions{
+		PollInterval: 10 * time.Millisecond,
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	stream.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "agent_changes") {
+		t.Errorf("response = %d %q, want a 500 without the SQL error", rec.Code, rec.Body.String())
+	}
+	if err := <-errs; !strings.Contains(err.Error(), "agent_changes") {
+		t.Errorf("reported error = %v, want the SQL error", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rec = httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		stream.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events?last_event_id=0", nil).WithContext(ctx))
+	}()
+	select {
+	case <-errs:
+	case <-time.After(2 * time.Second):
+		t.Error("query error not reported")
+	}
+	cancel()
+	<-done
+	body := rec.Body.String()
+	if !strings.Contains(body, ": failed to read changes, retrying\n\n") || strings.Contains(body, "agent_changes") {
+		t.Errorf("stream = %q, want a generic comment", body)
+	}
+}
@@ -72,6 +72,7 @@ type OverallStats struct {
 	UniqueSkills    int          `json:"unique_skills"`
 	TotalTokens     int          `json:"total_tokens"`
 	TopAgents       []AgentCount `json:"top_agents"`
+	TopSkills       []SkillCount `json:"top_skills"`
 	Since           time.Time    `json:"since"`
 }
 
@@ -91,6 +92,12 @@ type SessionDuration struct {
 //	stats, err := agent_tracking.GetAgentStats(db, "beads-workflow-orchestrator", time.Now().AddDate(0, -1, 0))
 //	fmt.Printf("Sessions: %d, Issues: %d\n", stats.TotalSessions, stats.TotalIssues)
 func GetAgentStats(db *sql.DB, agentName string, since time.Time) (*AgentStats, error) {
+	return getAgentStatsBetween(db, agentName, since, time.Time{})
+}
+
+// getAgentStatsBetween is GetAgentStats for sessions started in [since, until).
+// A zero until means no upper bound.
+func getAgentStatsBetween(db *sql.DB, agentName string, since, until time.Time) (*AgentStats, error) {
 	if db == nil {
 		return nil, fmt.Errorf("database connection is nil")
 	}
@@ -103,7 +110,7 @@ func GetAgentStats(db *sql.DB, agentName string, since time.Time) (*AgentStats,
 		Since:     since,
 	}
 
-	sinceStr := formatTime(since)
+	sinceStr, untilStr := formatTime(since), formatUntil(until)
 
 	// Get session counts
 	err := db.QueryRow(`
@@ -112,8 +119,8 @@ func GetAgentStats(db *sql.DB, agentName string, since time.Time) (*AgentStats,
 			SUM(CASE WHEN ended_at IS NULL THEN 1 ELSE 0 END) as active,
 			COALESCE(SUM(context_tokens), 0) as tokens
 		FROM agent_sessions
-		WHERE agent_name = ? AND started_at >= ?
-	`, agentName, sinceStr).Scan(&stats.TotalSessions, &stats.ActiveSessions, &stats.TotalTokens)
+		WHERE agent_name = ? AND started_at >= ? AND started_at < ?
+	`, agentName, sinceStr, untilStr).Scan(&stats.TotalSessions, &stats.ActiveSessions, &stats.TotalTokens)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get session counts: %w", err)
 	}
@@ -125,8 +132,8 @@ func GetAgentStats(db *sql.DB, agentName string, since time.Time) (*AgentStats,
 			JULIANDAY(ended_at) - JULIANDAY(started_at)
 		) * 86400 as avg_seconds
 		FROM agent_sessions
-		WHERE agent_name = ? AND started_at >= ? AND ended_at IS NOT NULL
-	`, agentName, sinceStr).Scan(&avgSeconds)
+		WHERE agent_name = ? AND started_at >= ? AND started_at < ? AND ended_at IS NOT NULL
+	`, agentName, sinceStr, untilStr).Scan(&avgSeconds)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get average session time: %w", err)
 	}
@@ -141,8 +148,8 @@ func GetAgentStats(db *sql.DB, agentName string, since time.Time) (*AgentStats,
 			COUNT(DISTINCT CASE WHEN completed = 1 THEN issue_id END) as completed
 		FROM agent_issue_work w
 		JOIN agent_sessions s ON w.session_id = s.session_id
-		WHERE w.agent_name = ? AND s.started_at >= ?
-	`, agentName, sinceStr).Scan(&stats.TotalIssues, &stats.CompletedIssues)
+		WHERE w.agent_name = ? AND s.started_at >= ? AND s.started_at < ?
+	`, agentName, sinceStr, untilStr).Scan(&stats.TotalIssues, &stats.CompletedIssues)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get issue counts: %w", err)
 	}
@@ -152,8 +159,8 @@ func GetAgentStats(db *sql.DB, agentName string, since time.Time) (*AgentStats,
 		SELECT COUNT(*)
 		FROM agent_skill_usage u
 		JOIN agent_sessions s ON u.session_id = s.session_id
-		WHERE s.agent_name = ? AND s.started_at >= ?
-	`, agentName, sinceStr).Scan(&stats.TotalSkillUses)
+		WHERE s.agent_name = ? AND s.started_at >= ? AND s.started_at < ?
+	`, agentName, sinceStr, untilStr).Scan(&stats.TotalSkillUses)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get skill usage count: %w", err)
 	}
@@ -163,11 +170,11 @@ func GetAgentStats(db *sql.DB, agentName string, since time.Time) (*AgentStats,
 		SELECT u.skill_name, COUNT(*) as cnt
 		FROM agent_skill_usage u
 		JOIN agent_sessions s ON u.session_id = s.session_id
-		WHERE s.agent_name = ? AND s.started_at >= ?
+		WHERE s.agent_name = ? AND s.started_at >= ? AND s.started_at < ?
 		GROUP BY u.skill_name
 		ORDER BY cnt DESC
 		LIMIT 5
-	`, agentName, sinceStr)
+	`, agentName, sinceStr, untilStr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get most used skills: %w", err)
 	}
@@ -191,6 +198,13 @@ func GetAgentStats(db *sql.DB, agentName string, since time.Time) (*AgentStats,
 //	stats, err := agent_tracking.GetIssueStats(db, "agents-42")
 //	fmt.Printf("Work sessions: %d, Agents: %d\n", stats.TotalWorkSessions, stats.TotalAgents)
 func GetIssueStats(db *sql.DB, issueID string) (*IssueStats, error) {
+	return getIssueStatsBetween(db, issueID, time.Time{}, time.Time{})
+}
+
+// getIssueStatsBetween is GetIssueStats limited to work done in sessions started
+// in [since, until), with open work counted up to until. A zero until means no
+// upper bound.
+func getIssueStatsBetween(db *sql.DB, issueID string, since, until time.Time) (*IssueStats, error) {
 	if db == nil {
 		return nil, fmt.Errorf("database connection is nil")
 	}
@@ -202,16 +216,29 @@ func GetIssueStats(db *sql.DB, issueID string) (*IssueStats, error) {
 		IssueID: issueID,
 	}
 
+	untilStr := formatUntil(until)
+
+	// Only a bounded window needs the sessions' start times; unbounded stats
+	// keep work whose session row is missing.
+	from := "agent_issue_work w"
+	where := "w.issue_id = ?"
+	args := []interface{}{issueID}
+	if !since.IsZero() || !until.IsZero() {
+		from += " JOIN agent_sessions s ON w.session_id = s.session_id"
+		where += " AND s.started_at >= ? AND s.started_at < ?"
+		args = append(args, formatTime(since), untilStr)
+	}
+	clippedArgs := append([]interface{}{untilStr}, args...)
+
 	// Get work session and agent counts
 	var isCompleted int
 	err := db.QueryRow(`
 		SELECT
 			COUNT(*) as total_work,
-			COUNT(DISTINCT agent_name) as agents,
-			MAX(completed) as is_completed
-		FROM agent_issue_work
-		WHERE issue_id = ?
-	`, issueID).Scan(&stats.TotalWorkSessions, &stats.TotalAgents, &isCompleted)
+			COUNT(DISTINCT w.agent_name) as agents,
+			COALESCE(MAX(w.completed), 0) as is_completed
+		FROM `+from+`
+		WHERE `+where, args...).Scan(&stats.TotalWorkSessions, &stats.TotalAgents, &isCompleted)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get issue counts: %w", err)
 	}
@@ -221,11 +248,10 @@ func GetIssueStats(db *sql.DB, issueID string) (*IssueStats, error) {
 	var totalSeconds sql.NullFloat64
 	err = db.QueryRow(`
 		SELECT SUM(
-			JULIANDAY(COALESCE(ended_at, datetime('now'))) - JULIANDAY(started_at)
+			MIN(JULIANDAY(COALESCE(w.ended_at, datetime('now'))), JULIANDAY(?)) - JULIANDAY(w.started_at)
 		) * 86400 as total_seconds
-		FROM agent_issue_work
-		WHERE issue_id = ?
-	`, issueID).Scan(&totalSeconds)
+		FROM `+from+`
+		WHERE `+where, clippedArgs...).Scan(&totalSeconds)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get total time: %w", err)
 	}
@@ -236,15 +262,15 @@ func GetIssueStats(db *sql.DB, issueID string) (*IssueStats, error) {
 	// Get agent breakdown
 	rows, err := db.Query(`
 		SELECT
-			agent_name,
+			w.agent_name,
 			COUNT(*) as work_sessions,
-			SUM(JULIANDAY(COALESCE(ended_at, datetime('now'))) - JULIANDAY(started_at)) * 86400 as total_seconds,
-			SUM(completed) as completed_count
-		FROM agent_issue_work
-		WHERE issue_id = ?
-		GROUP BY agent_name
+			SUM(MIN(JULIANDAY(COALESCE(w.ended_at, datetime('now'))), JULIANDAY(?)) - JULIANDAY(w.started_at)) * 86400 as total_seconds,
+			SUM(w.completed) as completed_count
+		FROM `+from+`
+		WHERE `+where+`
+		GROUP BY w.agent_name
 		ORDER BY work_sessions DESC
-	`, issueID)
+	`, clippedArgs...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get agent breakdown: %w", err)
 	}
@@ -270,6 +296,12 @@ func GetIssueStats(db *sql.DB, issueID string) (*IssueStats, error) {
 //	stats, err := agent_tracking.GetSkillStats(db, "dependency-thinking", time.Now().AddDate(0, -1, 0))
 //	fmt.Printf("Uses: %d, Avg context: %.1f\n", stats.TotalUses, stats.AvgContext)
 func GetSkillStats(db *sql.DB, skillName string, since time.Time) (*SkillStats, error) {
+	return getSkillStatsBetween(db, skillName, since, time.Time{})
+}
+
+// getSkillStatsBetween is GetSkillStats for skills loaded in [since, until).
+// A zero until means no upper bound.
+func getSkillStatsBetween(db *sql.DB, skillName string, since, until time.Time) (*SkillStats, error) {
 	if db == nil {
 		return nil, fmt.Errorf("database connection is nil")
 	}
@@ -282,7 +314,7 @@ func GetSkillStats(db *sql.DB, skillName string, since time.Time) (*SkillStats,
 		Since:     since,
 	}
 
-	sinceStr := formatTime(since)
+	sinceStr, untilStr := formatTime(since), formatUntil(until)
 
 	// Get usage counts
 	var avgContext sql.NullFloat64
@@ -295,8 +327,8 @@ func GetSkillStats(db *sql.DB, skillName string, since time.Time) (*SkillStats,
 			AVG(u.context_added) as avg_context
 		FROM agent_skill_usage u
 		JOIN agent_sessions s ON u.session_id = s.session_id
-		WHERE u.skill_name = ? AND u.loaded_at >= ?
-	`, skillName, sinceStr).Scan(
+		WHERE u.skill_name = ? AND u.loaded_at >= ? AND u.loaded_at < ?
+	`, skillName, sinceStr, untilStr).Scan(
 		&stats.TotalUses, &stats.UniqueSessions, &stats.UniqueAgents,
 		&stats.TotalContext, &avgContext,
 	)
@@ -312,11 +344,11 @@ func GetSkillStats(db *sql.DB, skillName string, since time.Time) (*SkillStats,
 		SELECT s.agent_name, COUNT(*) as cnt
 		FROM agent_skill_usage u
 		JOIN agent_sessions s ON u.session_id = s.session_id
-		WHERE u.skill_name = ? AND u.loaded_at >= ?
+		WHERE u.skill_name = ? AND u.loaded_at >= ? AND u.loaded_at < ?
 		GROUP BY s.agent_name
 		ORDER BY cnt DESC
 		LIMIT 5
-	`, skillName, sinceStr)
+	`, skillName, sinceStr, untilStr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get top agents: %w", err)
 	}
@@ -339,6 +371,12 @@ func GetSkillStats(db *sql.DB, skillName string, since time.Time) (*SkillStats,
 //
 //	stats, err := agent_tracking.GetOverallStats(db, time.Now().AddDate(0, -1, 0))
 func GetOverallStats(db *sql.DB, since time.Time) (*OverallStats, error) {
+	return getOverallStatsBetween(db, since, time.Time{})
+}
+
+// getOverallStatsBetween is GetOverallStats for [since, until). A zero until
+// means no upper bound.
+func getOverallStatsBetween(db *sql.DB, since, until time.Time) (*OverallStats, error) {
 	if db == nil {
 		return nil, fmt.Errorf("database connection is nil")
 	}
@@ -347,7 +385,7 @@ func GetOverallStats(db *sql.DB, since time.Time) (*OverallStats, error) {
 		Since: since,
 	}
 
-	sinceStr := formatTime(since)
+	sinceStr, untilStr := formatTime(since), formatUntil(until)
 
 	// Get session counts
 	err := db.QueryRow(`
@@ -357,8 +395,8 @@ func GetOverallStats(db *sql.DB, since time.Time) (*OverallStats, error) {
 			COUNT(DISTINCT agent_name) as agents,
 			COALESCE(SUM(context_tokens), 0) as tokens
 		FROM agent_sessions
-		WHERE started_at >= ?
-	`, sinceStr).Scan(&stats.TotalSessions, &stats.ActiveSessions, &stats.UniqueAgents, &stats.TotalTokens)
+		WHERE started_at >= ? AND started_at < ?
+	`, sinceStr, untilStr).Scan(&stats.TotalSessions, &stats.ActiveSessions, &stats.UniqueAgents, &stats.TotalTokens)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get session counts: %w", err)
 	}
@@ -370,8 +408,8 @@ func GetOverallStats(db *sql.DB, since time.Time) (*OverallStats, error) {
 			COUNT(DISTINCT CASE WHEN completed = 1 THEN issue_id END) as completed
 		FROM agent_issue_work w
 		JOIN agent_sessions s ON w.session_id = s.session_id
-		WHERE s.started_at >= ?
-	`, sinceStr).Scan(&stats.TotalIssues, &stats.CompletedIssues)
+		WHERE s.started_at >= ? AND s.started_at < ?
+	`, sinceStr, untilStr).Scan(&stats.TotalIssues, &stats.CompletedIssues)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get issue counts: %w", err)
 	}
@@ -380,8 +418,8 @@ func GetOverallStats(db *sql.DB, since time.Time) (*OverallStats, error) {
 	err = db.QueryRow(`
 		SELECT COUNT(DISTINCT skill_name)
 		FROM agent_skill_usage
-		WHERE loaded_at >= ?
-	`, sinceStr).Scan(&stats.UniqueSkills)
+		WHERE loaded_at >= ? AND loaded_at < ?
+	`, sinceStr, untilStr).Scan(&stats.UniqueSkills)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get skill count: %w", err)
 	}
@@ -390,11 +428,11 @@ func GetOverallStats(db *sql.DB, since time.Time) (*OverallStats, error) {
 	rows, err := db.Query(`
 		SELECT agent_name, COUNT(*) as cnt
 		FROM agent_sessions
-		WHERE started_at >= ?
+		WHERE started_at >= ? AND started_at < ?
 		GROUP BY agent_name
 		ORDER BY cnt DESC
 		LIMIT 5
-	`, sinceStr)
+	`, sinceStr, untilStr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get top agents: %w", err)
 	}
@@ -408,11 +446,40 @@ func GetOverallStats(db *sql.DB, since time.Time) (*OverallStats, error) {
 		stats.TopAgents = append(stats.TopAgents, ac)
 	}
 
+	// Get top skills
+	skillRows, err := db.Query(`
+		SELECT skill_name, COUNT(*) as cnt
+		FROM agent_skill_usage
+		WHERE loaded_at >= ? AND loaded_at < ?
+		GROUP BY skill_name
+		ORDER BY cnt DESC
+		LIMIT 5
+	`, sinceStr, untilStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top skills: %w", err)
+	}
+	defer skillRows.Close()
+
+	for skillRows.Next() {
+		var sc SkillCount
+		if err := skillRows.Scan(&sc.SkillName, &sc.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan skill count: %w", err)
+		}
+		stats.TopSkills = append(stats.TopSkills, sc)
+	}
+
 	return stats, nil
 }
 
 // GetSessionDurations returns session duration statistics for visualization.
 func GetSessionDurations(db *sql.DB, agentName string, since time.Time, limit int) ([]SessionDuration, error) {
+	return getSessionDurationsBetween(db, agentName, since, time.Time{}, limit)
+}
+
+// getSessionDurationsBetween is GetSessionDurations for sessions started in
+// [since, until), with open sessions measured up to until. A zero until means
+// no upper bound.
+func getSessionDurationsBetween(db *sql.DB, agentName string, since, until time.Time, limit int) ([]SessionDuration, error) {
 	if db == nil {
 		return nil, fmt.Errorf("database connection is nil")
 	}
@@ -420,7 +487,7 @@ func GetSessionDurations(db *sql.DB, agentName string, since time.Time, limit in
 		limit = 50
 	}
 
-	sinceStr := formatTime(since)
+	sinceStr, untilStr := formatTime(since), formatUntil(until)
 
 	var query string
 	var args []interface{}
@@ -431,28 +498,28 @@ func GetSessionDurations(db *sql.DB, agentName string, since time.Time, limit in
 				session_id,
 				agent_name,
 				started_at,
-				(JULIANDAY(COALESCE(ended_at, datetime('now'))) - JULIANDAY(started_at)) * 86400 as duration_seconds,
+				(MIN(JULIANDAY(COALESCE(ended_at, datetime('now'))), JULIANDAY(?)) - JULIANDAY(started_at)) * 86400 as duration_seconds,
 				ended_at IS NOT NULL as is_completed
 			FROM agent_sessions
-			WHERE agent_name = ? AND started_at >= ?
+			WHERE agent_name = ? AND started_at >= ? AND started_at < ?
 			ORDER BY started_at DESC
 			LIMIT ?
 		`
-		args = []interface{}{agentName, sinceStr, limit}
+		args = []interface{}{untilStr, agentName, sinceStr, untilStr, limit}
 	} else {
 		query = `
 			SELECT
 				session_id,
 				agent_name,
 				started_at,
-				(JULIANDAY(COALESCE(ended_at, datetime('now'))) - JULIANDAY(started_at)) * 86400 as duration_seconds,
+				(MIN(JULIANDAY(COALESCE(ended_at, datetime('now'))), JULIANDAY(?)) - JULIANDAY(started_at)) * 86400 as duration_seconds,
 				ended_at IS NOT NULL as is_completed
 			FROM agent_sessions
-			WHERE started_at >= ?
+			WHERE started_at >= ? AND started_at < ?
 			ORDER BY started_at DESC
 			LIMIT ?
 		`
-		args = []interface{}{sinceStr, limit}
+		args = []interface{}{untilStr, sinceStr, untilStr, limit}
 	}
 
 	rows, err := db.Query(query, args...)
@@ -482,3 +549,16 @@ func GetSessionDurations(db *sql.DB, agentName string, since time.Time, limit in
 
 	return durations, nil
 }
+
+// maxStoredTime is an upper bound later than any stored timestamp. It keeps
+// windowed queries to one shape whether or not an end time is given.
+const maxStoredTime = "9999-12-31T23:59:59Z"
+
+// formatUntil formats the exclusive end of a time window, treating zero as
+// no upper bound.
+func formatUntil(until time.Time) string {
+	if until.IsZero() {
+		return maxStoredTime
+	}
+	return formatTime(until)
+}
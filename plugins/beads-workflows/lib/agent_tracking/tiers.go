@@ -0,0 +1,288 @@
+package agent_tracking
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DefaultTierPrices returns the default cost in dollars per million context tokens for each model tier.
+func DefaultTierPrices() map[string]float64 {
+	return map[string]float64{
+		"haiku":  1,
+		"sonnet": 3,
+		"opus":   15,
+	}
+}
+
+// TierStats contains outcome statistics for one model tier, optionally narrowed
+// to an issue type and agent.
+//
+// An attempt is a work entry that was completed or whose session has ended.
+// A completed work entry counts as reworked when later work was started on the
+// same issue. Tokens are the session's context tokens split evenly across its
+// work entries, and cost prices those tokens at the tier's rate.
+type TierStats struct {
+	ModelTier      string        `json:"model_tier"`
+	IssueType      string        `json:"issue_type,omitempty"`
+	AgentName      string        `json:"agent_name,omitempty"`
+	Attempts       int           `json:"attempts"`
+	Completed      int           `json:"completed"`
+	Reworked       int           `json:"reworked"`
+	CompletionRate float64       `json:"completion_rate"`
+	ReworkRate     float64       `json:"rework_rate"`
+	SuccessRate    float64       `json:"success_rate"`
+	AvgDuration    time.Duration `json:"avg_duration"`
+	AvgTokens      float64       `json:"avg_tokens"`
+	AvgCost        float64       `json:"avg_cost"`
+	CostPerSuccess float64       `json:"cost_per_success"`
+}
+
+// TierRecommendationOptions controls which history a tier recommendation uses
+// and what counts as good enough.
+type TierRecommendationOptions struct {
+	IssueType        string             // Only consider work on this issue type (empty for all)
+	AgentName        string             // Only consider work by this agent (empty for all)
+	Since            time.Time          // Only consider sessions started at or after this time
+	SuccessThreshold float64            // Minimum success rate a tier must reach (default 0.8)
+	MinAttempts      int                // Minimum attempts before a tier is trusted (default 3)
+	Prices           map[string]float64 // Dollars per million tokens per tier (default DefaultTierPrices)
+}
+
+// TierRecommendation is the suggested model tier together with the evidence for it.
+type TierRecommendation struct {
+	ModelTier string      `json:"model_tier"`
+	IssueType string      `json:"issue_type,omitempty"`
+	AgentName string      `json:"agent_name,omitempty"`
+	MeetsGoal bool        `json:"meets_goal"`
+	Reason    string      `json:"reason"`
+	Evidence  []TierStats `json:"evidence"`
+}
+
+// tierKey identifies the group a tier attempt is aggregated into.
+type tierKey struct {
+	modelTier string
+	issueType string
+	agentName string
+}
+
+// tierTotals accumulates the sums behind a TierStats' averages.
+type tierTotals struct {
+	stats   TierStats
+	seconds float64
+	tokens  float64
+}
+
+// GetTierStats compares outcomes per model tier, issue type and agent for sessions
+// started since the given time. Costs use prices in dollars per million tokens;
+// pass nil to use DefaultTierPrices.
+//
+// Example:
+//
+//	stats, err := agent_tracking.GetTierStats(db, time.Now().AddDate(0, -1, 0), nil)
+//	for _, s := range stats {
+//	    fmt.Printf("%s %s %s: %.0f%% success, $%.2f per success\n",
+//	        s.ModelTier, s.IssueType, s.AgentName, s.SuccessRate*100, s.CostPerSuccess)
+//	}
+func GetTierStats(db *sql.DB, since time.Time, prices map[string]float64) ([]TierStats, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	if prices == nil {
+		prices = DefaultTierPrices()
+	}
+
+	attempts, err := getWorkAttempts(db, since)
+	if err != nil {
+		return nil, err
+	}
+
+	return aggregateTierStats(attempts, prices, func(a workAttempt) tierKey {
+		return tierKey{modelTier: a.modelTier, issueType: a.issueType, agentName: a.agentName}
+	}), nil
+}
+
+// RecommendTier suggests the cheapest model tier whose success rate meets the threshold.
+// Tiers are ranked by cost per success, or by their price when a tier has no token
+// data. Only tiers with at least MinAttempts attempts are considered. When no tier qualifies,
+// the tier with the best success rate is returned with MeetsGoal set to false.
+//
+// Example:
+//
+//	rec, err := agent_tracking.RecommendTier(db, agent_tracking.TierRecommendationOptions{
+//	    IssueType:        "bug",
+//	    AgentName:        "beads-workflow-orchestrator",
+//	    SuccessThreshold: 0.85,
+//	})
+//	fmt.Printf("Use %s: %s\n", rec.ModelTier, rec.Reason)
+func RecommendTier(db *sql.DB, opts TierRecommendationOptions) (*TierRecommendation, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	if opts.SuccessThreshold <= 0 {
+		opts.SuccessThreshold = 0.8
+	}
+	if opts.MinAttempts <= 0 {
+		opts.MinAttempts = 3
+	}
+	if opts.Prices == nil {
+		opts.Prices = DefaultTierPrices()
+	}
+
+	attempts, err := getWorkAttempts(db, opts.Since)
+	if err != nil {
+		return nil, err
+	}
+
+	var matching []workAttempt
+	for _, a := range attempts {
+		if a.modelTier == "" {
+			continue
+		}
+		if opts.IssueType != "" && a.issueType != opts.IssueType {
+			continue
+		}
+		if opts.AgentName != "" && a.agentName != opts.AgentName {
+			continue
+		}
+		matching = append(matching, a)
+	}
+
+	evidence := aggregateTierStats(matching, opts.Prices, func(a workAttempt) tierKey {
+		return tierKey{modelTier: a.modelTier, issueType: opts.IssueType, agentName: opts.AgentName}
+	})
+	if len(evidence) == 0 {
+		return nil, fmt.Errorf("no work history with a model tier to recommend from")
+	}
+
+	rec := &TierRecommendation{
+		IssueType: opts.IssueType,
+		AgentName: opts.AgentName,
+		Evidence:  evidence,
+	}
+
+	var qualifying []TierStats
+	var best *TierStats
+	for i := range evidence {
+		s := &evidence[i]
+		if s.Attempts < opts.MinAttempts {
+			continue
+		}
+		if s.SuccessRate >= opts.SuccessThreshold {
+			qualifying = append(qualifying, *s)
+		}
+		if best == nil || s.SuccessRate > best.SuccessRate {
+			best = s
+		}
+	}
+
+	if len(qualifying) > 0 {
+		// Sessions without context tokens cost nothing, so a tier without token
+		// data would always look cheapest. Rank by tier price when any cost is unknown.
+		costsKnown := true
+		for _, s := range qualifying {
+			if s.CostPerSuccess <= 0 {
+				costsKnown = false
+			}
+		}
+		sort.SliceStable(qualifying, func(i, j int) bool {
+			if costsKnown {
+				return qualifying[i].CostPerSuccess < qualifying[j].CostPerSuccess
+			}
+			return tierPrice(opts.Prices, qualifying[i].ModelTier) < tierPrice(opts.Prices, qualifying[j].ModelTier)
+		})
+		choice := qualifying[0]
+		rec.ModelTier = choice.ModelTier
+		rec.MeetsGoal = true
+		if costsKnown {
+			rec.Reason = fmt.Sprintf("cheapest tier meeting %.0f%% success: %d of %d attempts succeeded, $%.4f per success",
+				opts.SuccessThreshold*100, choice.Completed-choice.Reworked, choice.Attempts, choice.CostPerSuccess)
+		} else {
+			rec.Reason = fmt.Sprintf("lowest-priced tier meeting %.0f%% success: %d of %d attempts succeeded, $%.2f per million tokens (no token data to compare costs)",
+				opts.SuccessThreshold*100, choice.Completed-choice.Reworked, choice.Attempts, tierPrice(opts.Prices, choice.ModelTier))
+		}
+		return rec, nil
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("no tier has at least %d attempts", opts.MinAttempts)
+	}
+	rec.ModelTier = best.ModelTier
+	rec.Reason = fmt.Sprintf("no tier meets %.0f%% success; best is %.0f%% over %d attempts",
+		opts.SuccessThreshold*100, best.SuccessRate*100, best.Attempts)
+
+	return rec, nil
+}
+
+// aggregateTierStats groups attempts by the key built from each attempt and computes
+// the rates and averages. Results are ordered by tier, issue type and agent.
+func aggregateTierStats(attempts []workAttempt, prices map[string]float64, key func(workAttempt) tierKey) []TierStats {
+	groups := make(map[tierKey]*tierTotals)
+	var order []tierKey
+
+	for _, a := range attempts {
+		k := key(a)
+		t, ok := groups[k]
+		if !ok {
+			t = &tierTotals{stats: TierStats{ModelTier: k.modelTier, IssueType: k.issueType, AgentName: k.agentName}}
+			groups[k] = t
+			order = append(order, k)
+		}
+		t.stats.Attempts++
+		t.tokens += a.tokens
+		if a.completed {
+			t.stats.Completed++
+			t.seconds += a.seconds
+			if a.reworked {
+				t.stats.Reworked++
+			}
+		}
+	}
+
+	stats := make([]TierStats, 0, len(order))
+	for _, k := range order {
+		t := groups[k]
+		s := t.stats
+		s.CompletionRate = float64(s.Completed) / float64(s.Attempts)
+		s.SuccessRate = float64(s.Completed-s.Reworked) / float64(s.Attempts)
+		if s.Completed > 0 {
+			s.ReworkRate = float64(s.Reworked) / float64(s.Completed)
+			s.AvgDuration = secondsToDuration(t.seconds / float64(s.Completed))
+		}
+		s.AvgTokens = t.tokens / float64(s.Attempts)
+		totalCost := t.tokens * tierPrice(prices, s.ModelTier) / 1e6
+		s.AvgCost = totalCost / float64(s.Attempts)
+		if successes := s.Completed - s.Reworked; successes > 0 {
+			s.CostPerSuccess = totalCost / float64(successes)
+		}
+		stats = append(stats, s)
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].ModelTier != stats[j].ModelTier {
+			return stats[i].ModelTier < stats[j].ModelTier
+		}
+		if stats[i].IssueType != stats[j].IssueType {
+			return stats[i].IssueType < stats[j].IssueType
+		}
+		return stats[i].AgentName < stats[j].AgentName
+	})
+
+	return stats
+}
+
+// tierPrice returns the price for a tier. Tiers without a configured price are
+// charged the highest configured price so they are never mistaken for the cheapest.
+func tierPrice(prices map[string]float64, tier string) float64 {
+	if p, ok := prices[tier]; ok {
+		return p
+	}
+	highest := 0.0
+	for _, p := range prices {
+		if p > highest {
+			highest = p
+		}
+	}
+	return highest
+}
@@ -0,0 +1,104 @@
+package agent_tracking
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// seedTierAttempts records n bug attempts on a tier, the first completed of
+// which are completed. Each session uses one million context tokens.
+func seedTierAttempts(t *testing.T, db *sql.DB, tier string, n, completed int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		issueID := fmt.Sprintf("%s-%d", tier, i)
+		mustExec(t, db, `INSERT INTO issues (id, title, issue_type) VALUES (?, ?, 'bug')`, issueID, issueID)
+		sessionID, err := StartSession(db, "agent", "/ws", tier)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := UpdateSessionTokens(db, sessionID, 1000000); err != nil {
+			t.Fatal(err)
+		}
+		workID, err := RecordWork(db, sessionID, issueID, "agent", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i < completed {
+			if err := CompleteWork(db, workID, ""); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := EndSession(db, sessionID, ExitCompleted); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGetTierStats(t *testing.T) {
+	db := openTestDB(t)
+	seedTierAttempts(t, db, "haiku", 4, 2)
+	seedTierAttempts(t, db, "sonnet", 2, 2)
+
+	stats, err := GetTierStats(db, time.Time{}, nil)
+	if err != nil {
+		t.Fatalf("GetTierStats failed: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("got %d tier groups, want 2: %+v", len(stats), stats)
+	}
+	haiku := stats[0]
+	if haiku.ModelTier != "haiku" || haiku.IssueType != "bug" || haiku.Attempts != 4 || haiku.Completed != 2 {
+		t.Errorf("haiku = %+v", haiku)
+	}
+	if haiku.SuccessRate != 0.5 || haiku.AvgTokens != 1000000 || haiku.AvgCost != 1 || haiku.CostPerSuccess != 2 {
+		t.Errorf("haiku rates = %.2f success, %.0f tokens, $%.2f avg, $%.2f per success",
+			haiku.SuccessRate, haiku.AvgTokens, haiku.AvgCost, haiku.CostPerSuccess)
+	}
+}
+
+func TestRecommendTier(t *testing.T) {
+	db := openTestDB(t)
+	seedTierAttempts(t, db, "haiku", 5, 2)
+	seedTierAttempts(t, db, "sonnet", 5, 5)
+	seedTierAttempts(t, db, "opus", 5, 5)
+
+	rec, err := RecommendTier(db, TierRecommendationOptions{IssueType: "bug"})
+	if err != nil {
+		t.Fatalf("RecommendTier failed: %v", err)
+	}
+	if rec.ModelTier != "sonnet" || !rec.MeetsGoal || len(rec.Evidence) != 3 {
+		t.Errorf("recommendation = %s (meets goal %v, %d tiers), want sonnet", rec.ModelTier, rec.MeetsGoal, len(rec.Evidence))
+	}
+
+	// At 30% haiku qualifies and is cheaper per success ($2.50 against $3).
+	rec, err = RecommendTier(db, TierRecommendationOptions{IssueType: "bug", SuccessThreshold: 0.3})
+	if err != nil {
+		t.Fatalf("RecommendTier failed: %v", err)
+	}
+	if rec.ModelTier != "haiku" {
+		t.Errorf("recommendation = %s, want haiku", rec.ModelTier)
+	}
+
+	rec, err = RecommendTier(db, TierRecommendationOptions{IssueType: "bug", MinAttempts: 10})
+	if err == nil {
+		t.Errorf("expected an error when no tier has enough attempts, got %+v", rec)
+	}
+}
+
+func TestRecommendTierWithoutTokenData(t *testing.T) {
+	db := openTestDB(t)
+	seedTierAttempts(t, db, "sonnet", 5, 5)
+	seedTierAttempts(t, db, "opus", 5, 5)
+	mustExec(t, db, `UPDATE agent_sessions SET context_tokens = 0 WHERE model_tier = 'opus'`)
+
+	// Opus costs $0 per success without tokens; its price ranks it above sonnet.
+	rec, err := RecommendTier(db, TierRecommendationOptions{IssueType: "bug"})
+	if err != nil {
+		t.Fatalf("RecommendTier failed: %v", err)
+	}
+	if rec.ModelTier != "sonnet" || !rec.MeetsGoal {
+		t.Errorf("recommendation = %s (%s), want sonnet", rec.ModelTier, rec.Reason)
+	}
+}
@@ -0,0 +1,416 @@
+package agent_tracking
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// toolTimeLayout stores tool call times with milliseconds, since most calls
+// take less than a second. Times compared against stored tool call times must
+// use it too: RFC3339 without fractional seconds sorts after every call in the
+// same second.
+const toolTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// formatToolTime formats a time for storage in or comparison with tool call times.
+func formatToolTime(t time.Time) string {
+	return t.UTC().Format(toolTimeLayout)
+}
+
+// ToolCall is one tool invocation (bash, edit, search, bd ...) during a session,
+// optionally while working on a work item. Success and Duration are only
+// meaningful once the call has ended.
+type ToolCall struct {
+	CallID    string        `json:"call_id"`
+	SessionID string        `json:"session_id"`
+	WorkID    string        `json:"work_id,omitempty"`
+	ToolName  string        `json:"tool_name"`
+	StartedAt time.Time     `json:"started_at"`
+	EndedAt   *time.Time    `json:"ended_at,omitempty"`
+	Duration  time.Duration `json:"duration"`
+	Success   bool          `json:"success"`
+	BytesIn   int           `json:"bytes_in"`
+	BytesOut  int           `json:"bytes_out"`
+	TokensIn  int           `json:"tokens_in"`
+	TokensOut int           `json:"tokens_out"`
+}
+
+// ToolCallResult is the outcome of a tool call, passed to EndToolCall.
+type ToolCallResult struct {
+	Success   bool
+	BytesIn   int // Size of the tool input
+	BytesOut  int // Size of the tool output
+	TokensIn  int
+	TokensOut int
+}
+
+// ToolStats contains aggregate statistics for a tool. Failure rate and
+// durations only count calls that have ended.
+type ToolStats struct {
+	ToolName      string        `json:"tool_name"`
+	Calls         int           `json:"calls"`
+	Ended         int           `json:"ended"`
+	Failed        int           `json:"failed"`
+	FailureRate   float64       `json:"failure_rate"`
+	TotalDuration time.Duration `json:"total_duration"`
+	AvgDuration   time.Duration `json:"avg_duration"`
+	MaxDuration   time.Duration `json:"max_duration"`
+	BytesIn       int           `json:"bytes_in"`
+	BytesOut      int           `json:"bytes_out"`
+	TokensIn      int           `json:"tokens_in"`
+	TokensOut     int           `json:"tokens_out"`
+}
+
+// ToolShare is a tool's share of an agent's tool calls.
+type ToolShare struct {
+	ToolName string  `json:"tool_name"`
+	Calls    int     `json:"calls"`
+	Share    float64 `json:"share"`
+}
+
+// AgentToolMix is how an agent's tool calls split across tools.
+type AgentToolMix struct {
+	AgentName string      `json:"agent_name"`
+	Calls     int         `json:"calls"`
+	Tools     []ToolShare `json:"tools"`
+}
+
+// StartToolCall records the start of a tool call and returns its ID. workID is
+// optional and must belong to the session.
+//
+// Example:
+//
+//	callID, err := agent_tracking.StartToolCall(db, sessionID, workID, "Bash")
+//	out, runErr := exec.Command("go", "test", "./...").CombinedOutput()
+//	err = agent_tracking.EndToolCall(db, callID, agent_tracking.ToolCallResult{
+//	    Success:  runErr == nil,
+//	    BytesOut: len(out),
+//	})
+func StartToolCall(db *sql.DB, sessionID, workID, toolName string) (string, error) {
+	if db == nil {
+		return "", fmt.Errorf("database connection is nil")
+	}
+	if sessionID == "" {
+		return "", fmt.Errorf("session ID is required")
+	}
+	if toolName == "" {
+		return "", fmt.Errorf("tool name is required")
+	}
+	if workID != "" {
+		if _, err := getWorkIssueForSession(db, sessionID, workID); err != nil {
+			return "", err
+		}
+	}
+
+	callID := generateID()
+
+	_, err := db.Exec(`
+		INSERT INTO agent_tool_calls (call_id, session_id, work_id, tool_name, started_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, callID, sessionID, workID, toolName, formatToolTime(time.Now()))
+	if err != nil {
+		return "", fmt.Errorf("failed to start tool call: %w", err)
+	}
+
+	return callID, nil
+}
+
+// EndToolCall records the outcome of a tool call started with StartToolCall.
+//
+// Example:
+//
+//	err := agent_tracking.EndToolCall(db, callID, agent_tracking.ToolCallResult{Success: true, TokensOut: 1200})
+func EndToolCall(db *sql.DB, callID string, result ToolCallResult) error {
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+	if callID == "" {
+		return fmt.Errorf("call ID is required")
+	}
+
+	var startedAtStr string
+	err := db.QueryRow(`SELECT started_at FROM agent_tool_calls WHERE call_id = ?`, callID).Scan(&startedAtStr)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("tool call not found: %s", callID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get tool call: %w", err)
+	}
+	startedAt, err := parseTime(startedAtStr)
+	if err != nil {
+		return fmt.Errorf("failed to parse started_at: %w", err)
+	}
+
+	endedAt := time.Now()
+	if endedAt.Before(startedAt) {
+		endedAt = startedAt
+	}
+
+	_, err = db.Exec(`
+		UPDATE agent_tool_calls
+		SET ended_at = ?, duration_ms = ?, success = ?, bytes_in = ?, bytes_out = ?, tokens_in = ?, tokens_out = ?
+		WHERE call_id = ?
+	`, formatToolTime(endedAt), endedAt.Sub(startedAt).Milliseconds(), result.Success,
+		result.BytesIn, result.BytesOut, result.TokensIn, result.TokensOut, callID)
+	if err != nil {
+		return fmt.Errorf("failed to end tool call: %w", err)
+	}
+
+	return nil
+}
+
+// RecordToolCall records a tool call that has already finished and returns its
+// ID, for callers that learn about calls after the fact. StartedAt is required;
+// EndedAt defaults to StartedAt plus Duration.
+//
+// Example:
+//
+//	callID, err := agent_tracking.RecordToolCall(db, agent_tracking.ToolCall{
+//	    SessionID: sessionID,
+//	    ToolName:  "Grep",
+//	    StartedAt: start,
+//	    Duration:  time.Since(start),
+//	    Success:   true,
+//	})
+func RecordToolCall(db *sql.DB, call ToolCall) (string, error) {
+	if db == nil {
+		return "", fmt.Errorf("database connection is nil")
+	}
+	if call.SessionID == "" {
+		return "", fmt.Errorf("session ID is required")
+	}
+	if call.ToolName == "" {
+		return "", fmt.Errorf("tool name is required")
+	}
+	if call.StartedAt.IsZero() {
+		return "", fmt.Errorf("start time is required")
+	}
+	if call.WorkID != "" {
+		if _, err := getWorkIssueForSession(db, call.SessionID, call.WorkID); err != nil {
+			return "", err
+		}
+	}
+
+	endedAt := call.StartedAt.Add(call.Duration)
+	if call.EndedAt != nil {
+		endedAt = *call.EndedAt
+	}
+	if endedAt.Before(call.StartedAt) {
+		return "", fmt.Errorf("tool call cannot end before it starts")
+	}
+
+	callID := generateID()
+
+	_, err := db.Exec(`
+		INSERT INTO agent_tool_calls (call_id, session_id, work_id, tool_name, started_at, ended_at, duration_ms,
+		                              success, bytes_in, bytes_out, tokens_in, tokens_out)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, callID, call.SessionID, call.WorkID, call.ToolName,
+		formatToolTime(call.StartedAt), formatToolTime(endedAt),
+		endedAt.Sub(call.StartedAt).Milliseconds(), call.Success,
+		call.BytesIn, call.BytesOut, call.TokensIn, call.TokensOut)
+	if err != nil {
+		return "", fmt.Errorf("failed to record tool call: %w", err)
+	}
+
+	return callID, nil
+}
+
+// ListToolCallsBySession returns the tool calls of a session, oldest first.
+//
+// Example:
+//
+//	calls, err := agent_tracking.ListToolCallsBySession(db, sessionID)
+//	for _, c := range calls {
+//	    fmt.Printf("%s %v success=%v\n", c.ToolName, c.Duration, c.Success)
+//	}
+func ListToolCallsBySession(db *sql.DB, sessionID string) ([]*ToolCall, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	if sessionID == "" {
+		return nil, fmt.Errorf("session ID is required")
+	}
+
+	rows, err := db.Query(`
+		SELECT call_id, session_id, COALESCE(work_id, ''), tool_name, started_at, ended_at,
+		       COALESCE(duration_ms, 0), COALESCE(success, 0),
+		       bytes_in, bytes_out, tokens_in, tokens_out
+		FROM agent_tool_calls
+		WHERE session_id = ?
+		ORDER BY started_at, rowid
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tool calls: %w", err)
+	}
+	defer rows.Close()
+
+	var calls []*ToolCall
+	for rows.Next() {
+		var c ToolCall
+		var startedAtStr string
+		var endedAtStr sql.NullString
+		var durationMs int64
+		err := rows.Scan(&c.CallID, &c.SessionID, &c.WorkID, &c.ToolName, &startedAtStr, &endedAtStr,
+			&durationMs, &c.Success, &c.BytesIn, &c.BytesOut, &c.TokensIn, &c.TokensOut)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tool call: %w", err)
+		}
+
+		c.StartedAt, err = parseTime(startedAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse started_at: %w", err)
+		}
+		c.EndedAt, err = parseNullableTime(endedAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ended_at: %w", err)
+		}
+		c.Duration = time.Duration(durationMs) * time.Millisecond
+
+		calls = append(calls, &c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tool calls: %w", err)
+	}
+
+	return calls, nil
+}
+
+// GetToolStats returns statistics per tool for calls started since a given time,
+// slowest on average first. Pass an agent name to only count that agent's calls.
+//
+// Example:
+//
+//	stats, err := agent_tracking.GetToolStats(db, "", time.Now().AddDate(0, 0, -7))
+//	for _, s := range stats {
+//	    fmt.Printf("%s: %d calls, avg %v, max %v, %.0f%% failed\n",
+//	        s.ToolName, s.Calls, s.AvgDuration, s.MaxDuration, s.FailureRate*100)
+//	}
+func GetToolStats(db *sql.DB, agentName string, since time.Time) ([]ToolStats, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	rows, err := db.Query(`
+		SELECT
+			t.tool_name,
+			COUNT(*) as calls,
+			COUNT(t.ended_at) as ended,
+			SUM(CASE WHEN t.ended_at IS NOT NULL AND NOT t.success THEN 1 ELSE 0 END) as failed,
+			COALESCE(SUM(t.duration_ms), 0) as total_ms,
+			COALESCE(MAX(t.duration_ms), 0) as max_ms,
+			COALESCE(SUM(t.bytes_in), 0), COALESCE(SUM(t.bytes_out), 0),
+			COALESCE(SUM(t.tokens_in), 0), COALESCE(SUM(t.tokens_out), 0)
+		FROM agent_tool_calls t
+		JOIN agent_sessions s ON s.session_id = t.session_id
+		WHERE t.started_at >= ? AND (? = '' OR s.agent_name = ?)
+		GROUP BY t.tool_name
+	`, formatToolTime(since), agentName, agentName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tool stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []ToolStats
+	for rows.Next() {
+		var s ToolStats
+		var totalMs, maxMs int64
+		err := rows.Scan(&s.ToolName, &s.Calls, &s.Ended, &s.Failed, &totalMs, &maxMs,
+			&s.BytesIn, &s.BytesOut, &s.TokensIn, &s.TokensOut)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tool stats: %w", err)
+		}
+
+		s.TotalDuration = time.Duration(totalMs) * time.Millisecond
+		s.MaxDuration = time.Duration(maxMs) * time.Millisecond
+		if s.Ended > 0 {
+			s.AvgDuration = s.TotalDuration / time.Duration(s.Ended)
+			s.FailureRate = float64(s.Failed) / float64(s.Ended)
+		}
+		stats = append(stats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tool stats: %w", err)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].AvgDuration != stats[j].AvgDuration {
+			return stats[i].AvgDuration > stats[j].AvgDuration
+		}
+		return stats[i].ToolName < stats[j].ToolName
+	})
+
+	return stats, nil
+}
+
+// GetToolMix returns each agent's tool calls since a given time split by tool,
+// agents with the most calls first and tools by share.
+//
+// Example:
+//
+//	mix, err := agent_tracking.GetToolMix(db, time.Now().AddDate(0, 0, -7))
+//	for _, m := range mix {
+//	    for _, t := range m.Tools {
+//	        fmt.Printf("%s %s: %.0f%%\n", m.AgentName, t.ToolName, t.Share*100)
+//	    }
+//	}
+func GetToolMix(db *sql.DB, since time.Time) ([]AgentToolMix, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	rows, err := db.Query(`
+		SELECT s.agent_name, t.tool_name, COUNT(*)
+		FROM agent_tool_calls t
+		JOIN agent_sessions s ON s.session_id = t.session_id
+		WHERE t.started_at >= ?
+		GROUP BY s.agent_name, t.tool_name
+	`, formatToolTime(since))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tool mix: %w", err)
+	}
+	defer rows.Close()
+
+	agents := make(map[string]*AgentToolMix)
+	for rows.Next() {
+		var agentName, toolName string
+		var calls int
+		if err := rows.Scan(&agentName, &toolName, &calls); err != nil {
+			return nil, fmt.Errorf("failed to scan tool mix: %w", err)
+		}
+		mix, ok := agents[agentName]
+		if !ok {
+			mix = &AgentToolMix{AgentName: agentName}
+			agents[agentName] = mix
+		}
+		mix.Calls += calls
+		mix.Tools = append(mix.Tools, ToolShare{ToolName: toolName, Calls: calls})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tool mix: %w", err)
+	}
+
+	result := make([]AgentToolMix, 0, len(agents))
+	for _, mix := range agents {
+		for i := range mix.Tools {
+			mix.Tools[i].Share = float64(mix.Tools[i].Calls) / float64(mix.Calls)
+		}
+		sort.Slice(mix.Tools, func(i, j int) bool {
+			if mix.Tools[i].Calls != mix.Tools[j].Calls {
+				return mix.Tools[i].Calls > mix.Tools[j].Calls
+			}
+			return mix.Tools[i].ToolName < mix.Tools[j].ToolName
+		})
+		result = append(result, *mix)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Calls != result[j].Calls {
+			return result[i].Calls > result[j].Calls
+		}
+		return result[i].AgentName < result[j].AgentName
+	})
+
+	return result, nil
+}
@@ -0,0 +1,117 @@
+package agent_tracking
+
+import (
+	"testing"
+	"time"
+)
+
+func TestToolCallLifecycle(t *testing.T) {
+	db := openTestDB(t)
+	sessionID, err := StartSession(db, "orchestrator", "/ws", "opus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	workID, err := RecordWork(db, sessionID, "agents-1", "orchestrator", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	callID, err := StartToolCall(db, sessionID, workID, "Bash")
+	if err != nil {
+		t.Fatalf("StartToolCall failed: %v", err)
+	}
+	if err := EndToolCall(db, callID, ToolCallResult{Success: false, BytesIn: 20, BytesOut: 300}); err != nil {
+		t.Fatalf("EndToolCall failed: %v", err)
+	}
+	if err := EndToolCall(db, "no-such-call", ToolCallResult{Success: true}); err == nil {
+		t.Error("EndToolCall ended an unknown call")
+	}
+
+	calls, err := ListToolCallsBySession(db, sessionID)
+	if err != nil {
+		t.Fatalf("ListToolCallsBySession failed: %v", err)
+	}
+	if len(calls) != 1 {
+		t.Fatalf("calls = %+v", calls)
+	}
+	c := calls[0]
+	if c.WorkID != workID || c.ToolName != "Bash" || c.Success || c.EndedAt == nil || c.BytesOut != 300 {
+		t.Errorf("call = %+v", c)
+	}
+
+	if _, err := StartToolCall(db, sessionID, "no-such-work", "Bash"); err == nil {
+		t.Error("StartToolCall accepted work from no session")
+	}
+	start := time.Now()
+	if _, err := RecordToolCall(db, ToolCall{SessionID: sessionID, ToolName: "Edit", StartedAt: start, Duration: -time.Second}); err == nil {
+		t.Error("RecordToolCall accepted a call ending before it starts")
+	}
+}
+
+func TestToolStatsIncludeCallsInTheSinceSecond(t *testing.T) {
+	db := openTestDB(t)
+	since := time.Date(2026, 9, 14, 9, 0, 0, 0, time.UTC)
+
+	orchestrator, err := StartSession(db, "orchestrator", "/ws", "opus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reviewer, err := StartSession(db, "reviewer", "/ws", "haiku")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record := func(sessionID, tool string, start time.Time, d time.Duration, success bool) {
+		t.Helper()
+		_, err := RecordToolCall(db, ToolCall{
+			SessionID: sessionID, ToolName: tool, StartedAt: start, Duration: d, Success: success, TokensOut: 10,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 250ms after since, in the same second: stored as 09:00:00.250Z.
+	record(orchestrator, "Bash", since.Add(250*time.Millisecond), 2*time.Second, true)
+	record(orchestrator, "Bash", since.Add(time.Minute), 4*time.Second, false)
+	record(orchestrator, "Grep", since.Add(2*time.Minute), 120*time.Millisecond, true)
+	record(reviewer, "Read", since.Add(3*time.Minute), 40*time.Millisecond, true)
+	// Before since; never counted.
+	record(orchestrator, "Bash", since.Add(-time.Millisecond), time.Hour, true)
+
+	stats, err := GetToolStats(db, "", since)
+	if err != nil {
+		t.Fatalf("GetToolStats failed: %v", err)
+	}
+	if len(stats) != 3 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	bash := stats[0]
+	if bash.ToolName != "Bash" || bash.Calls != 2 || bash.Failed != 1 || bash.FailureRate != 0.5 {
+		t.Errorf("bash = %+v, want both calls since 09:00:00", bash)
+	}
+	if bash.AvgDuration != 3*time.Second || bash.MaxDuration != 4*time.Second || bash.TokensOut != 20 {
+		t.Errorf("bash durations = avg %v, max %v, tokens %d", bash.AvgDuration, bash.MaxDuration, bash.TokensOut)
+	}
+	if stats[1].ToolName != "Grep" || stats[1].AvgDuration != 120*time.Millisecond {
+		t.Errorf("second slowest = %+v, want Grep with millisecond durations", stats[1])
+	}
+
+	mine, err := GetToolStats(db, "reviewer", since)
+	if err != nil {
+		t.Fatalf("GetToolStats failed: %v", err)
+	}
+	if len(mine) != 1 || mine[0].ToolName != "Read" {
+		t.Errorf("reviewer stats = %+v", mine)
+	}
+
+	mix, err := GetToolMix(db, since)
+	if err != nil {
+		t.Fatalf("GetToolMix failed: %v", err)
+	}
+	if len(mix) != 2 || mix[0].AgentName != "orchestrator" || mix[0].Calls != 3 {
+		t.Fatalf("mix = %+v", mix)
+	}
+	if top := mix[0].Tools[0]; top.ToolName != "Bash" || top.Calls != 2 || top.Share != 2.0/3 {
+		t.Errorf("orchestrator's top tool = %+v", top)
+	}
+}
@@ -0,0 +1,232 @@
+package agent_tracking
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// TraceOptions selects the sessions to export. AgentName, Since and Limit filter
+// sessions the same way as GetSessionDurations.
+type TraceOptions struct {
+	AgentName string    // Only sessions of this agent; subagent sessions are included regardless
+	Since     time.Time // Only sessions started at or after this time
+	Limit     int       // Most recent sessions to export (default 50)
+	Now       time.Time // End time used for sessions and work still in progress (default time.Now())
+}
+
+// TraceEvent is one event in the Chrome Trace Event Format.
+type TraceEvent struct {
+	Name  string                 `json:"name"`
+	Cat   string                 `json:"cat,omitempty"`
+	Ph    string                 `json:"ph"`
+	Ts    int64                  `json:"ts"`
+	Dur   *int64                 `json:"dur,omitempty"`
+	Pid   int                    `json:"pid"`
+	Tid   int                    `json:"tid"`
+	Scope string                 `json:"s,omitempty"`
+	Args  map[string]interface{} `json:"args,omitempty"`
+}
+
+// Trace is a Chrome Trace Event Format document.
+type Trace struct {
+	TraceEvents     []TraceEvent `json:"traceEvents"`
+	DisplayTimeUnit string       `json:"displayTimeUnit"`
+}
+
+// BuildTrace exports sessions, their work and skill loads as a trace. Each
+// top-level session is a process track, with the session as a slice on its first
+// thread, work items as slices nested inside it and skill loads as instant
+// events carrying context_added. Subagent sessions recorded with
+// SetParentSession become further threads of their top-level session's process.
+//
+// Example:
+//
+//	trace, err := agent_tracking.BuildTrace(db, agent_tracking.TraceOptions{
+//	    Since: time.Now().AddDate(0, 0, -1),
+//	})
+func BuildTrace(db *sql.DB, opts TraceOptions) (*Trace, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	durations, err := GetSessionDurations(db, opts.AgentName, opts.Since, opts.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	trace := &Trace{TraceEvents: []TraceEvent{}, DisplayTimeUnit: "ms"}
+	seen := make(map[string]bool)
+
+	// GetSessionDurations returns the newest sessions first; number tracks oldest first.
+	pid := 0
+	for i := len(durations) - 1; i >= 0; i-- {
+		sessionID := durations[i].SessionID
+		if seen[sessionID] {
+			// Already exported as a subagent of an earlier session.
+			continue
+		}
+		pid++
+		tid := 0
+		if err := appendSessionTrace(db, trace, sessionID, pid, &tid, "", seen, opts.Now); err != nil {
+			return nil, err
+		}
+	}
+
+	return trace, nil
+}
+
+// WriteTrace writes a trace as JSON, ready to open in Perfetto or chrome://tracing.
+//
+// Example:
+//
+//	f, err := os.Create("agents.trace.json")
+//	if err != nil {
+//	    return err
+//	}
+//	defer f.Close()
+//	err = agent_tracking.WriteTrace(f, trace)
+func WriteTrace(w io.Writer, trace *Trace) error {
+	if trace == nil {
+		return fmt.Errorf("trace is required")
+	}
+
+	if err := json.NewEncoder(w).Encode(trace); err != nil {
+		return fmt.Errorf("failed to write trace: %w", err)
+	}
+	return nil
+}
+
+// appendSessionTrace adds a session and, recursively, its subagent sessions to
+// the trace. Each session gets the next thread ID in the process.
+func appendSessionTrace(db *sql.DB, trace *Trace, sessionID string, pid int, tid *int, parentAgent string, seen map[string]bool, now time.Time) error {
+	if seen[sessionID] {
+		return nil
+	}
+	seen[sessionID] = true
+
+	session, err := GetSession(db, sessionID)
+	if err != nil {
+		return err
+	}
+	work, err := ListWorkBySession(db, sessionID)
+	if err != nil {
+		return err
+	}
+	skills, err := ListSkillUsageBySession(db, sessionID)
+	if err != nil {
+		return err
+	}
+
+	*tid++
+	thread := *tid
+	end := now
+	if session.EndedAt != nil {
+		end = *session.EndedAt
+	}
+
+	if parentAgent == "" {
+		trace.TraceEvents = append(trace.TraceEvents,
+			TraceEvent{Name: "process_name", Ph: "M", Pid: pid, Args: map[string]interface{}{
+				"name": fmt.Sprintf("%s (%s)", session.AgentName, shortID(session.SessionID)),
+			}},
+			TraceEvent{Name: "process_sort_index", Ph: "M", Pid: pid, Args: map[string]interface{}{"sort_index": pid}},
+		)
+	}
+	threadName := session.AgentName
+	if parentAgent != "" {
+		threadName = fmt.Sprintf("%s (subagent of %s)", session.AgentName, parentAgent)
+	}
+	trace.TraceEvents = append(trace.TraceEvents,
+		TraceEvent{Name: "thread_name", Ph: "M", Pid: pid, Tid: thread, Args: map[string]interface{}{"name": threadName}},
+		TraceEvent{Name: "thread_sort_index", Ph: "M", Pid: pid, Tid: thread, Args: map[string]interface{}{"sort_index": thread}},
+	)
+
+	sessionArgs := map[string]interface{}{
+		"session_id":     session.SessionID,
+		"workspace_path": session.WorkspacePath,
+		"context_tokens": session.ContextTokens,
+	}
+	if session.ModelTier != "" {
+		sessionArgs["model_tier"] = session.ModelTier
+	}
+	if session.ExitReason != "" {
+		sessionArgs["exit_reason"] = session.ExitReason
+	}
+	trace.TraceEvents = append(trace.TraceEvents,
+		traceSlice(session.AgentName, "session", session.StartedAt, end, pid, thread, sessionArgs))
+
+	for _, w := range work {
+		workEnd := end
+		if w.EndedAt != nil {
+			workEnd = *w.EndedAt
+		}
+		// Keep work inside the session slice so it nests.
+		if workEnd.After(end) {
+			workEnd = end
+		}
+		args := map[string]interface{}{
+			"work_id":   w.WorkID,
+			"completed": w.Completed,
+		}
+		if w.DecisionRationale != "" {
+			args["rationale"] = w.DecisionRationale
+		}
+		if w.WorkNotes != "" {
+			args["notes"] = w.WorkNotes
+		}
+		trace.TraceEvents = append(trace.TraceEvents, traceSlice(w.IssueID, "work", w.StartedAt, workEnd, pid, thread, args))
+	}
+
+	for _, s := range skills {
+		args := map[string]interface{}{"context_added": s.ContextAdded}
+		if s.UsedForIssueID != "" {
+			args["issue_id"] = s.UsedForIssueID
+		}
+		trace.TraceEvents = append(trace.TraceEvents, TraceEvent{
+			Name:  s.SkillName,
+			Cat:   "skill",
+			Ph:    "i",
+			Ts:    s.LoadedAt.UnixMicro(),
+			Pid:   pid,
+			Tid:   thread,
+			Scope: "t",
+			Args:  args,
+		})
+	}
+
+	children, err := ListSubagentSessions(db, sessionID)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		if err := appendSessionTrace(db, trace, child, pid, tid, session.AgentName, seen, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// traceSlice builds a complete ("X") event between two times.
+func traceSlice(name, cat string, start, end time.Time, pid, tid int, args map[string]interface{}) TraceEvent {
+	dur := end.Sub(start).Microseconds()
+	if dur < 0 {
+		dur = 0
+	}
+	return TraceEvent{
+		Name: name,
+		Cat:  cat,
+		Ph:   "X",
+		Ts:   start.UnixMicro(),
+		Dur:  &dur,
+		Pid:  pid,
+		Tid:  tid,
+		Args: args,
+	}
+}
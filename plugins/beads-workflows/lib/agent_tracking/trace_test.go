@@ -0,0 +1,92 @@
+package agent_tracking
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestBuildTraceNestsWorkAndSubagents(t *testing.T) {
+	db := openTestDB(t)
+	start := time.Date(2026, 9, 14, 9, 0, 0, 0, time.UTC)
+	now := start.Add(2 * time.Hour)
+
+	parent, err := StartSession(db, "orchestrator", "/ws", "opus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	workID, err := RecordWork(db, parent, "agents-1", "orchestrator", "unblocks two issues")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordSkillUsage(db, parent, "dependency-thinking", "agents-1", 400); err != nil {
+		t.Fatal(err)
+	}
+	child, err := StartSession(db, "reviewer", "/ws", "haiku")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetParentSession(db, child, parent); err != nil {
+		t.Fatal(err)
+	}
+	if err := EndSession(db, child, ExitCompleted); err != nil {
+		t.Fatal(err)
+	}
+	mustExec(t, db, `UPDATE agent_sessions SET started_at = ? WHERE session_id = ?`, formatTime(start), parent)
+	mustExec(t, db, `UPDATE agent_issue_work SET started_at = ? WHERE work_id = ?`, formatTime(start.Add(10*time.Minute)), workID)
+	mustExec(t, db, `UPDATE agent_skill_usage SET loaded_at = ?`, formatTime(start.Add(5*time.Minute)))
+	mustExec(t, db, `UPDATE agent_sessions SET started_at = ?, ended_at = ? WHERE session_id = ?`,
+		formatTime(start.Add(30*time.Minute)), formatTime(start.Add(45*time.Minute)), child)
+
+	trace, err := BuildTrace(db, TraceOptions{AgentName: "orchestrator", Since: start.Add(-time.Hour), Now: now})
+	if err != nil {
+		t.Fatalf("BuildTrace failed: %v", err)
+	}
+
+	var b bytes.Buffer
+	if err := WriteTrace(&b, trace); err != nil {
+		t.Fatalf("WriteTrace failed: %v", err)
+	}
+	var decoded Trace
+	if err := json.Unmarshal(b.Bytes(), &decoded); err != nil {
+		t.Fatalf("trace isn't valid JSON: %v", err)
+	}
+
+	slices := make(map[string]TraceEvent)
+	threads := make(map[int]string)
+	var skill *TraceEvent
+	for i, e := range decoded.TraceEvents {
+		if e.Pid != 1 {
+			t.Errorf("event %+v outside the orchestrator's process", e)
+		}
+		switch {
+		case e.Ph == "X":
+			slices[e.Cat+":"+e.Name] = e
+		case e.Name == "thread_name":
+			threads[e.Tid] = e.Args["name"].(string)
+		case e.Ph == "i":
+			skill = &decoded.TraceEvents[i]
+		}
+	}
+
+	if threads[1] != "orchestrator" || threads[2] != "reviewer (subagent of orchestrator)" {
+		t.Errorf("threads = %v", threads)
+	}
+
+	session := slices["session:orchestrator"]
+	if session.Tid != 1 || session.Ts != start.UnixMicro() || *session.Dur != (2*time.Hour).Microseconds() {
+		t.Errorf("open session slice = %+v, want it to run from its start to Now", session)
+	}
+	work := slices["work:agents-1"]
+	if work.Tid != 1 || work.Ts != start.Add(10*time.Minute).UnixMicro() || work.Ts+*work.Dur != now.UnixMicro() {
+		t.Errorf("work slice = %+v, want it nested in the session", work)
+	}
+	sub := slices["session:reviewer"]
+	if sub.Tid != 2 || *sub.Dur != (15*time.Minute).Microseconds() || sub.Args["exit_reason"] != "completed" {
+		t.Errorf("subagent slice = %+v", sub)
+	}
+	if skill == nil || skill.Name != "dependency-thinking" || skill.Args["context_added"] != float64(400) || skill.Scope != "t" {
+		t.Errorf("skill event = %+v", skill)
+	}
+}
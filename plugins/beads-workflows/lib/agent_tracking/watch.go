@@ -0,0 +1,271 @@
+package agent_tracking
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// changeQuerier is satisfied by *sql.DB and *sql.Conn.
+type changeQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// WatchOptions controls which changes a watch streams and from where.
+type WatchOptions struct {
+	Cursor       int64         // Stream changes after this change ID; 0 streams the whole change log
+	Filter       EventFilter   // Only stream matching changes
+	PollInterval time.Duration // How often to check the database for changes (default 500ms)
+	BatchSize    int           // Changes read per query (default 500)
+	OnError      func(error)   // Called when a poll fails; the watch keeps polling
+}
+
+// Watch streams changes made to the tracking tables by any process using the
+// database, including this one. Changes are recorded by triggers in the
+// agent_changes table; the watch polls PRAGMA data_version and reads new changes
+// whenever another connection has written to the database.
+//
+// Each event's ID is its change log position. Save the ID of the last event
+// handled and pass it as the cursor to resume after a restart without missing
+// or repeating changes. Budget checks are not database writes, so
+// budget_exceeded events are only available in-process through Subscribe.
+//
+// The watch holds one connection from the pool until the context is canceled,
+// after which the channel is closed.
+//
+// Example:
+//
+//	events, err := agent_tracking.Watch(ctx, db, agent_tracking.WatchOptions{Cursor: lastSeen})
+//	if err != nil {
+//	    return err
+//	}
+//	for e := range events {
+//	    fmt.Printf("%s %s %s\n", e.Type, e.AgentName, e.SessionID)
+//	    lastSeen = e.ID
+//	}
+func Watch(ctx context.Context, db *sql.DB, opts WatchOptions) (<-chan Event, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 500 * time.Millisecond
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+
+	// data_version only reports changes made through other connections, so every
+	// poll must use the same connection.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watch connection: %w", err)
+	}
+
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		defer conn.Close()
+
+		cursor := opts.Cursor
+		var lastVersion int64 = -1
+		ticker := time.NewTicker(opts.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			var version int64
+			err := conn.QueryRowContext(ctx, `PRAGMA data_version`).Scan(&version)
+			if err == nil && version != lastVersion {
+				cursor, err = streamChanges(ctx, conn, cursor, opts, events)
+				if err == nil {
+					lastVersion = version
+				}
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil && opts.OnError != nil {
+				opts.OnError(fmt.Errorf("failed to poll changes: %w", err))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// streamChanges sends every change after the cursor and returns the new cursor.
+func streamChanges(ctx context.Context, q changeQuerier, cursor int64, opts WatchOptions, events chan<- Event) (int64, error) {
+	for {
+		batch, err := queryChanges(ctx, q, cursor, opts.Filter, opts.BatchSize)
+		if err != nil {
+			return cursor, err
+		}
+		for _, e := range batch {
+			select {
+			case events <- e:
+			case <-ctx.Done():
+				return cursor, ctx.Err()
+			}
+			cursor = e.ID
+		}
+		if len(batch) < opts.BatchSize {
+			return cursor, nil
+		}
+	}
+}
+
+// ListChanges returns up to limit changes after the given change ID, oldest first.
+//
+// Example:
+//
+//	changes, err := agent_tracking.ListChanges(db, lastSeen, agent_tracking.EventFilter{AgentName: "beads-workflow-orchestrator"}, 100)
+func ListChanges(db *sql.DB, afterID int64, filter EventFilter, limit int) ([]Event, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+
+	return queryChanges(context.Background(), db, afterID, filter, limit)
+}
+
+// LatestChangeID returns the ID of the newest change, or 0 if there are none.
+// Use it as a watch cursor to only stream changes from now on.
+func LatestChangeID(db *sql.DB) (int64, error) {
+	if db == nil {
+		return 0, fmt.Errorf("database connection is nil")
+	}
+
+	var id int64
+	err := db.QueryRow(`SELECT COALESCE(MAX(change_id), 0) FROM agent_changes`).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest change: %w", err)
+	}
+	return id, nil
+}
+
+// PruneChanges deletes changes recorded before the given time and returns how
+// many were deleted.
+//
+// Example:
+//
+//	deleted, err := agent_tracking.PruneChanges(db, time.Now().AddDate(0, 0, -30))
+func PruneChanges(db *sql.DB, before time.Time) (int64, error) {
+	if db == nil {
+		return 0, fmt.Errorf("database connection is nil")
+	}
+
+	result, err := db.Exec(`DELETE FROM agent_changes WHERE created_at < ?`, formatTime(before))
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune changes: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	return deleted, nil
+}
+
+// queryChanges reads up to limit matching changes after afterID.
+func queryChanges(ctx context.Context, q changeQuerier, afterID int64, filter EventFilter, limit int) ([]Event, error) {
+	conditions := []string{"c.change_id > ?"}
+	args := []interface{}{afterID}
+	if len(filter.Types) > 0 {
+		conditions = append(conditions, "c.event_type IN (?"+strings.Repeat(", ?", len(filter.Types)-1)+")")
+		for _, t := range filter.Types {
+			args = append(args, t)
+		}
+	}
+	if filter.SessionID != "" {
+		conditions = append(conditions, "c.session_id = ?")
+		args = append(args, filter.SessionID)
+	}
+	if filter.AgentName != "" {
+		conditions = append(conditions, "COALESCE(c.agent_name, s.agent_name) = ?")
+		args = append(args, filter.AgentName)
+	}
+	if filter.WorkspacePath != "" {
+		paths, err := getWorkspaceSpellings(ctx, q, filter.WorkspacePath)
+		if err != nil {
+			return nil, err
+		}
+		if len(paths) == 0 {
+			return nil, nil
+		}
+		conditions = append(conditions, "s.workspace_path IN (?"+strings.Repeat(", ?", len(paths)-1)+")")
+		for _, path := range paths {
+			args = append(args, path)
+		}
+	}
+	if filter.IssueID != "" {
+		conditions = append(conditions, "c.issue_id = ?")
+		args = append(args, filter.IssueID)
+	}
+	args = append(args, limit)
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT c.change_id, c.event_type, c.created_at,
+		       COALESCE(c.session_id, ''), COALESCE(c.work_id, ''), COALESCE(c.issue_id, ''),
+		       COALESCE(c.agent_name, s.agent_name, ''), COALESCE(s.workspace_path, ''), COALESCE(s.model_tier, ''),
+		       COALESCE(c.skill_name, ''), COALESCE(c.exit_reason, ''), COALESCE(c.notes, ''),
+		       c.issues, c.skills, COALESCE(c.tokens, 0), COALESCE(c.context_added, 0)
+		FROM agent_changes c
+		LEFT JOIN agent_sessions s ON s.session_id = c.session_id
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY c.change_id
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query changes: %w", err)
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var e Event
+		var createdAt string
+		var issues, skills sql.NullString
+		err := rows.Scan(
+			&e.ID, &e.Type, &createdAt,
+			&e.SessionID, &e.WorkID, &e.IssueID,
+			&e.AgentName, &e.WorkspacePath, &e.ModelTier,
+			&e.SkillName, &e.ExitReason, &e.Notes,
+			&issues, &skills, &e.Tokens, &e.ContextAdded,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan change: %w", err)
+		}
+
+		e.Time, err = parseTime(createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse created_at: %w", err)
+		}
+		if issues.Valid {
+			if err := json.Unmarshal([]byte(issues.String), &e.Issues); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal issues: %w", err)
+			}
+		}
+		if skills.Valid {
+			if err := json.Unmarshal([]byte(skills.String), &e.Skills); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal skills: %w", err)
+			}
+		}
+
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating changes: %w", err)
+	}
+
+	return events, nil
+}
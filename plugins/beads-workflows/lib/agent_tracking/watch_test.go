@@ -0,0 +1,171 @@
+package agent_tracking
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchStreamsChangesFromOtherConnections(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "beads.db")
+	writer := openRawTestDB(t, path)
+	if err := Initialize(writer); err != nil {
+		t.Fatal(err)
+	}
+	watcher := openRawTestDB(t, path)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := Watch(ctx, watcher, WatchOptions{
+		PollInterval: 10 * time.Millisecond,
+		BatchSize:    2,
+		Filter:       EventFilter{Types: []string{EventSessionStarted, EventWorkStarted, EventWorkCompleted, EventSessionEnded}},
+	})
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	sessionID, err := StartSession(writer, "agent", "/ws", "sonnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateSessionTokens(writer, sessionID, 100); err != nil {
+		t.Fatal(err)
+	}
+	workID, err := RecordWork(writer, sessionID, "agents-1", "agent", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CompleteWork(writer, workID, "done"); err != nil {
+		t.Fatal(err)
+	}
+	if err := EndSession(writer, sessionID, ExitCompleted); err != nil {
+		t.Fatal(err)
+	}
+
+	var types []string
+	var lastID int64
+	for len(types) < 4 {
+		select {
+		case e := <-events:
+			if e.ID <= lastID {
+				t.Errorf("change %d after %d", e.ID, lastID)
+			}
+			lastID = e.ID
+			if e.SessionID != sessionID || e.AgentName != "agent" {
+				t.Errorf("%s change context = %+v", e.Type, e)
+			}
+			if e.Type == EventWorkStarted && (e.IssueID != "agents-1" || e.WorkID != workID) {
+				t.Errorf("work change = %+v", e)
+			}
+			if e.Type == EventSessionEnded && e.ExitReason != ExitCompleted {
+				t.Errorf("session end change = %+v", e)
+			}
+			types = append(types, e.Type)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out after %v", types)
+		}
+	}
+	if got := strings.Join(types, ","); got != "session_started,work_started,work_completed,session_ended" {
+		t.Errorf("changes = %s", got)
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestListAndPruneChanges(t *testing.T) {
+	db := openTestDB(t)
+
+	latest, err := LatestChangeID(db)
+	if err != nil || latest != 0 {
+		t.Fatalf("LatestChangeID = %d, %v; want 0 on an empty log", latest, err)
+	}
+
+	first, err := StartSession(db, "agent", "/ws", "sonnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := StartSession(db, "reviewer", "/ws", "sonnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordSkillUsage(db, second, "code-review", "agents-1", 700); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := ListChanges(db, 0, EventFilter{AgentName: "reviewer"}, 10)
+	if err != nil {
+		t.Fatalf("ListChanges failed: %v", err)
+	}
+	if len(changes) != 2 || changes[0].Type != EventSessionStarted || changes[1].Type != EventSkillUsed {
+		t.Fatalf("reviewer changes = %+v", changes)
+	}
+	if changes[1].SkillName != "code-review" || changes[1].ContextAdded != 700 || changes[1].IssueID != "agents-1" {
+		t.Errorf("skill change = %+v", changes[1])
+	}
+
+	after, err := ListChanges(db, changes[0].ID, EventFilter{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != 1 || after[0].ID != changes[1].ID {
+		t.Errorf("changes after the cursor = %+v", after)
+	}
+
+	latest, err = LatestChangeID(db)
+	if err != nil || latest != changes[1].ID {
+		t.Errorf("LatestChangeID = %d, %v; want %d", latest, err, changes[1].ID)
+	}
+
+	deleted, err := PruneChanges(db, time.Now().Add(time.Hour))
+	if err != nil || deleted != 3 {
+		t.Errorf("PruneChanges = %d, %v; want 3", deleted, err)
+	}
+	if _, err := GetSession(db, first); err != nil {
+		t.Errorf("pruning removed tracking data: %v", err)
+	}
+}
+
+func TestChangeFiltersMatchWorkspaceSpellings(t *testing.T) {
+	db := openTestDB(t)
+	repo := filepath.Join(t.TempDir(), "repo")
+	if err := os.Mkdir(repo, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := StartSession(db, "agent", repo+"/", "sonnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := StartSession(db, "agent", filepath.Join(repo, "sub", ".."), "sonnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := StartSession(db, "agent", "/elsewhere", "sonnet"); err != nil {
+		t.Fatal(err)
+	}
+
+	filter := EventFilter{Types: []string{EventSessionStarted}, WorkspacePath: repo}
+	changes, err := ListChanges(db, 0, filter, 10)
+	if err != nil {
+		t.Fatalf("ListChanges failed: %v", err)
+	}
+	if len(changes) != 2 || changes[0].SessionID != first || changes[1].SessionID != second {
+		t.Errorf("changes = %+v, want both spellings of the repo", changes)
+	}
+	for _, e := range changes {
+		if !filter.Matches(e) {
+			t.Errorf("filter doesn't match %s in %s", e.Type, e.WorkspacePath)
+		}
+	}
+
+	none, err := ListChanges(db, 0, EventFilter{WorkspacePath: "/nowhere"}, 10)
+	if err != nil || len(none) != 0 {
+		t.Errorf("changes for an unknown workspace = %+v, %v", none, err)
+	}
+}
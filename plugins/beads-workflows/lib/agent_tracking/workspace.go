@@ -0,0 +1,242 @@
+package agent_tracking
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// WorkspaceStats contains aggregate statistics for a workspace. Paths lists the
+// recorded workspace paths that normalize to WorkspacePath.
+type WorkspaceStats struct {
+	WorkspacePath   string        `json:"workspace_path"`
+	Paths           []string      `json:"paths"`
+	TotalSessions   int           `json:"total_sessions"`
+	ActiveSessions  int           `json:"active_sessions"`
+	UniqueAgents    int           `json:"unique_agents"`
+	TotalIssues     int           `json:"total_issues"`
+	CompletedIssues int           `json:"completed_issues"`
+	TotalTokens     int           `json:"total_tokens"`
+	TotalTime       time.Duration `json:"total_time"`
+	AvgSessionTime  time.Duration `json:"avg_session_time"`
+	TopAgents       []AgentCount  `json:"top_agents"`
+	Since           time.Time     `json:"since"`
+}
+
+// GetWorkspaceStats returns aggregate statistics for sessions started since a
+// given time in a workspace, including sessions recorded under other spellings of
+// the same path.
+//
+// Example:
+//
+//	stats, err := agent_tracking.GetWorkspaceStats(db, "/myStuff/project", time.Now().AddDate(0, -1, 0))
+//	fmt.Printf("Sessions: %d, Completed: %d\n", stats.TotalSessions, stats.CompletedIssues)
+func GetWorkspaceStats(db *sql.DB, workspacePath string, since time.Time) (*WorkspaceStats, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	if workspacePath == "" {
+		return nil, fmt.Errorf("workspace path is required")
+	}
+
+	workspaces, err := getWorkspaceStats(db, since)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := NormalizeWorkspacePath(workspacePath)
+	if stats, ok := workspaces[normalized]; ok {
+		return stats, nil
+	}
+	return &WorkspaceStats{WorkspacePath: normalized, Paths: []string{}, TopAgents: []AgentCount{}, Since: since}, nil
+}
+
+// ListWorkspaceStats returns statistics for every workspace with sessions started
+// since a given time, most sessions first, for comparing workspaces.
+//
+// Example:
+//
+//	workspaces, err := agent_tracking.ListWorkspaceStats(db, time.Now().AddDate(0, 0, -7))
+//	for _, ws := range workspaces {
+//	    fmt.Printf("%s: %d sessions, %d tokens\n", ws.WorkspacePath, ws.TotalSessions, ws.TotalTokens)
+//	}
+func ListWorkspaceStats(db *sql.DB, since time.Time) ([]*WorkspaceStats, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	workspaces, err := getWorkspaceStats(db, since)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*WorkspaceStats, 0, len(workspaces))
+	for _, stats := range workspaces {
+		list = append(list, stats)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].TotalSessions != list[j].TotalSessions {
+			return list[i].TotalSessions > list[j].TotalSessions
+		}
+		return list[i].WorkspacePath < list[j].WorkspacePath
+	})
+
+	return list, nil
+}
+
+// getWorkspaceStats aggregates statistics per normalized workspace path. Queries
+// group by the recorded path; rows are merged after normalization, with issues and
+// agents de-duplicated across paths.
+func getWorkspaceStats(db *sql.DB, since time.Time) (map[string]*WorkspaceStats, error) {
+	sinceStr := formatTime(since)
+	workspaces := make(map[string]*WorkspaceStats)
+	normalized := make(map[string]string)
+	endedSessions := make(map[string]int)
+
+	// Get session counts, tokens and durations per recorded path
+	rows, err := db.Query(`
+		SELECT
+			workspace_path,
+			COUNT(*) as total,
+			SUM(CASE WHEN ended_at IS NULL THEN 1 ELSE 0 END) as active,
+			COALESCE(SUM(context_tokens), 0) as tokens,
+			COALESCE(SUM(CASE WHEN ended_at IS NOT NULL
+				THEN (JULIANDAY(ended_at) - JULIANDAY(started_at)) * 86400 END), 0) as total_seconds,
+			SUM(CASE WHEN ended_at IS NOT NULL THEN 1 ELSE 0 END) as ended
+		FROM agent_sessions
+		WHERE started_at >= ?
+		GROUP BY workspace_path
+		ORDER BY workspace_path
+	`, sinceStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace sessions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var path string
+		var total, active, tokens, ended int
+		var totalSeconds float64
+		if err := rows.Scan(&path, &total, &active, &tokens, &totalSeconds, &ended); err != nil {
+			return nil, fmt.Errorf("failed to scan workspace sessions: %w", err)
+		}
+
+		key := NormalizeWorkspacePath(path)
+		normalized[path] = key
+		stats, ok := workspaces[key]
+		if !ok {
+			stats = &WorkspaceStats{WorkspacePath: key, TopAgents: []AgentCount{}, Since: since}
+			workspaces[key] = stats
+		}
+		stats.Paths = append(stats.Paths, path)
+		stats.TotalSessions += total
+		stats.ActiveSessions += active
+		stats.TotalTokens += tokens
+		stats.TotalTime += time.Duration(totalSeconds * float64(time.Second))
+		endedSessions[key] += ended
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating workspace sessions: %w", err)
+	}
+
+	for key, stats := range workspaces {
+		if endedSessions[key] > 0 {
+			stats.AvgSessionTime = stats.TotalTime / time.Duration(endedSessions[key])
+		}
+	}
+
+	// Get issues per recorded path
+	issueRows, err := db.Query(`
+		SELECT s.workspace_path, w.issue_id, MAX(w.completed)
+		FROM agent_issue_work w
+		JOIN agent_sessions s ON w.session_id = s.session_id
+		WHERE s.started_at >= ?
+		GROUP BY s.workspace_path, w.issue_id
+	`, sinceStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace issues: %w", err)
+	}
+	defer issueRows.Close()
+
+	issues := make(map[string]map[string]bool)
+	for issueRows.Next() {
+		var path, issueID string
+		var completed bool
+		if err := issueRows.Scan(&path, &issueID, &completed); err != nil {
+			return nil, fmt.Errorf("failed to scan workspace issue: %w", err)
+		}
+		key := normalized[path]
+		if issues[key] == nil {
+			issues[key] = make(map[string]bool)
+		}
+		issues[key][issueID] = issues[key][issueID] || completed
+	}
+	if err := issueRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating workspace issues: %w", err)
+	}
+
+	for key, issueCompleted := range issues {
+		stats, ok := workspaces[key]
+		if !ok {
+			continue
+		}
+		stats.TotalIssues = len(issueCompleted)
+		for _, completed := range issueCompleted {
+			if completed {
+				stats.CompletedIssues++
+			}
+		}
+	}
+
+	// Get sessions per agent per recorded path
+	agentRows, err := db.Query(`
+		SELECT workspace_path, agent_name, COUNT(*)
+		FROM agent_sessions
+		WHERE started_at >= ?
+		GROUP BY workspace_path, agent_name
+	`, sinceStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace agents: %w", err)
+	}
+	defer agentRows.Close()
+
+	agents := make(map[string]map[string]int)
+	for agentRows.Next() {
+		var path, agentName string
+		var count int
+		if err := agentRows.Scan(&path, &agentName, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan workspace agent: %w", err)
+		}
+		key := normalized[path]
+		if agents[key] == nil {
+			agents[key] = make(map[string]int)
+		}
+		agents[key][agentName] += count
+	}
+	if err := agentRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating workspace agents: %w", err)
+	}
+
+	for key, counts := range agents {
+		stats, ok := workspaces[key]
+		if !ok {
+			continue
+		}
+		stats.UniqueAgents = len(counts)
+		for name, count := range counts {
+			stats.TopAgents = append(stats.TopAgents, AgentCount{AgentName: name, Count: count})
+		}
+		sort.Slice(stats.TopAgents, func(i, j int) bool {
+			if stats.TopAgents[i].Count != stats.TopAgents[j].Count {
+				return stats.TopAgents[i].Count > stats.TopAgents[j].Count
+			}
+			return stats.TopAgents[i].AgentName < stats.TopAgents[j].AgentName
+		})
+		if len(stats.TopAgents) > 5 {
+			stats.TopAgents = stats.TopAgents[:5]
+		}
+	}
+
+	return workspaces, nil
+}
@@ -0,0 +1,112 @@
+package agent_tracking
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestNormalizeWorkspacePath(t *testing.T) {
+	dir := t.TempDir()
+	repo := filepath.Join(dir, "repo")
+	if err := os.Mkdir(repo, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(repo, link); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	want := NormalizeWorkspacePath(repo)
+	for _, path := range []string{repo + "/", repo + "//", link, filepath.Join(link, "sub", "..")} {
+		if got := NormalizeWorkspacePath(path); got != want {
+			t.Errorf("NormalizeWorkspacePath(%q) = %q, want %q", path, got, want)
+		}
+	}
+	if got := NormalizeWorkspacePath(""); got != "" {
+		t.Errorf("NormalizeWorkspacePath(\"\") = %q", got)
+	}
+}
+
+func TestWorkspaceStatsMergeSpellings(t *testing.T) {
+	db := openTestDB(t)
+	dir := t.TempDir()
+	repo := filepath.Join(dir, "repo")
+	if err := os.Mkdir(repo, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(repo, link); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	first, err := StartSession(db, "orchestrator", repo+"/", "opus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RecordWork(db, first, "agents-1", "orchestrator", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateSessionTokens(db, first, 1000); err != nil {
+		t.Fatal(err)
+	}
+	second, err := StartSession(db, "reviewer", link, "haiku")
+	if err != nil {
+		t.Fatal(err)
+	}
+	workID, err := RecordWork(db, second, "agents-1", "reviewer", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CompleteWork(db, workID, "approved"); err != nil {
+		t.Fatal(err)
+	}
+	if err := EndSession(db, second, ExitCompleted); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := StartSession(db, "orchestrator", "/elsewhere", "opus"); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := ListWorkspaceStats(db, time.Time{})
+	if err != nil {
+		t.Fatalf("ListWorkspaceStats failed: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("workspaces = %d, want the repo and /elsewhere", len(list))
+	}
+	ws := list[0]
+	if ws.WorkspacePath != NormalizeWorkspacePath(repo) {
+		t.Errorf("busiest workspace = %q, want %q", ws.WorkspacePath, NormalizeWorkspacePath(repo))
+	}
+	paths := append([]string{}, ws.Paths...)
+	sort.Strings(paths)
+	if want := []string{link, repo + "/"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+	if ws.TotalSessions != 2 || ws.ActiveSessions != 1 || ws.UniqueAgents != 2 {
+		t.Errorf("sessions = %d (%d active, %d agents)", ws.TotalSessions, ws.ActiveSessions, ws.UniqueAgents)
+	}
+	if ws.TotalIssues != 1 || ws.CompletedIssues != 1 || ws.TotalTokens != 1000 {
+		t.Errorf("issues = %d, completed = %d, tokens = %d; want one issue counted once", ws.TotalIssues, ws.CompletedIssues, ws.TotalTokens)
+	}
+
+	byLink, err := GetWorkspaceStats(db, link+"/", time.Time{})
+	if err != nil {
+		t.Fatalf("GetWorkspaceStats failed: %v", err)
+	}
+	if byLink.TotalSessions != 2 {
+		t.Errorf("stats by link = %+v, want the merged workspace", byLink)
+	}
+
+	unknown, err := GetWorkspaceStats(db, "/nowhere", time.Time{})
+	if err != nil {
+		t.Fatalf("GetWorkspaceStats failed: %v", err)
+	}
+	if unknown.TotalSessions != 0 || unknown.Paths == nil {
+		t.Errorf("unknown workspace = %+v, want empty stats", unknown)
+	}
+}